@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// maxSyncRequests is how many requested syncs can wait for the daemon.
+const maxSyncRequests = 8
+
+// syncRequest asks the daemon to sync now, rather than at the next sync.
+type syncRequest struct {
+	// Account is the account to sync, or all of them when empty.
+	Account string `json:"account,omitempty"`
+	// Categories limits the sync to these categories, when set.
+	Categories []string `json:"categories,omitempty"`
+}
+
+func (r syncRequest) String() string {
+	s := "all accounts"
+	if r.Account != "" {
+		s = r.Account
+	}
+	if len(r.Categories) > 0 {
+		s += " (" + strings.Join(r.Categories, ", ") + ")"
+	}
+	return s
+}
+
+// serveStatus serves GET /status, the dashboard's data as JSON.
+func (d *dashboard) serveStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(d.status())
+	if err != nil {
+		log.Printf("[daemon] Error writing status: %v", err)
+	}
+}
+
+// serveSync serves POST /sync, which requests a sync of the account given
+// by the account parameter, or all accounts without it. category
+// parameters limit the sync to those categories.
+func (d *dashboard) serveSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Browsers send an Origin with requests made by web pages, any of
+	// which could otherwise trigger syncs.
+	if r.Header.Get("Origin") != "" {
+		http.Error(w, "requests from web pages aren't allowed", http.StatusForbidden)
+		return
+	}
+	req := syncRequest{Account: r.FormValue("account"), Categories: r.Form["category"]}
+	d.mu.Lock()
+	known := req.Account == "" || slices.Contains(d.configured, req.Account)
+	d.mu.Unlock()
+	if !known {
+		http.Error(w, fmt.Sprintf("no account called %q", req.Account), http.StatusNotFound)
+		return
+	}
+	for _, c := range req.Categories {
+		if !isCategory(c) {
+			http.Error(w, fmt.Sprintf("no category called %q", c), http.StatusBadRequest)
+			return
+		}
+	}
+
+	select {
+	case d.requests <- req:
+	default:
+		http.Error(w, "too many syncs already requested", http.StatusServiceUnavailable)
+		return
+	}
+	log.Printf("[daemon] Sync of %s requested", req)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	err := json.NewEncoder(w).Encode(req)
+	if err != nil {
+		log.Printf("[daemon] Error writing sync response: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+func TestServeSync(t *testing.T) {
+	board := newDashboard()
+	board.setConfigured([]string{"personal", "work"})
+
+	cases := []struct {
+		method, url, origin string
+		want                int
+	}{
+		{"GET", "/sync", "", http.StatusMethodNotAllowed},
+		{"POST", "/sync", "https://example.com", http.StatusForbidden},
+		{"POST", "/sync?account=other", "", http.StatusNotFound},
+		{"POST", "/sync?category=reviews", "", http.StatusBadRequest},
+		{"POST", "/sync?account=work&category=PRs&category=notifications", "", http.StatusAccepted},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(tc.method, tc.url, nil)
+		if tc.origin != "" {
+			r.Header.Set("Origin", tc.origin)
+		}
+		rec := httptest.NewRecorder()
+		board.ServeHTTP(rec, r)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: got %d, want %d: %s", tc.method, tc.url, rec.Code, tc.want, rec.Body)
+		}
+	}
+
+	if len(board.requests) != 1 {
+		t.Fatalf("Expected 1 sync request, got %d", len(board.requests))
+	}
+	req := <-board.requests
+	if req.Account != "work" || !slices.Equal(req.Categories, []string{"PRs", "notifications"}) {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+
+	for range maxSyncRequests {
+		board.requests <- syncRequest{}
+	}
+	rec := httptest.NewRecorder()
+	board.ServeHTTP(rec, httptest.NewRequest("POST", "/sync", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected syncs to be refused once too many are waiting, got %d", rec.Code)
+	}
+}
+
+func TestServeStatus(t *testing.T) {
+	board := newDashboard()
+	rec := httptest.NewRecorder()
+	board.ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", rec.Code, rec.Body)
+	}
+	var status daemonStatus
+	err := json.Unmarshal(rec.Body.Bytes(), &status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != Version || status.Accounts == nil {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestSyncRequestAccounts(t *testing.T) {
+	c := internal.Config{"work": {}, "personal": {}}
+	for _, tc := range []struct {
+		account string
+		want    []string
+	}{
+		{"", []string{"personal", "work"}},
+		{"work", []string{"work"}},
+		{"removed", []string{}},
+	} {
+		got := syncRequest{Account: tc.account}.accounts(c)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%q: got %v, want %v", tc.account, got, tc.want)
+		}
+	}
+}
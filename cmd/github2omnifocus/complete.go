@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+// completer decides how tasks are completed: optionally noting why on the
+// task first, and dropping rather than completing tasks for issues closed as
+// not planned. Tasks for PRs closed without merging can be dropped or kept
+// instead, as UnmergedPRAction says.
+type completer struct {
+	ghg *gh.GitHubGateway
+	og  omnifocus.Gateway
+	c   internal.GithubConfig
+	st  *state.Store
+	// desired holds the keys in each category of the desired state. Tasks
+	// for these are only completed to be re-created, as they differ from
+	// GitHub.
+	desired map[string]map[string]bool
+	now     time.Time
+}
+
+// errKept is returned by completions that kept the task open instead, so
+// recordCompletions doesn't record it as completed.
+var errKept = errors.New("task kept open")
+
+// forCategory wraps complete, the usual way of completing a task in
+// category.
+func (cm completer) forCategory(category string, complete func(omnifocus.Task) error) func(omnifocus.Task) error {
+	unmerged := cm.unmergedAction(category)
+	if !cm.c.AnnotateCompletedTasks && !(category == "issues" && cm.c.DropNotPlannedIssues) && unmerged == "" {
+		return complete
+	}
+	return func(t omnifocus.Task) error {
+		if cm.desired[category][t.Key()] {
+			if cm.c.AnnotateCompletedTasks {
+				err := cm.annotate(t, "Completed", "re-created as it changed on GitHub")
+				if err != nil {
+					return err
+				}
+			}
+			return complete(t)
+		}
+
+		s, known := cm.issueState(category, t)
+		closedUnmerged := known && s.PullRequest != nil && s.State == "closed" && !s.Merged()
+		if closedUnmerged && unmerged == internal.UnmergedPRActionKeep {
+			// Noted regardless of AnnotateCompletedTasks, as otherwise
+			// it's not clear why the task is still open.
+			err := cm.annotate(t, "Kept", "PR closed without merging")
+			if err != nil {
+				return err
+			}
+			cm.st.Keep(t.Key(), cm.now)
+			return errKept
+		}
+		drop := category == "issues" && cm.c.DropNotPlannedIssues && s.StateReason == "not_planned" ||
+			closedUnmerged && unmerged == internal.UnmergedPRActionDrop
+		if cm.c.AnnotateCompletedTasks {
+			verb := "Completed"
+			if drop {
+				verb = "Dropped"
+			}
+			err := cm.annotate(t, verb, completionReason(category, s, known))
+			if err != nil {
+				return err
+			}
+		}
+		if drop {
+			return cm.og.DropIssue(t)
+		}
+		return complete(t)
+	}
+}
+
+// unmergedAction returns the UnmergedPRAction for category's tasks, if
+// any.
+func (cm completer) unmergedAction(category string) string {
+	for alias, name := range categoryAliases {
+		if name == category {
+			return cm.c.UnmergedPRAction[alias]
+		}
+	}
+	return ""
+}
+
+func (cm completer) annotate(t omnifocus.Task, verb, reason string) error {
+	return cm.og.AppendNote(t, fmt.Sprintf("%s by github2omnifocus on %s: %s", verb, cm.now.Format("2006-01-02"), reason))
+}
+
+// issueState looks up the GitHub state of the issue or PR for t, if the
+// category's tasks are for issues or PRs. A failed lookup is logged, as
+// the task is completed either way.
+func (cm completer) issueState(category string, t omnifocus.Task) (gh.IssueState, bool) {
+	switch category {
+	case "issues", "PRs", "authored PRs", "mentions":
+	default:
+		return gh.IssueState{}, false
+	}
+	repo, number, ok := gh.ParseIssueKey(t.Key())
+	if !ok {
+		return gh.IssueState{}, false
+	}
+	s, err := cm.ghg.GetIssueState(repo, number)
+	if err != nil {
+		log.Printf("Could not check why %s is done: %v", t.Key(), err)
+		return gh.IssueState{}, false
+	}
+	return s, true
+}
+
+// completionReason describes why an item in category is no longer in the
+// desired state.
+func completionReason(category string, s gh.IssueState, known bool) string {
+	switch category {
+	case "notifications":
+		return "notification read"
+	case "failing checks":
+		return "workflow no longer failing"
+	case "tracked items":
+		return "checked off or removed from the tracking issue"
+	case "watched releases":
+		return "newer release or release too old"
+	}
+	if !known {
+		return "no longer on GitHub"
+	}
+	noun := "issue"
+	if s.PullRequest != nil {
+		noun = "PR"
+	}
+	switch {
+	case s.Merged():
+		return "PR merged"
+	case s.State == "closed" && s.StateReason == "not_planned":
+		return noun + " closed as not planned"
+	case s.State == "closed":
+		return noun + " closed"
+	case category == "issues":
+		return "issue no longer assigned"
+	case category == "PRs":
+		return "review no longer requested"
+	case category == "mentions":
+		return "no longer only mentioned"
+	}
+	return noun + " no longer open"
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+func TestCompletionReason(t *testing.T) {
+	merged := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	pr := &struct {
+		MergedAt *time.Time `json:"merged_at"`
+	}{}
+	mergedPR := &struct {
+		MergedAt *time.Time `json:"merged_at"`
+	}{MergedAt: &merged}
+
+	cases := []struct {
+		category string
+		state    gh.IssueState
+		known    bool
+		reason   string
+	}{
+		{"PRs", gh.IssueState{State: "closed", PullRequest: mergedPR}, true, "PR merged"},
+		{"authored PRs", gh.IssueState{State: "closed", PullRequest: pr}, true, "PR closed"},
+		{"issues", gh.IssueState{State: "closed", StateReason: "not_planned"}, true, "issue closed as not planned"},
+		{"issues", gh.IssueState{State: "open"}, true, "issue no longer assigned"},
+		{"PRs", gh.IssueState{State: "open", PullRequest: pr}, true, "review no longer requested"},
+		{"issues", gh.IssueState{}, false, "no longer on GitHub"},
+		{"notifications", gh.IssueState{}, false, "notification read"},
+	}
+	for _, c := range cases {
+		if r := completionReason(c.category, c.state, c.known); r != c.reason {
+			t.Fatalf("%s %+v: expected %q, got %q", c.category, c.state, c.reason, r)
+		}
+	}
+}
+
+func TestCompleterAnnotatesRecreatedTasks(t *testing.T) {
+	fake, err := omnifocus.NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := omnifocus.Gateway{AppTag: "github", AssignedTag: "assigned", AssignedProject: "GitHub Assigned", Backend: fake}
+	err = og.AddIssue(gh.GitHubItem{K: "acme/tools#1", Title: "Fix me", Repo: "acme/tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm := completer{
+		og:      og,
+		c:       internal.GithubConfig{AnnotateCompletedTasks: true},
+		desired: map[string]map[string]bool{"issues": {"acme/tools#1": true}},
+		now:     time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+	}
+	err = cm.forCategory("issues", og.CompleteIssue)(tasks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := fake.Tasks[0].Note
+	if !strings.HasSuffix(note, "Completed by github2omnifocus on 2024-05-01: re-created as it changed on GitHub") {
+		t.Fatalf("Didn't get expected note, got: %q", note)
+	}
+	if !fake.Tasks[0].Completed {
+		t.Fatal("Expected task to be completed")
+	}
+}
+
+func TestCompleterOtherCategoryKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/issues/1") {
+			_, _ = io.WriteString(w, `{"state": "closed", "state_reason": "not_planned"}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	ghg, err := gh.NewGitHubGateway(context.Background(), "", server.URL+"/", gh.TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake, err := omnifocus.NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := omnifocus.Gateway{AppTag: "github", AssignedTag: "assigned", AssignedProject: "GitHub Assigned", Backend: fake}
+	err = og.AddIssue(gh.GitHubItem{K: "acme/tools#1", Title: "Fix me", Repo: "acme/tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A notification about the closed issue shares its key.
+	cm := completer{
+		ghg:     &ghg,
+		og:      og,
+		c:       internal.GithubConfig{AnnotateCompletedTasks: true, DropNotPlannedIssues: true},
+		desired: map[string]map[string]bool{"issues": {}, "notifications": {"acme/tools#1": true}},
+		now:     time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+	}
+	err = cm.forCategory("issues", og.CompleteIssue)(tasks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := fake.Tasks[0]
+	if !task.Dropped || !strings.HasSuffix(task.Note, "Dropped by github2omnifocus on 2024-05-01: issue closed as not planned") {
+		t.Fatalf("Expected the task to be dropped as not planned, got: %+v", task)
+	}
+}
+
+func TestCompleterUnmergedPRs(t *testing.T) {
+	reopened := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPatch:
+			reopened = append(reopened, r.URL.Path)
+			_, _ = io.WriteString(w, `{}`)
+		case strings.HasSuffix(r.URL.Path, "/issues/1"):
+			_, _ = io.WriteString(w, `{"state": "closed", "pull_request": {"merged_at": null}}`)
+		case strings.HasSuffix(r.URL.Path, "/issues/2"):
+			_, _ = io.WriteString(w, `{"state": "closed", "pull_request": {"merged_at": "2024-05-01T08:00:00Z"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ghg, err := gh.NewGitHubGateway(context.Background(), "", server.URL+"/", gh.TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	st, err := state.Load(path.Join(dir, "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, action := range []string{internal.UnmergedPRActionKeep, internal.UnmergedPRActionDrop} {
+		fake, err := omnifocus.NewFakeBackend(path.Join(dir, action+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		og := omnifocus.Gateway{AppTag: "github", PendingChangesTag: "pending", PendingChangesProject: "GitHub Pending", Backend: fake}
+		for _, item := range []gh.GitHubItem{{K: "acme/tools#1", Title: "Closed", Repo: "acme/tools"}, {K: "acme/tools#2", Title: "Merged", Repo: "acme/tools"}} {
+			err = og.AddAuthoredPR(item)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		tasks, err := og.GetAuthoredPRs()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cm := completer{
+			ghg:     &ghg,
+			og:      og,
+			c:       internal.GithubConfig{UnmergedPRAction: map[string]string{"authored": action}},
+			st:      st,
+			desired: map[string]map[string]bool{},
+			now:     time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+		}
+		for _, task := range tasks {
+			err = recordCompletions(st, cm.forCategory("authored PRs", og.CompletePR))(task)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		byKey := map[string]omnifocus.FakeTask{}
+		for _, task := range fake.Tasks {
+			byKey[strings.Fields(task.Name)[0]] = task
+		}
+		closed, merged := byKey["acme/tools#1"], byKey["acme/tools#2"]
+		if !merged.Completed || merged.Dropped {
+			t.Fatalf("%s: expected the merged PR's task to be completed, got: %+v", action, merged)
+		}
+		switch action {
+		case internal.UnmergedPRActionKeep:
+			if closed.Completed || closed.Dropped || !st.IsKept("acme/tools#1") || !strings.HasSuffix(closed.Note, "Kept by github2omnifocus on 2024-05-01: PR closed without merging") {
+				t.Fatalf("Expected the closed PR's task to be kept with a note, got: %+v", closed)
+			}
+			// Kept tasks are left alone by later syncs, and as they weren't
+			// completed, they aren't taken to have been marked incomplete
+			// again, which would reopen the PR.
+			if st.WasCompleted("acme/tools#1") {
+				t.Fatal("Expected the kept task not to be recorded as completed")
+			}
+			open, err := og.GetAuthoredPRs()
+			if err != nil {
+				t.Fatal(err)
+			}
+			current := OFCurrentState{AuthoredPRs: open}
+			c := internal.GithubConfig{UncompletedAction: internal.UncompletedActionReopen}
+			handleUncompleted(&ghg, c, st, GHDesiredState{}, &current, cm.now)
+			if len(open) != 1 || len(current.AuthoredPRs) != 0 {
+				t.Fatalf("Expected the kept task to be left alone, got: %v", current.AuthoredPRs)
+			}
+			if len(reopened) > 0 {
+				t.Fatalf("Expected the closed PR not to be reopened, got: %v", reopened)
+			}
+		case internal.UnmergedPRActionDrop:
+			if !closed.Dropped {
+				t.Fatalf("Expected the closed PR's task to be dropped, got: %+v", closed)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+// accountCounts is the number of open tasks in each category for an
+// account, as printed by counts --json.
+type accountCounts struct {
+	Account string         `json:"account"`
+	Counts  map[string]int `json:"counts"`
+	Total   int            `json:"total"`
+}
+
+// counts implements the counts command, which prints how many open tasks
+// each account has in each category. It reads Omnifocus without contacting
+// GitHub, so it's quick enough to call from Shortcuts.
+func counts(ctx context.Context, c internal.Config, opts runOptions, args []string, output string, w io.Writer) error {
+	fs := flag.NewFlagSet("counts", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the counts as JSON, as --output=json does")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	all := []accountCounts{}
+	for _, account := range slices.Sorted(maps.Keys(c)) {
+		backend, err := accountBackend(c[account], opts)
+		if err != nil {
+			return err
+		}
+		og := newOmnifocusGateway(account, c[account], nil, backend)
+		stats := newSyncStats(ctx, account)
+		current, err := GetOFState(og, stats)
+		stats.end()
+		if err != nil {
+			return fmt.Errorf("%s: %v", account, err)
+		}
+		ac := accountCounts{Account: account, Counts: map[string]int{}}
+		for _, cat := range current.categories() {
+			ac.Counts[cat.name] = len(cat.tasks)
+			ac.Total += len(cat.tasks)
+		}
+		all = append(all, ac)
+	}
+
+	if *asJSON || output == outputJSON {
+		return writeJSON(w, struct {
+			Version  string          `json:"version"`
+			Accounts []accountCounts `json:"accounts"`
+		}{Version, all})
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:gomnd
+	for _, ac := range all {
+		fmt.Fprintf(tw, "%s\t%d\t\n", ac.Account, ac.Total)
+		for _, cat := range (OFCurrentState{}).categories() {
+			fmt.Fprintf(tw, "  %s\t%d\t\n", cat.name, ac.Counts[cat.name])
+		}
+	}
+	return tw.Flush()
+}
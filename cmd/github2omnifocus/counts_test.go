@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+)
+
+func TestCounts(t *testing.T) {
+	fake, err := omnifocus.NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nt := range []omnifocus.NewOmnifocusTask{
+		{Name: "acme/tools#1 Fix it", ProjectName: "Reviews", Tags: []string{"github", "review"}},
+		{Name: "acme/tools#2 Fix it too", ProjectName: "Reviews", Tags: []string{"github", "review"}},
+		{Name: "acme/tools#3 Crash", ProjectName: "Work", Tags: []string{"github", "assigned"}},
+	} {
+		_, err = fake.AddTask(nt)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := internal.Config{"work": {
+		AppTag:          "github",
+		AssignedTag:     "assigned",
+		AssignedProject: "Work",
+		ReviewTag:       "review",
+		ReviewProject:   "Reviews",
+	}}
+
+	var out bytes.Buffer
+	err = counts(context.Background(), c, runOptions{backend: fake}, []string{"--json"}, outputText, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Accounts []accountCounts `json:"accounts"`
+	}
+	err = json.Unmarshal(out.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Accounts) != 1 || got.Accounts[0].Total != 3 || got.Accounts[0].Counts["PRs"] != 2 || got.Accounts[0].Counts["issues"] != 1 {
+		t.Errorf("Unexpected counts: %+v", got.Accounts)
+	}
+
+	out.Reset()
+	err = counts(context.Background(), c, runOptions{backend: fake}, nil, outputText, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if strings.Join(strings.Fields(lines[0]), " ") != "work 3" || strings.Join(strings.Fields(lines[2]), " ") != "PRs 2" {
+		t.Errorf("Unexpected counts:\n%s", out.String())
+	}
+}
+
+func TestParseCategories(t *testing.T) {
+	got, err := parseCategories("reviews, notifications,authored PRs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "PRs,notifications,authored PRs" {
+		t.Errorf("got %q", got)
+	}
+	_, err = parseCategories("reviews,bogus")
+	if err == nil {
+		t.Error("Expected an error for an unknown category")
+	}
+	got, err = parseCategories("")
+	if err != nil || len(got) != 0 {
+		t.Errorf("Expected no categories, got %q %v", got, err)
+	}
+}
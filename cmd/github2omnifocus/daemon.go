@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+// daemon syncs each account when it's due, as given by its Schedule or
+// otherwise every interval, until interrupted. The config is loaded again
+// before each round of syncs, so changes to it apply without a restart.
+// Syncs can also be requested through the API served at statusAddr and on
+// the Unix socket at socket. Profiles are served at pprofAddr, if set.
+func daemon(ctx context.Context, configPath string, c internal.Config, opts runOptions, interval time.Duration, resultFile, statusAddr, socket, pprofAddr string) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	board := newDashboard()
+	if statusAddr != "" {
+		l, err := listenLocal(statusAddr)
+		if err != nil {
+			log.Fatalf("[daemon] Error serving status dashboard: %v", err)
+		}
+		log.Printf("[daemon] Serving status dashboard at http://%s/", l.Addr())
+		board.serve(ctx, l)
+	}
+	if pprofAddr != "" {
+		err := servePprof(ctx, pprofAddr)
+		if err != nil {
+			log.Fatalf("[daemon] Error serving profiles: %v", err)
+		}
+		log.Printf("[daemon] Serving profiles at http://%s/debug/pprof/", pprofAddr)
+	}
+	if socket != "" {
+		l, err := listenUnix(socket)
+		if err != nil {
+			log.Fatalf("[daemon] Error serving API on socket: %v", err)
+		}
+		log.Printf("[daemon] Serving API on socket %s", socket)
+		board.serve(ctx, l)
+	}
+
+	log.Printf("[daemon] Syncing accounts without a schedule every %s", interval)
+	last := map[string]time.Time{}
+	var req *syncRequest
+	for {
+		board.setConfigured(slices.Sorted(maps.Keys(c)))
+		started := time.Now()
+		names := dueAccounts(c, last, interval, started)
+		o := opts
+		if req != nil {
+			names = req.accounts(c)
+			o.categories = req.Categories
+			log.Printf("[daemon] Syncing %s as requested", req)
+		}
+		stats := []*syncStats{}
+		for _, name := range names {
+			s, err := sync_github(ctx, name, c[name], o)
+			logFailure(name, err)
+			notifyWebhook(c[name], started, s)
+			notifyEmail(c[name], started, s)
+			stats = append(stats, s)
+			// A sync of some categories doesn't put off a full one.
+			if len(o.categories) == 0 {
+				last[name] = started
+			}
+		}
+		if len(stats) > 0 {
+			warnSharedKeys(stats)
+			logSummary(stats)
+			if resultFile != "" {
+				err := writeResultFile(resultFile, newSyncResult(started, exitCode(stats, false), stats))
+				if err != nil {
+					log.Printf("Error writing result file: %v", err)
+				}
+			}
+		}
+
+		wake := nextWake(c, last, interval)
+		if len(stats) > 0 {
+			board.record(started, stats, wake)
+		}
+		log.Printf("[daemon] Next sync at %s", wake.Format(time.RFC3339))
+		req = nil
+		select {
+		case <-ctx.Done():
+			log.Printf("[daemon] Stopping")
+			return
+		case <-time.After(time.Until(wake)):
+		case r := <-board.requests:
+			req = &r
+		}
+		c = reloadConfig(configPath, c)
+	}
+}
+
+// dueAccounts returns the accounts in c to sync at now, given when each was
+// last synced. Accounts never synced are always due.
+func dueAccounts(c internal.Config, last map[string]time.Time, interval time.Duration, now time.Time) []string {
+	due := []string{}
+	for name, v := range c {
+		l, ok := last[name]
+		if !ok {
+			due = append(due, name)
+			continue
+		}
+		next := v.NextSync(l, interval)
+		if !next.IsZero() && !next.After(now) {
+			due = append(due, name)
+		}
+	}
+	return due
+}
+
+// nextWake returns when the next account in c is due to be synced. It's at
+// most interval away, so config changes are picked up.
+func nextWake(c internal.Config, last map[string]time.Time, interval time.Duration) time.Time {
+	wake := time.Now().Add(interval)
+	for name, v := range c {
+		next := v.NextSync(last[name], interval)
+		if !next.IsZero() && next.Before(wake) {
+			wake = next
+		}
+	}
+	return wake
+}
+
+// reloadConfig loads the config at configPath again, keeping previous if
+// the file can no longer be loaded, e.g. as it's mid-edit.
+func reloadConfig(configPath string, previous internal.Config) internal.Config {
+	c, err := internal.LoadConfig2(configPath)
+	if err != nil {
+		log.Printf("[daemon] Keeping previous config, error reloading: %v", err)
+		return previous
+	}
+	if !reflect.DeepEqual(c, previous) {
+		log.Printf("[daemon] Config changed, applying to this sync")
+	}
+	return c
+}
+
+// accounts returns the accounts in c the request is for.
+func (r syncRequest) accounts(c internal.Config) []string {
+	if r.Account == "" {
+		return slices.Sorted(maps.Keys(c))
+	}
+	if _, ok := c[r.Account]; !ok {
+		// Removed from the config since the request was made.
+		return []string{}
+	}
+	return []string{r.Account}
+}
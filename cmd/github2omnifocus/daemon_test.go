@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+func TestReloadConfig(t *testing.T) {
+	p := path.Join(t.TempDir(), "config.json")
+	write := func(content string) {
+		err := os.WriteFile(p, []byte(content), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"work": {"AccessToken": "a", "ReviewProject": "Reviews"}}`)
+	c, err := internal.LoadConfig2(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write(`{"work": {"AccessToken": "a", "ReviewProject": "Work Reviews"}, "home": {"AccessToken": "b"}}`)
+	c = reloadConfig(p, c)
+	if len(c) != 2 || c["work"].ReviewProject != "Work Reviews" {
+		t.Fatalf("Expected changed config, got: %v", c)
+	}
+
+	write(`{"work": {"AccessToken": `)
+	c = reloadConfig(p, c)
+	if len(c) != 2 {
+		t.Fatalf("Expected previous config to be kept, got: %v", c)
+	}
+}
+
+func TestDueAccounts(t *testing.T) {
+	c := internal.Config{
+		"work": {Schedule: []string{"0 * * * *"}},
+		"home": {},
+	}
+	now := time.Date(2024, 5, 1, 22, 30, 0, 0, time.UTC)
+	due := dueAccounts(c, map[string]time.Time{}, 5*time.Minute, now)
+	if len(due) != 2 {
+		t.Fatalf("Expected accounts never synced to be due, got: %v", due)
+	}
+
+	last := map[string]time.Time{"work": now, "home": now}
+	due = dueAccounts(c, last, 5*time.Minute, now.Add(5*time.Minute))
+	if len(due) != 1 || due[0] != "home" {
+		t.Fatalf("Expected only home to be due, got: %v", due)
+	}
+	due = dueAccounts(c, last, 5*time.Minute, now.Add(30*time.Minute))
+	if len(due) != 2 {
+		t.Fatalf("Expected both accounts to be due, got: %v", due)
+	}
+}
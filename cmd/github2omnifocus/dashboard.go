@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// recentSyncs is how many account syncs the dashboard lists.
+const recentSyncs = 20
+
+// dashboard is a page showing how the daemon's syncs are going, so its
+// health can be checked without reading its logs. It also serves the API
+// in api.go.
+type dashboard struct {
+	mu       sync.Mutex
+	started  time.Time
+	nextSync time.Time
+	// accounts holds each account's latest sync.
+	accounts map[string]accountStatus
+	// recent holds the latest syncs of all accounts, newest first.
+	recent []accountStatus
+	// configured holds the names of the accounts in the config.
+	configured []string
+
+	// requests passes syncs requested through the API to the daemon.
+	requests chan syncRequest
+}
+
+type accountStatus struct {
+	Synced time.Time `json:"synced"`
+	accountResult
+	// Pending describes the operations queued for the next sync.
+	Pending []string `json:"pending"`
+}
+
+func newDashboard() *dashboard {
+	return &dashboard{
+		started:  time.Now(),
+		accounts: map[string]accountStatus{},
+		requests: make(chan syncRequest, maxSyncRequests),
+	}
+}
+
+// setConfigured records the names of the accounts in the config.
+func (d *dashboard) setConfigured(names []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.configured = names
+}
+
+// record adds a round of syncs, started at started, to the dashboard.
+func (d *dashboard) record(started time.Time, stats []*syncStats, nextSync time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextSync = nextSync
+	for i, a := range newSyncResult(started, exitCode(stats, false), stats).Accounts {
+		s := accountStatus{Synced: started, accountResult: a, Pending: []string{}}
+		for _, op := range stats[i].queued {
+			s.Pending = append(s.Pending, op.String())
+		}
+		d.accounts[a.Account] = s
+		d.recent = append([]accountStatus{s}, d.recent...)
+	}
+	if len(d.recent) > recentSyncs {
+		d.recent = d.recent[:recentSyncs]
+	}
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="30">
+<title>github2omnifocus</title>
+<style>
+body { font-family: -apple-system, sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.3em 1em 0.3em 0; vertical-align: top; }
+.error { color: #c00; }
+</style>
+</head>
+<body>
+<h1>github2omnifocus {{.Version}}</h1>
+<p>Running since {{.Started.Format "2006-01-02 15:04:05"}}. Next sync at {{.NextSync.Format "15:04:05"}}.</p>
+<h2>Accounts</h2>
+<table>
+<tr><th>Account</th><th>Last synced</th><th>Result</th><th>Rate limit</th><th>Pending</th></tr>
+{{range .Accounts}}<tr>
+<td>{{.Account}}</td>
+<td>{{.Synced.Format "2006-01-02 15:04:05"}}</td>
+<td>{{template "result" .}}</td>
+<td>{{with .RateLimit}}{{.Remaining}}/{{.Limit}} until {{.Reset.Format "15:04"}}{{else}}-{{end}}</td>
+<td>{{range .Pending}}{{.}}<br>{{else}}-{{end}}</td>
+</tr>{{else}}<tr><td colspan="5">No syncs yet</td></tr>{{end}}
+</table>
+<h2>Recent syncs</h2>
+<table>
+<tr><th>Started</th><th>Account</th><th>Added</th><th>Completed</th><th>Result</th></tr>
+{{range .Recent}}<tr>
+<td>{{.Synced.Format "2006-01-02 15:04:05"}}</td>
+<td>{{.Account}}</td>
+<td>{{range $category, $n := .Added}}{{$n}} {{$category}}<br>{{else}}-{{end}}</td>
+<td>{{range $category, $n := .Completed}}{{$n}} {{$category}}<br>{{else}}-{{end}}</td>
+<td>{{template "result" .}}</td>
+</tr>{{end}}
+</table>
+</body>
+</html>
+{{define "result"}}{{if .Error}}<span class="error">{{.Error}}</span>{{else if .Skipped}}skipped: {{.Skipped}}{{else}}ok{{end}}{{range .Warnings}}<br><span class="error">{{.}}</span>{{end}}{{end}}
+`))
+
+// daemonStatus is the dashboard's data, also served by GET /status.
+type daemonStatus struct {
+	Version  string          `json:"version"`
+	Started  time.Time       `json:"started"`
+	NextSync time.Time       `json:"nextSync"`
+	Accounts []accountStatus `json:"accounts"`
+	Recent   []accountStatus `json:"recent"`
+}
+
+func (d *dashboard) status() daemonStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := daemonStatus{Version, d.started, d.nextSync, []accountStatus{}, slices.Clone(d.recent)}
+	for _, a := range d.accounts {
+		s.Accounts = append(s.Accounts, a)
+	}
+	slices.SortFunc(s.Accounts, func(a, b accountStatus) int { return strings.Compare(a.Account, b.Account) })
+	return s
+}
+
+func (d *dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		d.serveDashboard(w, r)
+	case "/status":
+		d.serveStatus(w, r)
+	case "/sync":
+		d.serveSync(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (d *dashboard) serveDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := dashboardTemplate.Execute(w, d.status())
+	if err != nil {
+		log.Printf("[daemon] Error rendering dashboard: %v", err)
+	}
+}
+
+// serve serves the dashboard on l until ctx is done.
+func (d *dashboard) serve(ctx context.Context, l net.Listener) {
+	var h http.Handler = d
+	if l.Addr().Network() == "tcp" {
+		h = localHostOnly(h)
+	}
+	server := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second} //nolint:gomnd
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		err := server.Serve(l)
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[daemon] Status dashboard stopped: %v", err)
+		}
+	}()
+}
+
+// listenLocal listens on the TCP address addr, which must be on the
+// loopback interface, as what's served there isn't authenticated. Without
+// a host, as in ":8642", it listens on 127.0.0.1.
+func listenLocal(addr string) (net.Listener, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if !isLoopback(host) {
+		return nil, fmt.Errorf("%s isn't a loopback address, such as localhost:%s", addr, port)
+	}
+	return net.Listen("tcp", net.JoinHostPort(host, port))
+}
+
+// isLoopback is true for localhost and loopback IP addresses.
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// localHostOnly rejects requests not addressed to a loopback host, so a
+// web page can't reach h by pointing its own domain at 127.0.0.1.
+func localHostOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !isLoopback(host) {
+			http.Error(w, "forbidden host", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// listenUnix listens on the Unix socket at p, which only the user can
+// connect to. A socket left behind by a daemon that didn't stop cleanly is
+// replaced.
+func listenUnix(p string) (net.Listener, error) {
+	l, err := net.Listen("unix", p)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		if conn, dialErr := net.Dial("unix", p); dialErr == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another daemon is listening on %s", p)
+		}
+		err = os.Remove(p)
+		if err != nil {
+			return nil, err
+		}
+		l, err = net.Listen("unix", p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chmod(p, 0o600)
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
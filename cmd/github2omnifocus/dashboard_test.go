@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
+
+func TestDashboard(t *testing.T) {
+	board := newDashboard()
+
+	rec := httptest.NewRecorder()
+	board.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(rec.Body.String(), "No syncs yet") {
+		t.Errorf("Expected no syncs, got:\n%s", rec.Body)
+	}
+
+	work := newSyncStats(context.Background(), "work")
+	work.adds["issues"] = 2
+	work.rate = &gh.Rate{Limit: 5000, Remaining: 4321, Reset: time.Now()}
+	work.err = errors.New("Omnifocus quit <mid-sync>")
+	work.queued = []queuedOp{{Category: "issues", Op: queuedAdd, Item: gh.GitHubItem{K: "acme/tools#1"}}}
+	personal := newSyncStats(context.Background(), "personal")
+	board.record(time.Now(), []*syncStats{work, personal}, time.Now().Add(time.Minute))
+
+	rec = httptest.NewRecorder()
+	board.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	body := rec.Body.String()
+	for _, want := range []string{"work", "personal", "2 issues", "4321/5000", "Omnifocus quit &lt;mid-sync&gt;", "add issues acme/tools#1"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in dashboard, got:\n%s", want, body)
+		}
+	}
+	if strings.Index(body, "personal") > strings.Index(body, "work") {
+		t.Errorf("Expected accounts in name order")
+	}
+}
+
+func TestDashboardKeepsRecentSyncs(t *testing.T) {
+	board := newDashboard()
+	for range recentSyncs + 5 {
+		board.record(time.Now(), []*syncStats{newSyncStats(context.Background(), "work")}, time.Now())
+	}
+	if len(board.recent) != recentSyncs || len(board.accounts) != 1 {
+		t.Errorf("Expected %d recent syncs of 1 account, got %d of %d", recentSyncs, len(board.recent), len(board.accounts))
+	}
+}
+
+func TestServeOnUnixSocket(t *testing.T) {
+	p := path.Join(t.TempDir(), "daemon.sock")
+	// A socket left behind by a daemon that's gone.
+	stale, err := net.Listen("unix", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := listenUnix(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	newDashboard().serve(ctx, l)
+
+	_, err = listenUnix(p)
+	if err == nil {
+		t.Fatal("Expected an error listening while another daemon is")
+	}
+
+	client := http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", p)
+		},
+	}}
+	resp, err := client.Get("http://daemon/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got %d", resp.StatusCode)
+	}
+}
+
+func TestServeLocalOnly(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:0", "192.168.1.2:8642", "example.com:8642"} {
+		if l, err := listenLocal(addr); err == nil {
+			l.Close()
+			t.Errorf("Expected an error listening on %s", addr)
+		}
+	}
+	l, err := listenLocal(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host, _, _ := net.SplitHostPort(l.Addr().String()); host != "127.0.0.1" {
+		t.Errorf("Expected to listen on 127.0.0.1 without a host, got: %s", l.Addr())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	newDashboard().serve(ctx, l)
+
+	for host, status := range map[string]int{
+		l.Addr().String():    http.StatusOK,
+		"localhost":          http.StatusOK,
+		"evil.example.com":   http.StatusForbidden,
+		"evil.example.com:1": http.StatusForbidden,
+	} {
+		req, err := http.NewRequest(http.MethodGet, "http://"+l.Addr().String()+"/status", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("Expected %d for host %s, got %d", status, host, resp.StatusCode)
+		}
+	}
+}
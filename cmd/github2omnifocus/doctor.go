@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+)
+
+// doctor implements the doctor command, which checks each account can
+// reach GitHub and control Omnifocus, printing how to fix what it can't.
+// An error is returned if any check failed.
+func doctor(ctx context.Context, c internal.Config, opts runOptions, w io.Writer) error {
+	failed := 0
+	for _, account := range slices.Sorted(maps.Keys(c)) {
+		checks := []struct {
+			name string
+			f    func() ([]string, error)
+		}{
+			{"GitHub", func() ([]string, error) { return checkGitHub(ctx, c[account], opts) }},
+			{"Omnifocus", func() ([]string, error) { return nil, checkOmnifocus(account, c[account], opts) }},
+		}
+		for _, check := range checks {
+			notes, err := check.f()
+			if err == nil {
+				fmt.Fprintf(w, "%s: %s: ok\n", account, check.name)
+			} else {
+				failed++
+				fmt.Fprintf(w, "%s: %s: %v\n", account, check.name, err)
+			}
+			for _, n := range notes {
+				fmt.Fprintf(w, "  %s\n", n)
+			}
+			if fix := omnifocus.Remediation(err); fix != "" {
+				fmt.Fprintf(w, "  %s\n", fix)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
+
+// checkGitHub checks the account's token is accepted by GitHub and has
+// the scopes the account's categories need. Notes say when the token
+// expires and which categories may be missing items for its scopes.
+func checkGitHub(ctx context.Context, c internal.GithubConfig, opts runOptions) ([]string, error) {
+	ghg, err := newGitHubGateway(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = ghg.Login(); err != nil {
+		return nil, err
+	}
+	notes := []string{}
+	if expiry, ok := ghg.TokenExpiry(); ok {
+		n := fmt.Sprintf("GitHub token expires %s", expiry.Local().Format(time.DateTime))
+		if time.Until(expiry) < c.TokenExpiryWarning() {
+			n = "Warning: " + n
+		}
+		notes = append(notes, n)
+	}
+	scopes, ok := ghg.TokenScopes()
+	if !ok {
+		return notes, nil
+	}
+	missing, partial := missingScopes(scopes, neededScopes(c))
+	maps.DeleteFunc(missing, func(cat string, _ error) bool { return !opts.syncs(cat) })
+	maps.DeleteFunc(partial, func(cat string, _ []string) bool { return !opts.syncs(cat) || missing[cat] != nil })
+	for _, w := range partialScopeWarnings(partial) {
+		notes = append(notes, "Warning: "+w)
+	}
+	errs := []error{}
+	for _, cat := range slices.Sorted(maps.Keys(missing)) {
+		errs = append(errs, fmt.Errorf("%s: %w", cat, missing[cat]))
+	}
+	return notes, errors.Join(errs...)
+}
+
+// checkOmnifocus checks Omnifocus is running and its tasks can be read.
+// Reading tasks is what needs automation access, which macOS asks for on
+// the first run; if it was denied, this fails with ErrNotAuthorized.
+func checkOmnifocus(account string, c internal.GithubConfig, opts runOptions) error {
+	backend, err := accountBackend(c, opts)
+	if err != nil {
+		return err
+	}
+	og := newOmnifocusGateway(account, c, nil, backend)
+	running, err := og.Running(false)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return omnifocus.ErrNotRunning
+	}
+	_, err = og.GetPRs()
+	return err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+)
+
+// unauthorizedBackend fails to read tasks as macOS does when automation
+// access was denied.
+type unauthorizedBackend struct {
+	*omnifocus.FakeBackend
+}
+
+func (unauthorizedBackend) TasksForQuery(omnifocus.TaskQuery) ([]omnifocus.Task, error) {
+	return nil, &omnifocus.ScriptError{Err: omnifocus.ErrNotAuthorized, Stderr: "Error: Not authorized to send Apple events to OmniFocus. (-1743)"}
+}
+
+func TestDoctor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/user") {
+			_, _ = io.WriteString(w, `{"login": "me"}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	fake, err := omnifocus.NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := internal.Config{"work": {AccessToken: "token", APIURL: server.URL + "/"}}
+
+	var out bytes.Buffer
+	err = doctor(context.Background(), c, runOptions{backend: fake}, &out)
+	if err != nil {
+		t.Fatalf("Expected checks to pass, got: %v\n%s", err, out.String())
+	}
+	if out.String() != "work: GitHub: ok\nwork: Omnifocus: ok\n" {
+		t.Fatalf("Unexpected output: %s", out.String())
+	}
+
+	out.Reset()
+	err = doctor(context.Background(), c, runOptions{backend: unauthorizedBackend{fake}}, &out)
+	if err == nil {
+		t.Fatal("Expected an error when Omnifocus can't be controlled")
+	}
+	if !strings.Contains(out.String(), "work: Omnifocus: ") || !strings.Contains(out.String(), "Privacy & Security > Automation") {
+		t.Fatalf("Expected instructions to allow automation, got: %s", out.String())
+	}
+
+	out.Reset()
+	fake.Stopped = true
+	err = doctor(context.Background(), c, runOptions{backend: fake}, &out)
+	if err == nil || !strings.Contains(out.String(), "Open Omnifocus") {
+		t.Fatalf("Expected Omnifocus not running to be reported, got: %v\n%s", err, out.String())
+	}
+}
+
+func TestDoctorToken(t *testing.T) {
+	scopes := "public_repo"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-OAuth-Scopes", scopes)
+		w.Header().Set("GitHub-Authentication-Token-Expiration", time.Now().Add(48*time.Hour).UTC().Format("2006-01-02 15:04:05 MST"))
+		_, _ = io.WriteString(w, `{"login": "me"}`)
+	}))
+	defer server.Close()
+	fake, err := omnifocus.NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := internal.Config{"work": {AccessToken: "token", APIURL: server.URL + "/"}}
+
+	var out bytes.Buffer
+	err = doctor(context.Background(), c, runOptions{backend: fake}, &out)
+	if err == nil || !strings.Contains(out.String(), "notifications: the GitHub token lacks the notifications scope") {
+		t.Fatalf("Expected the missing notifications scope to be reported, got: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "  Warning: GitHub token expires ") {
+		t.Errorf("Expected the token's expiry to be warned about, got: %s", out.String())
+	}
+	if !strings.Contains(out.String(), "  Warning: PRs, authored PRs, failing checks, issues, mentions, tracked items may be missing items") {
+		t.Errorf("Expected the partial repo scope to be warned about, got: %s", out.String())
+	}
+
+	scopes = "repo, notifications"
+	out.Reset()
+	err = doctor(context.Background(), c, runOptions{backend: fake}, &out)
+	if err != nil {
+		t.Fatalf("Expected checks to pass, got: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "work: GitHub: ok\n  Warning: GitHub token expires ") || strings.Contains(out.String(), "missing items") {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+)
+
+// taskTarget is the project an item's task goes in, and its key.
+type taskTarget struct {
+	Project string
+	Key     string
+}
+
+// itemSource is the account and category an item came from.
+type itemSource struct {
+	Account  string
+	Category string
+}
+
+// taskTargets returns where the task for each item in desired goes, with
+// the account and categories the items came from.
+func taskTargets(account string, og omnifocus.Gateway, desired GHDesiredState) map[taskTarget][]itemSource {
+	projects := map[string]func(gh.GitHubItem) string{
+		"issues":           func(gh.GitHubItem) string { return og.AssignedProject },
+		"PRs":              func(gh.GitHubItem) string { return og.ReviewProject },
+		"authored PRs":     func(gh.GitHubItem) string { return og.PendingChangesProject },
+		"failing checks":   func(gh.GitHubItem) string { return og.FailingChecksProject },
+		"tracked items":    og.TrackingProject,
+		"watched releases": func(gh.GitHubItem) string { return og.WatchedReleasesProject },
+		"mentions":         og.MentionProject,
+		"notifications":    og.NotificationProject,
+	}
+	targets := map[taskTarget][]itemSource{}
+	for _, cat := range desired.Categories() {
+		for _, item := range cat.Items {
+			t := taskTarget{Project: projects[cat.Name](item), Key: item.Key()}
+			targets[t] = append(targets[t], itemSource{account, cat.Name})
+		}
+	}
+	return targets
+}
+
+// warnDuplicateKeys logs the items that share a key and project, when
+// shared says their sources do. Their tasks can't be told apart, so which
+// item a task is for can change from sync to sync.
+func warnDuplicateKeys(targets map[taskTarget][]itemSource, shared func([]itemSource) bool) {
+	for _, t := range slices.SortedFunc(maps.Keys(targets), func(a, b taskTarget) int {
+		return strings.Compare(a.Project+" "+a.Key, b.Project+" "+b.Key)
+	}) {
+		sources := targets[t]
+		if !shared(sources) {
+			continue
+		}
+		from := []string{}
+		for _, s := range sources {
+			from = append(from, s.Account+" "+s.Category)
+		}
+		log.Printf("Warning: %s in project %q comes from more than one place: %s", t.Key, t.Project, strings.Join(from, ", "))
+	}
+}
+
+// duplicated is true for more than one source.
+func duplicated(sources []itemSource) bool {
+	return len(sources) > 1
+}
+
+// acrossAccounts is true for sources from more than one account.
+func acrossAccounts(sources []itemSource) bool {
+	return slices.ContainsFunc(sources, func(s itemSource) bool { return s.Account != sources[0].Account })
+}
+
+// warnSharedKeys warns of items synced by more than one of the accounts
+// into the same project.
+func warnSharedKeys(stats []*syncStats) {
+	targets := map[taskTarget][]itemSource{}
+	for _, s := range stats {
+		for t, sources := range s.targets {
+			targets[t] = append(targets[t], sources...)
+		}
+	}
+	warnDuplicateKeys(targets, acrossAccounts)
+}
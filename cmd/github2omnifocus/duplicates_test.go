@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+)
+
+func TestTaskTargets(t *testing.T) {
+	og := omnifocus.Gateway{NotificationsProject: "GitHub", ReleasesProject: "Upgrades", WatchedReleasesProject: "Upgrades"}
+	desired := GHDesiredState{
+		WatchedReleases: []gh.GitHubItem{{K: "acme/tools@v2"}},
+		Notifications: []gh.GitHubItem{
+			{K: "acme/tools@v2", SubjectType: "Release"},
+			{K: "acme/tools#1", SubjectType: "Issue"},
+		},
+	}
+	targets := taskTargets("work", og, desired)
+
+	release := targets[taskTarget{"Upgrades", "acme/tools@v2"}]
+	if !duplicated(release) || release[0] != (itemSource{"work", "watched releases"}) || release[1] != (itemSource{"work", "notifications"}) {
+		t.Errorf("Expected the release to come from both categories, got: %v", targets)
+	}
+	if duplicated(targets[taskTarget{"GitHub", "acme/tools#1"}]) {
+		t.Errorf("Expected the issue notification to come from one place, got: %v", targets)
+	}
+}
+
+func TestAcrossAccounts(t *testing.T) {
+	if acrossAccounts([]itemSource{{"work", "issues"}, {"work", "PRs"}}) {
+		t.Error("Expected sources in one account not to be across accounts")
+	}
+	if !acrossAccounts([]itemSource{{"work", "issues"}, {"oss", "issues"}}) {
+		t.Error("Expected sources in two accounts to be across accounts")
+	}
+}
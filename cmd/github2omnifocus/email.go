@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+// sendMail is smtp.SendMail, replaced in tests.
+var sendMail = smtp.SendMail
+
+// notifyEmail emails a summary of an account's sync, started at started, to
+// its EmailTo addresses, if it has any and EmailOn says to. Failures are
+// logged, as they don't affect the sync.
+func notifyEmail(c internal.GithubConfig, started time.Time, s *syncStats) {
+	if len(c.EmailTo) == 0 || !shouldNotify(c.EmailOn, s) {
+		return
+	}
+	stats := []*syncStats{s}
+	result := newSyncResult(started, exitCode(stats, false), stats)
+	r := result.Accounts[0]
+
+	var auth smtp.Auth
+	if c.SMTPUsername != "" {
+		host, _, _ := net.SplitHostPort(c.SMTPServer)
+		auth = smtp.PlainAuth("", c.SMTPUsername, c.SMTPPassword, host)
+	}
+	err := sendMail(c.SMTPServer, auth, c.EmailFrom, c.EmailTo, emailMessage(c, r, result.Finished))
+	if err != nil {
+		log.Printf("[%s] Error sending summary email: %v", s.account, err)
+	}
+}
+
+// emailMessage returns the email summarising an account's sync, headers
+// and all.
+func emailMessage(c internal.GithubConfig, r accountResult, sent time.Time) []byte {
+	subject := fmt.Sprintf("github2omnifocus: %s synced", r.Account)
+	switch {
+	case r.Error != "":
+		subject = fmt.Sprintf("github2omnifocus: %s sync failed", r.Account)
+	case r.Skipped != "":
+		subject = fmt.Sprintf("github2omnifocus: %s sync skipped", r.Account)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", c.EmailFrom)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(c.EmailTo, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", sent.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&b, "%s\r\n", webhookText(r))
+	if r.Queued > 0 {
+		fmt.Fprintf(&b, "\r\n%d changes are queued for the next sync.\r\n", r.Queued)
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+func TestNotifyEmail(t *testing.T) {
+	type sent struct {
+		addr string
+		to   []string
+		msg  string
+	}
+	emails := []sent{}
+	sendMail = func(addr string, _ smtp.Auth, _ string, to []string, msg []byte) error {
+		emails = append(emails, sent{addr, to, string(msg)})
+		return nil
+	}
+	defer func() { sendMail = smtp.SendMail }()
+
+	unchanged := newSyncStats(context.Background(), "work")
+	failed := newSyncStats(context.Background(), "work")
+	failed.err = errors.New("Omnifocus quit")
+
+	c := internal.GithubConfig{SMTPServer: "localhost:25", EmailFrom: "g2o@example.com", EmailTo: []string{"me@example.com"}}
+	for _, s := range []*syncStats{unchanged, failed} {
+		notifyEmail(c, time.Now(), s)
+	}
+	if len(emails) != 1 {
+		t.Fatalf("Expected an email for the failed sync only, got: %v", emails)
+	}
+	if emails[0].addr != "localhost:25" || len(emails[0].to) != 1 || emails[0].to[0] != "me@example.com" {
+		t.Errorf("Unexpected email: %v", emails[0])
+	}
+	for _, want := range []string{"Subject: github2omnifocus: work sync failed\r\n", "\r\n\r\ngithub2omnifocus: work sync failed: Omnifocus quit\r\n"} {
+		if !strings.Contains(emails[0].msg, want) {
+			t.Errorf("Expected %q in email: %s", want, emails[0].msg)
+		}
+	}
+
+	emails = nil
+	c.EmailOn = internal.NotifyOnAlways
+	notifyEmail(c, time.Now(), unchanged)
+	if len(emails) != 1 || !strings.Contains(emails[0].msg, "Subject: github2omnifocus: work synced\r\n") {
+		t.Errorf("Expected an email for the unchanged sync, got: %v", emails)
+	}
+}
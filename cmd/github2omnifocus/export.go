@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+// exportItem is a single row of exported desired state.
+type exportItem struct {
+	Account  string   `json:"account"`
+	Category string   `json:"category"`
+	Key      string   `json:"key"`
+	Title    string   `json:"title"`
+	URL      string   `json:"url"`
+	Repo     string   `json:"repo"`
+	Labels   []string `json:"labels"`
+}
+
+// export implements the export command, which writes the items each
+// account would sync from GitHub without reading or changing Omnifocus, so
+// it works on any platform.
+func export(ctx context.Context, configFlag string, opts runOptions, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "json", "output format: json, csv or taskpaper")
+	account := fs.String("account", "", "export only the account called `name`")
+	only := fs.String("only", "", "export only these comma-separated `categories`, such as reviews,issues")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	opts.categories, err = parseCategories(*only)
+	if err != nil {
+		return err
+	}
+
+	write, ok := exportFormats[*format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q", *format)
+	}
+
+	configPath, err := internal.ConfigPath(configFlag)
+	if err != nil {
+		return err
+	}
+	c, err := internal.LoadConfig2(configPath)
+	if err != nil {
+		return err
+	}
+
+	accounts := make([]string, 0, len(c))
+	for name := range c {
+		accounts = append(accounts, name)
+	}
+	sort.Strings(accounts)
+	if *account != "" {
+		if _, ok := c[*account]; !ok {
+			return fmt.Errorf("no account called %q", *account)
+		}
+		accounts = []string{*account}
+	}
+
+	items, err := exportItems(ctx, c, opts, accounts)
+	if err != nil {
+		return err
+	}
+	return write(os.Stdout, items)
+}
+
+// exportItems fetches the items for each of accounts from GitHub.
+func exportItems(ctx context.Context, c internal.Config, opts runOptions, accounts []string) ([]exportItem, error) {
+	items := []exportItem{}
+	for _, account := range accounts {
+		stats := newSyncStats(ctx, account)
+		ghg, err := newGitHubGateway(stats.ctx, c[account], opts)
+		if err != nil {
+			return nil, err
+		}
+		desired := GetGitHubState(ghg, c[account], opts, nil, stats)
+		stats.end()
+		if err := desired.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %v", account, err)
+		}
+		for _, cat := range desired.Categories() {
+			// Some are fetched along with other categories.
+			if !opts.syncs(cat.Name) {
+				continue
+			}
+			for _, item := range cat.Items {
+				items = append(items, exportItem{
+					Account:  account,
+					Category: cat.Name,
+					Key:      item.Key(),
+					Title:    item.Title,
+					URL:      item.HTMLURL,
+					Repo:     item.Repo,
+					Labels:   item.Labels,
+				})
+			}
+		}
+	}
+	return items, nil
+}
+
+var exportFormats = map[string]func(io.Writer, []exportItem) error{
+	"json":      func(w io.Writer, items []exportItem) error { return writeJSON(w, items) },
+	"csv":       writeCSV,
+	"taskpaper": writeTaskPaper,
+}
+
+func writeCSV(w io.Writer, items []exportItem) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"account", "category", "key", "title", "url", "repo", "labels"})
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		err = cw.Write([]string{
+			item.Account, item.Category, item.Key, item.Title, item.URL, item.Repo,
+			strings.Join(item.Labels, ";"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTaskPaper writes a project per account and category, with the items
+// as tasks tagged with their repo and labels and the URL as a note.
+func writeTaskPaper(w io.Writer, items []exportItem) error {
+	project := ""
+	for _, item := range items {
+		p := item.Account + " " + item.Category
+		if p != project {
+			project = p
+			if _, err := fmt.Fprintf(w, "%s:\n", project); err != nil {
+				return err
+			}
+		}
+		tags := []string{taskPaperTag(item.Repo)}
+		for _, l := range item.Labels {
+			tags = append(tags, taskPaperTag(l))
+		}
+		_, err := fmt.Fprintf(w, "\t- %s %s @%s\n\t\t%s\n", item.Key, item.Title, strings.Join(tags, " @"), item.URL)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var taskPaperTagInvalid = regexp.MustCompile(`[^\pL\pN_.\-/]+`)
+
+// taskPaperTag makes s usable as a TaskPaper tag name, which can't contain
+// spaces or parentheses.
+func taskPaperTag(s string) string {
+	return taskPaperTagInvalid.ReplaceAllString(s, "_")
+}
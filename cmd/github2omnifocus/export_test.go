@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+func TestWriteTaskPaper(t *testing.T) {
+	items := []exportItem{
+		{Account: "work", Category: "issues", Key: "acme/tools#1", Title: "Fix it", URL: "https://github.com/acme/tools/issues/1", Repo: "acme/tools", Labels: []string{"good first issue"}},
+		{Account: "work", Category: "issues", Key: "acme/tools#2", Title: "Fix more", URL: "https://github.com/acme/tools/issues/2", Repo: "acme/tools"},
+		{Account: "work", Category: "PRs", Key: "acme/tools#3", Title: "Review me", URL: "https://github.com/acme/tools/pull/3", Repo: "acme/tools"},
+	}
+	var buf bytes.Buffer
+	err := writeTaskPaper(&buf, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "work issues:\n" +
+		"\t- acme/tools#1 Fix it @acme/tools @good_first_issue\n\t\thttps://github.com/acme/tools/issues/1\n" +
+		"\t- acme/tools#2 Fix more @acme/tools\n\t\thttps://github.com/acme/tools/issues/2\n" +
+		"work PRs:\n" +
+		"\t- acme/tools#3 Review me @acme/tools\n\t\thttps://github.com/acme/tools/pull/3\n"
+	if buf.String() != expected {
+		t.Fatalf("Didn't get expected TaskPaper, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	items := []exportItem{
+		{Account: "work", Category: "issues", Key: "acme/tools#1", Title: "Fix, it", URL: "u", Repo: "acme/tools", Labels: []string{"a", "b"}},
+	}
+	var buf bytes.Buffer
+	err := writeCSV(&buf, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "account,category,key,title,url,repo,labels\nwork,issues,acme/tools#1,\"Fix, it\",u,acme/tools,a;b\n"
+	if buf.String() != expected {
+		t.Fatalf("Didn't get expected CSV, got:\n%s", buf.String())
+	}
+}
+
+func TestExportItemsOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/search/issues") {
+			w.Write([]byte(`{"items": []}`))
+			return
+		}
+		w.Write([]byte(`[{"number": 1, "title": "Fix it", "html_url": "https://github.com/acme/tools/issues/1", "repository": {"full_name": "acme/tools"}}]`))
+	}))
+	defer server.Close()
+	c := internal.Config{"work": {APIURL: server.URL + "/", AccessToken: "token"}}
+
+	items, err := exportItems(context.Background(), c, runOptions{categories: []string{"issues"}}, []string{"work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Category != "issues" || items[0].Title != "Fix it" {
+		t.Fatalf("Expected just the issue, got: %+v", items)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+// itemCounts is the number of items fetched from GitHub in each category,
+// repo and label, as printed by the stats command.
+type itemCounts struct {
+	Total      int            `json:"total"`
+	Categories map[string]int `json:"categories"`
+	Repos      map[string]int `json:"repos"`
+	Labels     map[string]int `json:"labels"`
+}
+
+// countItems groups items by category, repo and label. Items with several
+// labels are counted under each of them.
+func countItems(items []exportItem) itemCounts {
+	counts := itemCounts{
+		Total:      len(items),
+		Categories: map[string]int{},
+		Repos:      map[string]int{},
+		Labels:     map[string]int{},
+	}
+	for _, item := range items {
+		counts.Categories[item.Category]++
+		if item.Repo != "" {
+			counts.Repos[item.Repo]++
+		}
+		for _, l := range item.Labels {
+			counts.Labels[l]++
+		}
+	}
+	return counts
+}
+
+// itemStats implements the stats command, which fetches each account's
+// items from GitHub, as export does, and prints how many there are by
+// category, repo and label.
+func itemStats(ctx context.Context, c internal.Config, opts runOptions, args []string, output string, w io.Writer) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	account := fs.String("account", "", "count only the account called `name`")
+	only := fs.String("only", "", "count only these comma-separated `categories`, such as reviews,issues")
+	top := fs.Int("top", 10, "list the `n` repos and labels with the most items")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	opts.categories, err = parseCategories(*only)
+	if err != nil {
+		return err
+	}
+	accounts := slices.Sorted(maps.Keys(c))
+	if *account != "" {
+		if _, ok := c[*account]; !ok {
+			return fmt.Errorf("no account called %q", *account)
+		}
+		accounts = []string{*account}
+	}
+
+	items, err := exportItems(ctx, c, opts, accounts)
+	if err != nil {
+		return err
+	}
+	counts := countItems(items)
+	if output == outputJSON {
+		return writeJSON(w, counts)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:gomnd
+	fmt.Fprintf(tw, "total\t%d\t\n", counts.Total)
+	for _, group := range []struct {
+		name   string
+		counts map[string]int
+		top    int
+	}{
+		{"category", counts.Categories, 0},
+		{"repo", counts.Repos, *top},
+		{"label", counts.Labels, *top},
+	} {
+		fmt.Fprintf(tw, "\nby %s\t\t\n", group.name)
+		for i, k := range mostFirst(group.counts) {
+			if group.top > 0 && i == group.top {
+				fmt.Fprintf(tw, "  and %d more\t\t\n", len(group.counts)-group.top)
+				break
+			}
+			fmt.Fprintf(tw, "  %s\t%d\t\n", k, group.counts[k])
+		}
+	}
+	return tw.Flush()
+}
+
+// mostFirst returns the keys of counts with the highest count first, then
+// by name.
+func mostFirst(counts map[string]int) []string {
+	return slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		if c := cmp.Compare(counts[b], counts[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+}
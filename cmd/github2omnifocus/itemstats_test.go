@@ -0,0 +1,23 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCountItems(t *testing.T) {
+	counts := countItems([]exportItem{
+		{Category: "issues", Repo: "acme/tools", Labels: []string{"bug", "p1"}},
+		{Category: "issues", Repo: "acme/web", Labels: []string{"bug"}},
+		{Category: "PRs", Repo: "acme/tools"},
+	})
+	if counts.Total != 3 || counts.Categories["issues"] != 2 || counts.Repos["acme/tools"] != 2 || counts.Labels["bug"] != 2 || counts.Labels["p1"] != 1 {
+		t.Fatalf("Unexpected counts: %+v", counts)
+	}
+	if got := mostFirst(counts.Labels); !slices.Equal(got, []string{"bug", "p1"}) {
+		t.Errorf("Expected most common label first, got %v", got)
+	}
+	if got := mostFirst(counts.Repos); !slices.Equal(got, []string{"acme/tools", "acme/web"}) {
+		t.Errorf("Expected most common repo first, got %v", got)
+	}
+}
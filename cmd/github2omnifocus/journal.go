@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+// journaled wraps actions so that each task added or completed is recorded
+// in stats, to be added to the account's journal.
+func journaled(stats *syncStats, category string, actions taskActions) taskActions {
+	return taskActions{
+		add: func(item gh.GitHubItem) error {
+			err := actions.add(item)
+			if err == nil {
+				stats.journal = append(stats.journal, state.Entry{
+					At: time.Now(), Op: state.JournalAdd, Category: category,
+					Key: item.Key(), Repo: item.Repo, Title: item.Title,
+				})
+			}
+			return err
+		},
+		complete: func(t omnifocus.Task) error {
+			err := actions.complete(t)
+			if err == nil {
+				stats.journal = append(stats.journal, state.Entry{
+					At: time.Now(), Op: state.JournalComplete, Category: category,
+					Key: t.Key(), Repo: repoOfKey(t.Key()), Title: t.Name,
+				})
+			}
+			return err
+		},
+		update:  actions.update,
+		retitle: actions.retitle,
+	}
+}
+
+// repoOfKey returns the repo part of an item's key, such as acme/tools for
+// acme/tools#12 or acme/tools@v1.0.
+func repoOfKey(key string) string {
+	if i := strings.IndexAny(key, "#@"); i != -1 {
+		return key[:i]
+	}
+	return key
+}
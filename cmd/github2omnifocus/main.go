@@ -2,47 +2,689 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/rhyshort/github-to-omnifocus/internal"
 	"github.com/rhyshort/github-to-omnifocus/internal/delta"
 	"github.com/rhyshort/github-to-omnifocus/internal/gh"
 	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
 )
 
 // Version can be overridden at build time using PROJECT_VERSION in the makefile.
 var Version = "development"
 
 type OFCurrentState struct {
-	Issues        []omnifocus.Task
-	PRs           []omnifocus.Task
-	Notifications []omnifocus.Task
-	AuthoredPRs   []omnifocus.Task
+	Issues          []omnifocus.Task
+	PRs             []omnifocus.Task
+	Notifications   []omnifocus.Task
+	AuthoredPRs     []omnifocus.Task
+	FailingChecks   []omnifocus.Task
+	TrackedItems    []omnifocus.Task
+	WatchedReleases []omnifocus.Task
+	Mentions        []omnifocus.Task
+	// Duplicates holds tasks that share a key with another task in the
+	// same category. They are left out of the lists above.
+	Duplicates []omnifocus.Task
+}
+
+// taskCategory is a named list of tasks of one type from the current state.
+type taskCategory struct {
+	name  string
+	tasks []omnifocus.Task
+}
+
+// categories returns the tasks in the current state grouped by type, in
+// the same order as GHDesiredState.Categories.
+func (s OFCurrentState) categories() []taskCategory {
+	return []taskCategory{
+		{"issues", s.Issues},
+		{"PRs", s.PRs},
+		{"authored PRs", s.AuthoredPRs},
+		{"failing checks", s.FailingChecks},
+		{"tracked items", s.TrackedItems},
+		{"watched releases", s.WatchedReleases},
+		{"mentions", s.Mentions},
+		{"notifications", s.Notifications},
+	}
 }
 
 type GHDesiredState struct {
-	Issues        []gh.GitHubItem
-	PRs           []gh.GitHubItem
-	Notifications []gh.GitHubItem
-	AuthoredPRs   []gh.GitHubItem
+	Issues          []gh.GitHubItem
+	PRs             []gh.GitHubItem
+	Notifications   []gh.GitHubItem
+	AuthoredPRs     []gh.GitHubItem
+	FailingChecks   []gh.GitHubItem
+	TrackedItems    []gh.GitHubItem
+	WatchedReleases []gh.GitHubItem
+	Mentions        []gh.GitHubItem
+	// Incomplete holds the names of categories that weren't fully fetched,
+	// as the request budget was used up or they're not being synced. Their
+	// tasks are only added to.
+	Incomplete map[string]bool
+	// Failed holds the error for each category that couldn't be fetched.
+	// These categories are also incomplete, and aren't synced at all.
+	Failed map[string]error
+	// Capped holds the keys of the items left out of each category by
+	// MaxItemsPerCategory. They're still open, so their tasks are left
+	// alone.
+	Capped map[string][]string
+	// Skipped holds the keys of the items each category's items couldn't
+	// be looked up for, such as the PRs whose workflow runs couldn't be
+	// fetched for failing checks. Tasks with keys under them are left
+	// alone.
+	Skipped map[string][]string
+}
+
+// Err returns the errors fetching the categories that failed, if any.
+func (s GHDesiredState) Err() error {
+	errs := []error{}
+	for _, name := range slices.Sorted(maps.Keys(s.Failed)) {
+		errs = append(errs, fmt.Errorf("couldn't fetch %s: %v", name, s.Failed[name]))
+	}
+	return errors.Join(errs...)
+}
+
+// errBudgetSkipped stands in for the error of a step skipped because the
+// request budget is used up.
+var errBudgetSkipped = errors.New("skipped as the GitHub request budget is used up")
+
+// category is a named list of items of one type from the desired state.
+type category struct {
+	Name  string
+	Items []gh.GitHubItem
+}
+
+// Categories returns the items in the desired state grouped by type.
+func (s GHDesiredState) Categories() []category {
+	return []category{
+		{"issues", s.Issues},
+		{"PRs", s.PRs},
+		{"authored PRs", s.AuthoredPRs},
+		{"failing checks", s.FailingChecks},
+		{"tracked items", s.TrackedItems},
+		{"watched releases", s.WatchedReleases},
+		{"mentions", s.Mentions},
+		{"notifications", s.Notifications},
+	}
+}
+
+// Keys returns the keys of every item in the desired state.
+func (s GHDesiredState) Keys() []string {
+	keys := []string{}
+	for _, c := range s.Categories() {
+		for _, item := range c.Items {
+			keys = append(keys, item.Key())
+		}
+	}
+	return keys
+}
+
+// runOptions are settings from the command line that apply to every
+// account synced.
+type runOptions struct {
+	// backend is used for all Omnifocus operations.
+	backend omnifocus.Backend
+	// recordGitHub and replayGitHub are directories to save GitHub
+	// responses to, or serve them from.
+	recordGitHub string
+	replayGitHub string
+	// categories, if set, limits the sync to the named categories. Tasks
+	// in the others are left as they are.
+	categories []string
+	// freezeAdditions stops tasks being added for every account, as
+	// FreezeAdditions does for one.
+	freezeAdditions bool
+}
+
+// syncs reports whether the category is synced.
+func (o runOptions) syncs(category string) bool {
+	return len(o.categories) == 0 || slices.Contains(o.categories, category)
+}
+
+// isCategory reports whether name is the name of a category of items.
+func isCategory(name string) bool {
+	return slices.ContainsFunc(GHDesiredState{}.Categories(), func(c category) bool { return c.Name == name })
+}
+
+// categoryAliases are shorter names for categories, for use on the command
+// line.
+var categoryAliases = map[string]string{
+	"reviews":  "PRs",
+	"authored": "authored PRs",
+	"checks":   "failing checks",
+	"tracked":  "tracked items",
+	"releases": "watched releases",
+}
+
+// parseCategories parses a comma-separated list of category names or
+// aliases, as given to --only.
+func parseCategories(s string) ([]string, error) {
+	categories := []string{}
+	if s == "" {
+		return categories, nil
+	}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if alias, ok := categoryAliases[name]; ok {
+			name = alias
+		}
+		if !isCategory(name) {
+			return nil, fmt.Errorf("unknown category %q", name)
+		}
+		categories = append(categories, name)
+	}
+	return categories, nil
 }
 
 func main() {
-	log.Printf("[main] Starting github2omnifocus; version: %s.", Version)
+	configFlag := flag.String("config", "", "load config from `path` rather than ~/.config/github2omnifocus")
+	fakeOmnifocus := flag.String("fake-omnifocus", "", "record Omnifocus changes in the JSON file at `path` instead of using Omnifocus")
+	recordGitHub := flag.String("record-github", "", "save every GitHub API response to `dir`")
+	replayGitHub := flag.String("replay-github", "", "serve GitHub API responses from `dir` saved by --record-github")
+	resultFile := flag.String("result-file", "", "write a JSON summary of the sync to `path`")
+	interval := flag.Duration("interval", 5*time.Minute, "time between syncs when running as a daemon")
+	socket := flag.String("socket", "", "serve the status API on the Unix socket at `path` when running as a daemon")
+	statusAddr := flag.String("status-addr", "", "serve a status dashboard and API at `addr`, such as localhost:8642, when running as a daemon")
+	pprofAddr := flag.String("pprof", "", "serve runtime profiles at `addr`, such as localhost:6060, when running as a daemon")
+	cpuProfile := flag.String("cpuprofile", "", "write a CPU profile of the run to `path`")
+	output := flag.String("output", outputText, "print results as `format`, text or json")
+	detailedExitCodes := flag.Bool("detailed-exit-codes", false, fmt.Sprintf("exit with %d rather than 0 when changes were applied", exitChangesApplied))
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [sync|daemon|plan|counts|doctor|stats|report|export|version|update]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *output != outputText && *output != outputJSON {
+		log.Fatalf("unknown output format %q", *output)
+	}
+
+	syncFlags := flag.NewFlagSet("sync", flag.ExitOnError)
+	only := syncFlags.String("only", "", "sync only these comma-separated `categories`, such as reviews,notifications")
+	jsonSummary := syncFlags.Bool("json", false, "print a JSON summary of the sync, as --output=json does")
+	freezeAdditions := syncFlags.Bool("freeze-additions", false, "complete tasks for closed items but don't add any, as FreezeAdditions does")
 
-	c, err := internal.LoadConfig2()
+	switch flag.Arg(0) {
+	case "sync":
+		err := syncFlags.Parse(flag.Args()[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "", "daemon", "plan", "counts", "doctor":
+	case "export":
+		opts := runOptions{recordGitHub: *recordGitHub, replayGitHub: *replayGitHub}
+		err := export(context.Background(), *configFlag, opts, flag.Args()[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "stats":
+		configPath, err := internal.ConfigPath(*configFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		c, err := internal.LoadConfig2(configPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts := runOptions{recordGitHub: *recordGitHub, replayGitHub: *replayGitHub}
+		err = itemStats(context.Background(), c, opts, flag.Args()[1:], *output, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "report":
+		configPath, err := internal.ConfigPath(*configFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		c, err := internal.LoadConfig2(configPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = report(c, flag.Args()[1:], os.Stdout, time.Now())
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "version":
+		err := printVersion(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "update":
+		err := selfUpdate(context.Background(), flag.Args()[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	default:
+		flag.Usage()
+		os.Exit(exitTotalFailure)
+	}
+
+	log.Printf("[main] Starting github2omnifocus; %s.", getBuildInfo())
+	started := time.Now()
+
+	configPath, err := internal.ConfigPath(*configFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c, err := internal.LoadConfig2(configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+	stopProfile := func() {}
+	if *cpuProfile != "" {
+		stopProfile, err = startCPUProfile(*cpuProfile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer stopProfile()
+	}
+	shutdownTracing, err := setupTracing(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := runOptions{
+		backend:         omnifocus.JXABackend{},
+		recordGitHub:    *recordGitHub,
+		replayGitHub:    *replayGitHub,
+		freezeAdditions: *freezeAdditions,
+	}
+	if *fakeOmnifocus != "" {
+		opts.backend, err = omnifocus.NewFakeBackend(*fakeOmnifocus)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Using fake Omnifocus at %s", *fakeOmnifocus)
+	}
+	opts.categories, err = parseCategories(*only)
 	if err != nil {
 		log.Fatal(err)
 	}
-	for _, v := range c {
-		sync_github(v)
+
+	if flag.Arg(0) == "counts" {
+		err = counts(ctx, c, opts, flag.Args()[1:], *output, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "plan" {
+		err = plan(ctx, c, opts, *output, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "doctor" {
+		err = doctor(ctx, c, opts, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "daemon" {
+		daemon(ctx, configPath, c, opts, *interval, *resultFile, *statusAddr, *socket, *pprofAddr)
+		err = shutdownTracing(ctx)
+		if err != nil {
+			log.Printf("Error flushing traces: %v", err)
+		}
+		return
+	}
+
+	stats := syncAll(ctx, c, opts)
+
+	err = shutdownTracing(ctx)
+	if err != nil {
+		log.Printf("Error flushing traces: %v", err)
+	}
+	// os.Exit skips deferred calls, so the profile is written now.
+	stopProfile()
+
+	code := exitCode(stats, *detailedExitCodes)
+	result := newSyncResult(started, code, stats)
+	if *resultFile != "" {
+		err = writeResultFile(*resultFile, result)
+		if err != nil {
+			log.Printf("Error writing result file: %v", err)
+		}
+	}
+	if *jsonSummary || *output == outputJSON {
+		err = writeJSON(os.Stdout, result)
+		if err != nil {
+			log.Printf("Error writing summary: %v", err)
+		}
+	}
+	os.Exit(code)
+}
+
+// syncAll syncs every account in c, logging a summary at the end. Each
+// account's sync is posted to its webhook and emailed, as configured.
+func syncAll(ctx context.Context, c internal.Config, opts runOptions) []*syncStats {
+	stats := []*syncStats{}
+	for name, v := range c {
+		started := time.Now()
+		s, err := sync_github(ctx, name, v, opts)
+		logFailure(name, err)
+		notifyWebhook(v, started, s)
+		notifyEmail(v, started, s)
+		stats = append(stats, s)
+	}
+	warnSharedKeys(stats)
+	logSummary(stats)
+	return stats
+}
+
+// logFailure logs why an account's sync failed, if it did, along with how
+// to fix it when that's known.
+func logFailure(account string, err error) {
+	if err == nil {
+		return
+	}
+	log.Printf("[%s] Sync failed: %v", account, err)
+	if fix := omnifocus.Remediation(err); fix != "" {
+		log.Printf("[%s] %s", account, fix)
 	}
 }
 
-func sync_github(c internal.GithubConfig) {
+// sync_github brings the Omnifocus tasks for an account into line with
+// GitHub. The returned stats are valid even if an error is returned.
+func sync_github(ctx context.Context, account string, c internal.GithubConfig, opts runOptions) (stats *syncStats, err error) {
+	stats = newSyncStats(ctx, account)
+	defer func() {
+		stats.err = err
+		stats.end()
+	}()
+
+	ignoreTags := ignoredTags(c)
+	cacheDir, err := internal.CacheDir()
+	if err != nil {
+		return stats, err
+	}
+	st, err := state.Load(state.Path(cacheDir, account))
+	if err != nil {
+		return stats, err
+	}
+	// The state is saved however the sync ends, as what's been done to
+	// GitHub and Omnifocus by then, such as comments posted for kept
+	// tasks, mustn't be repeated.
+	defer func() {
+		err = errors.Join(err, st.Save())
+	}()
+	defer func() {
+		err := state.AppendJournal(state.JournalPath(cacheDir, account), stats.journal, time.Now())
+		if err != nil {
+			log.Printf("[%s] Error writing journal: %v", account, err)
+		}
+	}()
 
-	ignoreTags := []string{c.AppTag, c.AssignedTag, c.ReviewTag, c.NotificationTag, c.PendingChangesTag, "no action"}
+	backend, err := accountBackend(c, opts)
+	if err != nil {
+		return stats, err
+	}
+	og := newOmnifocusGateway(account, c, st, backend)
+	if c.OmnifocusNotRunning != "" {
+		running, err := og.Running(c.OmnifocusNotRunning == internal.OmnifocusNotRunningLaunch)
+		if err != nil {
+			return stats, err
+		}
+		if !running {
+			if c.OmnifocusNotRunning == internal.OmnifocusNotRunningLaunch {
+				return stats, fmt.Errorf("Omnifocus didn't start after being launched")
+			}
+			log.Printf("[%s] Warning: skipping sync as Omnifocus isn't running", account)
+			stats.skipped = "Omnifocus isn't running"
+			return stats, nil
+		}
+	}
+	err = og.EnsureTags()
+	if err != nil {
+		return stats, err
+	}
+	err = og.EnsureProjectTypes()
+	if err != nil {
+		return stats, err
+	}
+	ghg, err := newGitHubGateway(stats.ctx, c, opts)
+	if err != nil {
+		return stats, err
+	}
+	defer func() {
+		if r, ok := ghg.Rate(); ok {
+			stats.rate = &r
+		}
+		if expiry, ok := ghg.TokenExpiry(); ok {
+			stats.tokenExpiry = &expiry
+			if time.Until(expiry) < c.TokenExpiryWarning() {
+				w := fmt.Sprintf("GitHub token expires %s", expiry.Local().Format(time.DateTime))
+				log.Printf("[%s] Warning: %s", account, w)
+				stats.warnings = append(stats.warnings, w)
+			}
+		}
+	}()
+
+	// Retrieve our current (from Omnifocus) and desired (from GitHub) states
+	currentState, err := GetOFState(og, stats)
+	if err != nil {
+		return stats, err
+	}
+	desiredState := GetGitHubState(ghg, c, opts, st, stats)
+	stats.targets = taskTargets(account, og, desiredState)
+	warnDuplicateKeys(stats.targets, duplicated)
+
+	// Failing to re-key tasks for renamed repos only means they're
+	// completed and added again.
+	err = stats.track("github: repo renames", func() error {
+		return handleRenamedRepos(&ghg, og, st, desiredState, &currentState)
+	})
+	if err != nil {
+		log.Printf("[%s] Warning: couldn't check for renamed repos: %v", account, err)
+	}
+
+	// Failing to check for re-requested reviews only leaves their tasks
+	// as they were.
+	err = stats.track("github: review re-requests", func() error {
+		return handleReRequests(&ghg, og, st, desiredState, &currentState)
+	})
+	if err != nil {
+		log.Printf("[%s] Warning: couldn't check for re-requested reviews: %v", account, err)
+	}
+
+	observed := desiredState.Keys()
+	for _, keys := range desiredState.Capped {
+		observed = append(observed, keys...)
+	}
+	for _, cat := range currentState.categories() {
+		for _, t := range cat.tasks {
+			if isSkipped(t.Key(), desiredState.Skipped[cat.name]) {
+				observed = append(observed, t.Key())
+			}
+		}
+	}
+	if len(desiredState.Incomplete) > 0 {
+		// Keep first-seen times for items that may not have been fetched.
+		for _, ts := range [][]omnifocus.Task{currentState.Issues, currentState.PRs, currentState.Notifications, currentState.AuthoredPRs, currentState.FailingChecks, currentState.TrackedItems, currentState.WatchedReleases, currentState.Mentions} {
+			for _, t := range ts {
+				observed = append(observed, t.Key())
+			}
+		}
+	}
+	st.Observe(observed, time.Now())
+	handleUncompleted(&ghg, c, st, desiredState, &currentState, time.Now())
+	handleDismissed(st, &desiredState, currentState, time.Now())
+	err = handleStaleNotifications(&ghg, og, c, st, &desiredState, &currentState, time.Now())
+	if err != nil {
+		return stats, err
+	}
+	err = handleCoveredNotifications(og, c, &desiredState, currentState)
+	if err != nil {
+		return stats, err
+	}
+
+	log.Printf("Current state: %d issues; %d PRs; %d notifications.", len(currentState.Issues), len(currentState.PRs), len(currentState.Notifications))
+	for _, t := range currentState.Duplicates {
+		if !c.CompleteDuplicateTasks {
+			log.Printf("Duplicate task for key %s, set CompleteDuplicateTasks to clean up: %s", t.Key(), t)
+			continue
+		}
+		log.Printf("Completing duplicate task for key %s: %s", t.Key(), t)
+		err := og.CompleteDuplicate(t)
+		if err != nil {
+			return stats, err
+		}
+	}
+	log.Printf("Desired state: %d issues; %d PRs; %d notifications.", len(desiredState.Issues), len(desiredState.PRs), len(desiredState.Notifications))
+
+	// Create the delta and apply it to Omnifocus.
+	// Items in other categories, such as a notification about a closed
+	// issue, share keys, so each category's keys are kept apart.
+	desiredKeys := map[string]map[string]bool{}
+	for _, cat := range desiredState.Categories() {
+		desiredKeys[cat.Name] = map[string]bool{}
+		for _, item := range cat.Items {
+			desiredKeys[cat.Name][item.Key()] = true
+		}
+	}
+	cm := completer{ghg: &ghg, og: og, c: c, st: st, desired: desiredKeys, now: time.Now()}
+	complete := func(category string, f func(omnifocus.Task) error) func(omnifocus.Task) error {
+		return recordCompletions(st, cm.forCategory(category, f))
+	}
+	updates := []func([]omnifocus.SyncedTask) error{}
+	if c.NoteMetadata {
+		updates = append(updates, og.UpdateNotes)
+	}
+	issueUpdates := slices.Clone(updates)
+	if c.IssueTaskLists {
+		issueUpdates = append(issueUpdates, og.UpdateTaskLists)
+	}
+	prUpdates := slices.Clone(updates)
+	if c.ProjectPriorityField != "" || c.ProjectIterationField != "" {
+		issueUpdates = append(issueUpdates, og.UpdateSchedules)
+		prUpdates = append(prUpdates, og.UpdateSchedules)
+	}
+	authoredUpdates := slices.Clone(updates)
+	if c.TrackPendingReviewers {
+		authoredUpdates = append(authoredUpdates, og.UpdateReviewers)
+	}
+	update, updateIssues, updatePRs, updateAuthored := updateSynced(updates), updateSynced(issueUpdates), updateSynced(prUpdates), updateSynced(authoredUpdates)
+	categories := []struct {
+		name    string
+		desired []gh.GitHubItem
+		current []omnifocus.Task
+		actions taskActions
+	}{
+		{"issues", desiredState.Issues, currentState.Issues, taskActions{og.AddIssue, complete("issues", og.CompleteIssue), updateIssues, og.Retitle}},
+		{"PRs", desiredState.PRs, currentState.PRs, taskActions{og.AddPR, complete("PRs", og.CompletePR), updatePRs, og.Retitle}},
+		{"authored PRs", desiredState.AuthoredPRs, currentState.AuthoredPRs, taskActions{og.AddAuthoredPR, complete("authored PRs", og.CompletePR), updateAuthored, og.Retitle}},
+		{"failing checks", desiredState.FailingChecks, currentState.FailingChecks, taskActions{og.AddFailingCheck, complete("failing checks", og.CompleteFailingCheck), update, og.Retitle}},
+		{"tracked items", desiredState.TrackedItems, currentState.TrackedItems, taskActions{og.AddTrackedItem, complete("tracked items", og.CompleteTrackedItem), update, og.Retitle}},
+		{"watched releases", desiredState.WatchedReleases, currentState.WatchedReleases, taskActions{og.AddWatchedRelease, complete("watched releases", og.CompleteWatchedRelease), update, og.Retitle}},
+		{"mentions", desiredState.Mentions, currentState.Mentions, taskActions{og.AddMention, complete("mentions", og.CompleteMention), update, og.Retitle}},
+		{"notifications", desiredState.Notifications, currentState.Notifications, taskActions{og.AddNotification, complete("notifications", og.CompleteNotification), update, og.Retitle}},
+	}
+	// Operations that failed on an earlier run are replayed first.
+	q, err := loadQueue(queuePath(cacheDir, account))
+	if err != nil {
+		return stats, err
+	}
+	defer func() { stats.queued = q.Ops }()
+	for _, cat := range categories {
+		if !opts.syncs(cat.name) || desiredState.Failed[cat.name] != nil {
+			continue
+		}
+		actions := journaled(stats, cat.name, cat.actions)
+		// Frozen additions apply to queued adds too, which are dropped as
+		// no longer needed, to be made by the delta once unfrozen.
+		desired := cat.desired
+		if c.FreezeAdditions || opts.freezeAdditions {
+			desired = withTasks(desired, cat.current)
+		}
+		desired, current, err := q.replay(stats, cat.name, desired, cat.current, actions)
+		if err != nil {
+			return stats, errors.Join(err, q.save())
+		}
+		current = withoutCapped(current, desiredState.Capped[cat.name])
+		current = withoutSkipped(current, desiredState.Skipped[cat.name])
+		keyOnly, current := compared(c, desiredState.Incomplete[cat.name], cat.name, desired, current)
+		err = applyDelta(stats, cat.name, keyOnly, desired, current, ignoreTags, q.wrap(cat.name, actions))
+		if err != nil {
+			return stats, errors.Join(err, q.save())
+		}
+	}
+	err = q.save()
+	if err != nil {
+		return stats, err
+	}
+	if q.err != nil {
+		return stats, fmt.Errorf("%d operations queued for the next run: %w", len(q.Ops), q.err)
+	}
+	// The other categories were synced, but the account still failed.
+	err = desiredState.Err()
+	stats.partial = err != nil
+	return stats, err
+}
+
+// ignoredTags returns the tags the app adds to tasks itself, which aren't
+// compared with the items' labels.
+func ignoredTags(c internal.GithubConfig) []string {
+	return []string{c.AppTag, c.AssignedTag, c.ReviewTag, c.NotificationTag, c.PendingChangesTag, c.FailingChecksTag, c.WatchedReleasesTag, c.TrackingTag, c.MentionsTag, "no action"}
+}
+
+// compared returns whether only keys are compared for a category, and the
+// current tasks to compare with desired. For a category that wasn't fully
+// fetched, only tasks are added: tasks for items that weren't fetched must
+// be left alone.
+func compared(c internal.GithubConfig, incomplete bool, category string, desired []gh.GitHubItem, current []omnifocus.Task) (bool, []omnifocus.Task) {
+	if !incomplete {
+		return c.KeyOnly(category), current
+	}
+	log.Printf("Only adding tasks for %s, as not all of them were fetched", category)
+	current = slices.DeleteFunc(slices.Clone(current), func(t omnifocus.Task) bool {
+		return !slices.ContainsFunc(desired, func(item gh.GitHubItem) bool { return item.Key() == t.Key() })
+	})
+	return true, current
+}
+
+// withTasks returns the items in desired that already have a task in
+// current, so that no tasks are added while additions are frozen. Tasks
+// for closed items are still completed.
+func withTasks(desired []gh.GitHubItem, current []omnifocus.Task) []gh.GitHubItem {
+	keys := toSet(current)
+	return slices.DeleteFunc(slices.Clone(desired), func(item gh.GitHubItem) bool {
+		if _, ok := keys[item.Key()]; !ok {
+			log.Printf("Not adding %s, as additions are frozen", item.Key())
+			return true
+		}
+		return false
+	})
+}
+
+// accountBackend returns the backend for driving Omnifocus for an account,
+// which is opts.backend unless that's the default JXA one.
+func accountBackend(c internal.GithubConfig, opts runOptions) (omnifocus.Backend, error) {
+	if _, ok := opts.backend.(omnifocus.JXABackend); ok {
+		return omnifocusBackend(c.OmnifocusDriver, c.OmnifocusDocument)
+	}
+	return opts.backend, nil
+}
+
+// newOmnifocusGateway creates the gateway to Omnifocus for an account.
+func newOmnifocusGateway(account string, c internal.GithubConfig, st *state.Store, backend omnifocus.Backend) omnifocus.Gateway {
 	// The due date we use is "end of today" which is 5pm local.
 	dueDate := time.Now().Local()
 	dueDate = time.Date(
@@ -55,117 +697,214 @@ func sync_github(c internal.GithubConfig) {
 		0,
 		dueDate.Location())
 
-	// Gateways are used to access Omnifocus and GitHub
 	og := omnifocus.Gateway{
-		AppTag:                  c.AppTag,
-		AssignedTag:             c.AssignedTag,
-		AssignedProject:         c.AssignedProject,
-		ReviewTag:               c.ReviewTag,
-		ReviewProject:           c.ReviewProject,
-		NotificationTag:         c.NotificationTag,
-		NotificationsProject:    c.NotificationsProject,
-		SetNotificationsDueDate: c.SetNotificationsDueDate,
-		SetTaskmasterDueDate:    c.SetTaskmasterDueDate,
-		TaskMasterTaskTag:       c.TaskMasterTaskTag,
-		DueDate:                 dueDate,
-		PendingChangesProject:   c.PendingChangesProject,
-		PendingChangesTag:       c.PendingChangesTag,
-	}
-	ghg, err := gh.NewGitHubGateway(context.Background(), c.AccessToken, c.APIURL)
-	if err != nil {
-		log.Fatal(err)
+		AppTag:                    c.AppTag,
+		AssignedTag:               c.AssignedTag,
+		AssignedProject:           c.AssignedProject,
+		ReviewTag:                 c.ReviewTag,
+		ReviewProject:             c.ReviewProject,
+		NotificationTag:           c.NotificationTag,
+		NotificationsProject:      c.NotificationsProject,
+		ReleasesProject:           c.ReleasesProject,
+		SecurityProject:           c.SecurityProject,
+		SetNotificationsDueDate:   c.SetNotificationsDueDate,
+		SetTaskmasterDueDate:      c.SetTaskmasterDueDate,
+		TaskMasterTaskTag:         c.TaskMasterTaskTag,
+		TaskMasterMonths:          c.TaskMasterMonths,
+		DueDate:                   dueDate,
+		PendingChangesProject:     c.PendingChangesProject,
+		PendingChangesTag:         c.PendingChangesTag,
+		FailingChecksProject:      c.FailingChecksProject,
+		FailingChecksTag:          c.FailingChecksTag,
+		WatchedReleasesProject:    c.WatchedReleasesProject,
+		WatchedReleasesTag:        c.WatchedReleasesTag,
+		MentionsTag:               c.MentionsTag,
+		TrackingTag:               c.TrackingTag,
+		ReviewDueIn:               c.ReviewDueIn,
+		IssueDueIn:                time.Duration(c.IssueDueInDays) * 24 * time.Hour,
+		WaitingDeferIn:            time.Duration(c.WaitingOnReviewersDeferHours) * time.Hour,
+		PriorityField:             c.ProjectPriorityField,
+		PriorityFlagged:           c.ProjectPriorityFlagged,
+		PriorityDueIn:             map[string]time.Duration{},
+		IterationField:            c.ProjectIterationField,
+		ReviewChecklist:           c.ReviewChecklist,
+		TrackingFolder:            c.TrackingFolder,
+		IssueTaskLists:            c.IssueTaskLists,
+		State:                     st,
+		Backend:                   backend,
+		NoteMetadata:              c.NoteMetadata,
+		Account:                   account,
+		APIURL:                    c.APIURL,
+		MaxTitleLength:            c.MaxTitleLength,
+		ProjectReviewIntervalDays: c.ProjectReviewIntervalDays,
+		ProjectFirstReviewIn:      time.Duration(c.ProjectFirstReviewDays) * 24 * time.Hour,
+		TrackingProjectType:       c.TrackingProjectType,
+		ProjectTypes:              c.ProjectTypesWithDefaults(),
+		NotificationsByRepo:       c.NotificationsByRepo,
+		TagCase:                   c.TagCase,
+	}
+	for priority, hours := range c.ProjectPriorityDueInHours {
+		og.PriorityDueIn[priority] = time.Duration(hours) * time.Hour
+	}
+	if c.DueDatePattern != "" {
+		// Already checked when the config was loaded.
+		og.DueDatePattern = regexp.MustCompile(c.DueDatePattern)
 	}
+	return og
+}
 
-	// Retrieve our current (from Omnifocus) and desired (from GitHub) states
-	currentState, err := GetOFState(og)
-	if err != nil {
-		log.Fatal(err)
+// omnifocusBackend returns the backend for driving the Omnifocus document
+// given the account's OmnifocusDriver.
+func omnifocusBackend(driver, document string) (omnifocus.Backend, error) {
+	switch driver {
+	case internal.OmnifocusDriverJXA:
+		return omnifocus.JXABackend{Document: document}, nil
+	case internal.OmnifocusDriverAppleScript:
+		return omnifocus.AppleScriptBackend{Document: document}, nil
+	}
+	return omnifocus.ProbeBackend(document)
+}
+
+// newGitHubGateway creates the gateway to GitHub for an account.
+func newGitHubGateway(ctx context.Context, c internal.GithubConfig, opts runOptions) (gh.GitHubGateway, error) {
+	// Replayed responses don't need credentials, which means a user's
+	// recording can be replayed by someone else.
+	token := ""
+	if opts.replayGitHub == "" {
+		var err error
+		token, err = c.Token()
+		if err != nil {
+			return gh.GitHubGateway{}, err
+		}
 	}
-	desiredState, err := GetGitHubState(ghg)
+	ghg, err := gh.NewGitHubGateway(ctx, token, c.APIURL, gh.TransportOptions{
+		ProxyURL:           c.ProxyURL,
+		CACertFile:         c.CACertFile,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		RecordDir:          opts.recordGitHub,
+		ReplayDir:          opts.replayGitHub,
+		MaxRequests:        c.MaxRequestsPerSync,
+	})
 	if err != nil {
-		log.Fatal(err)
+		return gh.GitHubGateway{}, err
 	}
+	ghg.SetSort(c.Sort())
+	ghg.SetQueries(c.SearchQueries)
+	return ghg, nil
+}
 
-	log.Printf("Current state: %d issues; %d PRs; %d notifications.", len(currentState.Issues), len(currentState.PRs), len(currentState.Notifications))
-	log.Printf("Desired state: %d issues; %d PRs; %d notifications.", len(desiredState.Issues), len(desiredState.PRs), len(desiredState.Notifications))
+// repoFilter returns the filter for c's RepoVisibility and ExcludeForks.
+func repoFilter(c internal.GithubConfig) gh.RepoFilter {
+	return gh.RepoFilter{
+		PublicOnly:   c.RepoVisibility == internal.RepoVisibilityPublic,
+		PrivateOnly:  c.RepoVisibility == internal.RepoVisibilityPrivate,
+		ExcludeForks: c.ExcludeForks,
+	}
+}
 
-	// Create the delta and apply it to Omnifocus.
-	// The awful looking *(d.Item.(*gh.GitHubItem)) casts are hacks, that we
-	// know to be true because we know how Delta works. I suspect this is a
-	// thing that generics will make easier as we can better smuggle the
-	// types through Delta rather than using the interface.
-
-	d := delta.Delta(toSet(desiredState.Issues), toSet(currentState.Issues), ignoreTags)
-	log.Printf("Found %d changes to apply to Issues", len(d))
-	for _, d := range d {
-		if d.Type == delta.Add {
-			err := og.AddIssue((d.Item.(gh.GitHubItem)))
-			if err != nil {
-				// should never fail
-				log.Fatal(err)
-			}
-		} else if d.Type == delta.Remove {
-			err := og.CompleteIssue((d.Item.(omnifocus.Task)))
-			if err != nil {
-				// should never fail
-				log.Fatal(err)
+// updateSynced returns a taskActions update that runs each of updates, or
+// nil if there are none.
+func updateSynced(updates []func([]omnifocus.SyncedTask) error) func([]delta.Pair[gh.GitHubItem, omnifocus.Task]) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	return func(pairs []delta.Pair[gh.GitHubItem, omnifocus.Task]) error {
+		synced := []omnifocus.SyncedTask{}
+		for _, p := range pairs {
+			synced = append(synced, omnifocus.SyncedTask{Item: p.Desired, Task: p.Current})
+		}
+		for _, update := range updates {
+			if err := update(synced); err != nil {
+				return err
 			}
 		}
+		return nil
 	}
+}
 
-	d = delta.Delta(toSet(desiredState.PRs), toSet(currentState.PRs), ignoreTags)
-	log.Printf("Found %d changes to apply to PRs", len(d))
-	for _, d := range d {
-		if d.Type == delta.Add {
-			err := og.AddPR((d.Item.(gh.GitHubItem)))
-			if err != nil {
-				// should never fail
-				log.Fatal(err)
-			}
-		} else if d.Type == delta.Remove {
-			err := og.CompletePR((d.Item.(omnifocus.Task)))
-			if err != nil {
-				// should never fail
-				log.Fatal(err)
-			}
+// taskActions change the tasks for one category in Omnifocus.
+type taskActions struct {
+	add      func(gh.GitHubItem) error
+	complete func(omnifocus.Task) error
+	// update, if set, is given the tasks that already match GitHub, with
+	// their items, so they can be refreshed in place.
+	update func([]delta.Pair[gh.GitHubItem, omnifocus.Task]) error
+	// retitle, if set, renames a task whose item's title has changed.
+	// Without it, the task is completed and added again.
+	retitle func(omnifocus.Task, gh.GitHubItem) error
+}
+
+// applyDelta works out the operations needed to bring the current tasks for
+// a category into line with the desired items, and applies them using
+// actions. If keyOnly, tasks are never re-created or renamed because their
+// tags or title differ.
+func applyDelta(
+	stats *syncStats,
+	category string,
+	keyOnly bool,
+	desired []gh.GitHubItem,
+	current []omnifocus.Task,
+	ignoreTags []string,
+	actions taskActions,
+) error {
+	var changes delta.Changes[gh.GitHubItem, omnifocus.Task]
+	_ = stats.track("delta", func() error {
+		if keyOnly {
+			changes = delta.KeysOnly(toSet(desired), toSet(current))
+		} else {
+			changes = delta.Delta(toSet(desired), toSet(current), ignoreTags)
 		}
-	}
+		return nil
+	})
+	log.Printf("Found %d changes to apply to %s", changes.Len(), category)
 
-	d = delta.Delta(toSet(desiredState.AuthoredPRs), toSet(currentState.AuthoredPRs), ignoreTags)
-	log.Printf("Found %d changes to apply to PRs", len(d))
-	for _, d := range d {
-		if d.Type == delta.Add {
-			err := og.AddAuthoredPR((d.Item.(gh.GitHubItem)))
-			if err != nil {
-				// should never fail
-				log.Fatal(err)
+	// apply counts op if it's applied rather than queued.
+	apply := func(op delta.OperationType, err error) error {
+		if errors.Is(err, errQueued) {
+			return nil
+		}
+		if err == nil {
+			stats.count(category, op)
+		}
+		return err
+	}
+	return stats.track("omnifocus: apply "+category, func() error {
+		for _, item := range changes.Adds {
+			log.Printf("add %s: %s", item.Key(), delta.Missing)
+			if err := apply(delta.Add, actions.add(item)); err != nil {
+				return err
 			}
-		} else if d.Type == delta.Remove {
-			err := og.CompletePR((d.Item.(omnifocus.Task)))
-			if err != nil {
-				// should never fail
-				log.Fatal(err)
+		}
+		for _, task := range changes.Removes {
+			log.Printf("remove %s: %s", task.Key(), delta.Extra)
+			if err := apply(delta.Remove, actions.complete(task)); err != nil {
+				return err
 			}
 		}
-	}
-
-	d = delta.Delta(toSet(desiredState.Notifications), toSet(currentState.Notifications), ignoreTags)
-	log.Printf("Found %d changes to apply to Notifications", len(d))
-	for _, d := range d {
-		if d.Type == delta.Add {
-			err := og.AddNotification((d.Item.(gh.GitHubItem)))
-			if err != nil {
-				// should never fail
-				log.Fatal(err)
+		// A changed title is renamed in place, keeping what's been added
+		// to the task. Tags can't be updated in place, so items whose tags
+		// changed are completed and re-added, losing any notes made on the
+		// task.
+		for _, m := range changes.Modifies {
+			log.Printf("modify %s: %s", m.Current.Key(), m.Explain())
+			if m.Reason == delta.TitleChanged && actions.retitle != nil {
+				if err := actions.retitle(m.Current, m.Desired); err != nil && !errors.Is(err, errQueued) {
+					return err
+				}
+				continue
 			}
-		} else if d.Type == delta.Remove {
-			err := og.CompleteNotification((d.Item.(omnifocus.Task)))
-			if err != nil {
-				// should never fail
-				log.Fatal(err)
+			if err := apply(delta.Remove, actions.complete(m.Current)); err != nil {
+				return err
+			}
+			if err := apply(delta.Add, actions.add(m.Desired)); err != nil {
+				return err
 			}
 		}
-	}
+		if actions.update != nil && len(changes.Unchanged) > 0 {
+			return actions.update(changes.Unchanged)
+		}
+		return nil
+	})
 }
 
 func toSet[T delta.Keyed](l []T) map[string]T {
@@ -185,59 +924,389 @@ func toSet[T delta.Keyed](l []T) map[string]T {
 	return r
 }
 
-// GetGitHubState retrieves the current state of our item types from GitHub
-func GetGitHubState(ghg gh.GitHubGateway) (GHDesiredState, error) {
-	ghState := GHDesiredState{}
-	var err error
+// GetGitHubState retrieves the current state of our item types from
+// GitHub. A category that fails to be fetched doesn't stop the others: it's
+// recorded in the state's Failed.
+func GetGitHubState(ghg gh.GitHubGateway, c internal.GithubConfig, opts runOptions, st *state.Store, stats *syncStats) GHDesiredState {
+	ghState := GHDesiredState{Incomplete: map[string]bool{}, Failed: map[string]error{}, Capped: map[string][]string{}, Skipped: map[string][]string{}}
 
-	ghState.Issues, err = ghg.GetIssues()
-	if err != nil {
-		return GHDesiredState{}, err
+	// checkScopes fails the categories the token lacks the scopes for, once
+	// a response has said what they are, rather than them coming back
+	// empty. Categories that would only miss some items are warned about.
+	scopesChecked := false
+	checkScopes := func() {
+		scopes, ok := ghg.TokenScopes()
+		if scopesChecked || !ok {
+			return
+		}
+		scopesChecked = true
+		missing, partial := missingScopes(scopes, neededScopes(c))
+		for cat, err := range missing {
+			if opts.syncs(cat) && ghState.Failed[cat] == nil {
+				log.Printf("Warning: %s won't be synced as %v", cat, err)
+				ghState.Incomplete[cat] = true
+				ghState.Failed[cat] = err
+			}
+		}
+		maps.DeleteFunc(partial, func(cat string, _ []string) bool { return !opts.syncs(cat) || missing[cat] != nil })
+		for _, w := range partialScopeWarnings(partial) {
+			log.Printf("[%s] Warning: %s", stats.account, w)
+			stats.warnings = append(stats.warnings, w)
+		}
 	}
-	ghState.PRs, err = ghg.GetPRs()
-	if err != nil {
-		return GHDesiredState{}, err
+
+	// fetch runs a step that retrieves the items for categories from
+	// GitHub. Once the request budget is used up, the categories are
+	// marked incomplete rather than the sync failing, and later steps for
+	// any category are skipped. If a step fails otherwise, its categories
+	// are marked failed, and later steps only for failed categories are
+	// skipped. Steps for categories that aren't being synced are skipped
+	// too.
+	fetch := func(name string, categories []string, f func() error) {
+		if !slices.ContainsFunc(categories, opts.syncs) {
+			for _, c := range categories {
+				ghState.Incomplete[c] = true
+			}
+			return
+		}
+		// There's nothing left to fetch once all the categories failed.
+		if !slices.ContainsFunc(categories, func(c string) bool { return ghState.Failed[c] == nil }) {
+			return
+		}
+		err := errBudgetSkipped
+		if !ghg.BudgetExhausted() {
+			err = stats.track(name, f)
+		}
+		checkScopes()
+		if err == nil {
+			return
+		}
+		if ghg.BudgetExhausted() {
+			log.Printf("Warning: %s incomplete as the GitHub request budget is used up", name)
+		} else {
+			log.Printf("Warning: %s failed, so its categories won't be synced: %v", name, err)
+		}
+		for _, c := range categories {
+			ghState.Incomplete[c] = true
+			if !ghg.BudgetExhausted() && ghState.Failed[c] == nil {
+				ghState.Failed[c] = fmt.Errorf("%s: %v", name, err)
+			}
+		}
 	}
 
-	ghState.AuthoredPRs, err = ghg.GetOpenPRs()
-	if err != nil {
-		return GHDesiredState{}, err
+	fetch("github: issues", []string{"issues", "tracked items"}, func() (err error) {
+		ghState.Issues, err = ghg.GetIssues()
+		if err != nil {
+			return err
+		}
+		ghState.Issues, err = ghg.AddSearchedIssues(ghState.Issues)
+		return err
+	})
+	fetch("github: PRs", []string{"PRs"}, func() (err error) {
+		ghState.PRs, err = ghg.GetPRs(c.DirectReviewRequestsOnly)
+		return err
+	})
+
+	fetch("github: authored PRs", []string{"authored PRs", "failing checks"}, func() (err error) {
+		ghState.AuthoredPRs, err = ghg.GetOpenPRs()
+		return err
+	})
+
+	fetch("github: mentions", []string{"mentions"}, func() (err error) {
+		if c.MentionsTag == "" {
+			return nil
+		}
+		ghState.Mentions, err = ghg.GetMentions()
+		return err
+	})
+
+	repos := repoFilter(c)
+	if !repos.IsZero() {
+		// This comes before anything else is fetched for these items.
+		fetch("github: repo filter", []string{"issues", "tracked items", "PRs", "authored PRs", "failing checks", "mentions"}, func() error {
+			// Every list is filtered even if one fails, so none is left
+			// with items from repos that are left out.
+			errs := []error{}
+			for _, items := range []*[]gh.GitHubItem{&ghState.Issues, &ghState.PRs, &ghState.AuthoredPRs, &ghState.Mentions} {
+				kept, err := ghg.FilterRepos(*items, repos)
+				*items = kept
+				errs = append(errs, err)
+			}
+			return errors.Join(errs...)
+		})
 	}
 
-	ghState.Notifications, err = ghg.GetNotifications()
-	if err != nil {
-		return GHDesiredState{}, err
+	if c.RepoTopicsAsTags {
+		// Only these categories' tasks are tagged with labels.
+		fetch("github: repo topics", []string{"issues", "PRs", "authored PRs"}, func() error {
+			for _, items := range []*[]gh.GitHubItem{&ghState.Issues, &ghState.PRs, &ghState.AuthoredPRs} {
+				withTopics, err := ghg.AddRepoTopics(*items)
+				if err != nil {
+					return err
+				}
+				*items = withTopics
+			}
+			return nil
+		})
 	}
 
-	return ghState, nil
-}
+	if len(c.PRSizeThresholds) > 0 {
+		fetch("github: PR sizes", []string{"PRs"}, func() error {
+			sized, err := ghg.AddPRSizes(ghState.PRs)
+			if err != nil {
+				return err
+			}
+			for i := range sized {
+				sized[i] = sized[i].WithSizeLabel(c.PRSizeThresholds)
+			}
+			ghState.PRs = sized
+			return nil
+		})
+	}
 
-// GetOFState retrieves the current state of our item types from Omnifocus
-func GetOFState(og omnifocus.Gateway) (OFCurrentState, error) {
-	ofState := OFCurrentState{}
-	var err error
+	if c.AuthorTags {
+		for _, items := range [][]gh.GitHubItem{ghState.Issues, ghState.PRs} {
+			for i := range items {
+				items[i] = items[i].WithAuthorLabel()
+			}
+		}
+	}
 
-	ofState.Issues, err = og.GetIssues()
-	if err != nil {
-		return OFCurrentState{}, err
+	for _, issue := range ghState.Issues {
+		if issue.HasLabel(c.TrackingIssueLabels) {
+			ghState.TrackedItems = append(ghState.TrackedItems, gh.TrackedItems(issue)...)
+		}
 	}
-	ofState.PRs, err = og.GetPRs()
-	if err != nil {
-		return OFCurrentState{}, err
+
+	// After finding tracked items, so their projects keep the plain title.
+	if len(c.LabelEmoji) > 0 {
+		for _, items := range [][]gh.GitHubItem{ghState.Issues, ghState.PRs, ghState.AuthoredPRs} {
+			for i := range items {
+				items[i] = items[i].WithLabelEmoji(c.LabelEmoji)
+			}
+		}
 	}
-	ofState.Notifications, err = og.GetNotifications()
-	if err != nil {
-		return OFCurrentState{}, err
+
+	if c.ProjectPriorityField != "" || c.ProjectIterationField != "" {
+		fetch("github: project fields", []string{"issues", "PRs"}, func() error {
+			for _, items := range []*[]gh.GitHubItem{&ghState.Issues, &ghState.PRs} {
+				withFields, err := ghg.AddProjectFields(*items)
+				if err != nil {
+					return err
+				}
+				*items = withFields
+			}
+			return nil
+		})
 	}
 
-	ofState.AuthoredPRs, err = og.GetAuthoredPRs()
-	if err != nil {
-		return OFCurrentState{}, err
+	if c.TrackPendingReviewers {
+		fetch("github: pending reviewers", []string{"authored PRs"}, func() error {
+			withReviewers, err := ghg.AddPendingReviewers(ghState.AuthoredPRs)
+			if err != nil {
+				return err
+			}
+			ghState.AuthoredPRs = withReviewers
+			return nil
+		})
+	}
+
+	if c.TrackWaitingOn {
+		fetch("github: waiting on", []string{"authored PRs"}, func() error {
+			withWaiting, err := ghg.AddWaitingOn(ghState.AuthoredPRs)
+			if err != nil {
+				return err
+			}
+			for i := range withWaiting {
+				withWaiting[i] = withWaiting[i].WithWaitingLabel()
+			}
+			ghState.AuthoredPRs = withWaiting
+			return nil
+		})
+	}
+
+	if c.FailingChecksProject != "" {
+		fetch("github: failing checks", []string{"failing checks"}, func() (err error) {
+			ghState.FailingChecks, ghState.Skipped["failing checks"], err = ghg.GetFailingWorkflows(ghState.AuthoredPRs)
+			return err
+		})
+	}
+
+	// Without WatchedReleases, this makes no requests.
+	fetch("github: watched releases", []string{"watched releases"}, func() (err error) {
+		ghState.WatchedReleases, err = ghg.GetLatestReleases(c.WatchedReleases)
+		if err != nil || c.WatchedReleaseDays == 0 {
+			return err
+		}
+		// Old releases' tasks are completed.
+		since := time.Now().AddDate(0, 0, -c.WatchedReleaseDays)
+		ghState.WatchedReleases = slices.DeleteFunc(ghState.WatchedReleases, func(item gh.GitHubItem) bool {
+			return item.UpdatedAt.Before(since)
+		})
+		return nil
+	})
+
+	fetch("github: notifications", []string{"notifications"}, func() (err error) {
+		ghState.Notifications, err = getNotifications(ghg, c, st, time.Now())
+		if err != nil {
+			return err
+		}
+		ghState.Notifications, err = ghg.ResolveInvitations(ghState.Notifications)
+		if err != nil {
+			return err
+		}
+		if c.SuppressOwnActivity {
+			login, err := ghg.Login()
+			if err != nil {
+				return err
+			}
+			ghState.Notifications = withoutOwnActivity(ghState.Notifications, login)
+		}
+		if !repos.IsZero() {
+			ghState.Notifications, err = ghg.FilterRepos(ghState.Notifications, repos)
+		}
+		return err
+	})
+
+	if c.MaxItemsPerCategory > 0 {
+		capCategories(&ghState, c, stats)
+	}
+
+	// Clean titles as they will be in task names, so they compare equal.
+	for _, cat := range ghState.Categories() {
+		for i := range cat.Items {
+			cat.Items[i].Title = omnifocus.CleanTitle(cat.Items[i].Title, c.MaxTitleLength)
+		}
+	}
+
+	return ghState
+}
+
+// capCategories keeps only the first MaxItemsPerCategory items of each
+// category, in the order c.Sort gives, recording the keys of the rest in
+// s.Capped. The category was still fully fetched, so tasks for items in
+// neither are completed as usual.
+func capCategories(s *GHDesiredState, c internal.GithubConfig, stats *syncStats) {
+	limit := c.MaxItemsPerCategory
+	sort, order := c.Sort()
+	for _, cat := range []struct {
+		name  string
+		items *[]gh.GitHubItem
+	}{
+		{"issues", &s.Issues},
+		{"PRs", &s.PRs},
+		{"authored PRs", &s.AuthoredPRs},
+		{"failing checks", &s.FailingChecks},
+		{"tracked items", &s.TrackedItems},
+		{"watched releases", &s.WatchedReleases},
+		{"mentions", &s.Mentions},
+		{"notifications", &s.Notifications},
+	} {
+		if len(*cat.items) <= limit {
+			continue
+		}
+		if sort == "updated" {
+			// Items come from more than one list, such as searches added
+			// to REST results, or from lists GitHub doesn't sort this way.
+			slices.SortStableFunc(*cat.items, func(a, b gh.GitHubItem) int {
+				if order == "asc" {
+					return a.UpdatedAt.Compare(b.UpdatedAt)
+				}
+				return b.UpdatedAt.Compare(a.UpdatedAt)
+			})
+		}
+		w := fmt.Sprintf("only syncing %d of %d %s, as MaxItemsPerCategory is %d", limit, len(*cat.items), cat.name, limit)
+		log.Printf("[%s] Warning: %s", stats.account, w)
+		stats.warnings = append(stats.warnings, w)
+		for _, item := range (*cat.items)[limit:] {
+			s.Capped[cat.name] = append(s.Capped[cat.name], item.Key())
+		}
+		*cat.items = (*cat.items)[:limit]
+	}
+}
+
+// withoutCapped returns current without the tasks for the items capped
+// left out, which mustn't be completed.
+func withoutCapped(current []omnifocus.Task, capped []string) []omnifocus.Task {
+	if len(capped) == 0 {
+		return current
+	}
+	return slices.DeleteFunc(slices.Clone(current), func(t omnifocus.Task) bool {
+		return slices.Contains(capped, t.Key())
+	})
+}
+
+// withoutSkipped returns current without the tasks for the items under the
+// keys in skipped, whose items couldn't be looked up.
+func withoutSkipped(current []omnifocus.Task, skipped []string) []omnifocus.Task {
+	if len(skipped) == 0 {
+		return current
+	}
+	return slices.DeleteFunc(slices.Clone(current), func(t omnifocus.Task) bool {
+		return isSkipped(t.Key(), skipped)
+	})
+}
+
+// isSkipped reports whether key is under one of the keys in skipped, as
+// acme/tools#1/ci/5 is under acme/tools#1.
+func isSkipped(key string, skipped []string) bool {
+	return slices.ContainsFunc(skipped, func(k string) bool { return strings.HasPrefix(key, k+"/") })
+}
+
+// GetOFState retrieves the current state of our item types from Omnifocus.
+// Tasks sharing a key with an earlier task in the same category are moved
+// into Duplicates rather than being silently dropped by toSet.
+func GetOFState(og omnifocus.Gateway, stats *syncStats) (OFCurrentState, error) {
+	ofState := OFCurrentState{}
+
+	categories := []struct {
+		name  string
+		tasks *[]omnifocus.Task
+		get   func() ([]omnifocus.Task, error)
+	}{
+		{"issues", &ofState.Issues, og.GetIssues},
+		{"PRs", &ofState.PRs, og.GetPRs},
+		{"notifications", &ofState.Notifications, og.GetNotifications},
+		{"authored PRs", &ofState.AuthoredPRs, og.GetAuthoredPRs},
+		{"failing checks", &ofState.FailingChecks, og.GetFailingChecks},
+		{"tracked items", &ofState.TrackedItems, og.GetTrackedItems},
+		{"watched releases", &ofState.WatchedReleases, og.GetWatchedReleases},
+		{"mentions", &ofState.Mentions, og.GetMentions},
+	}
+	for _, c := range categories {
+		var tasks []omnifocus.Task
+		err := stats.track("omnifocus: "+c.name, func() (err error) {
+			tasks, err = c.get()
+			return err
+		})
+		if err != nil {
+			return OFCurrentState{}, err
+		}
+		var dups []omnifocus.Task
+		*c.tasks, dups = splitDuplicates(tasks)
+		ofState.Duplicates = append(ofState.Duplicates, dups...)
 	}
 
 	return ofState, nil
 }
 
+// splitDuplicates returns the tasks with unique keys, keeping the first task
+// seen for each key, and separately the extra tasks that repeat a key.
+func splitDuplicates(tasks []omnifocus.Task) ([]omnifocus.Task, []omnifocus.Task) {
+	seen := map[string]bool{}
+	unique := []omnifocus.Task{}
+	dups := []omnifocus.Task{}
+	for _, t := range tasks {
+		if seen[t.Key()] {
+			dups = append(dups, t)
+			continue
+		}
+		seen[t.Key()] = true
+		unique = append(unique, t)
+	}
+	return unique, dups
+}
+
 // func exerciseGitHubClient(c internal.Config) error {
 
 // 	ctx := context.Background()
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/delta"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+func TestSyncSkippedWhenOmnifocusNotRunning(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	fake, err := omnifocus.NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.Stopped = true
+
+	c := internal.GithubConfig{OmnifocusNotRunning: internal.OmnifocusNotRunningSkip}
+	stats, err := sync_github(context.Background(), "work", c, runOptions{backend: fake})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.skipped == "" {
+		t.Fatal("Expected sync to be skipped")
+	}
+	if len(fake.Tags) > 0 || len(fake.Operations) > 0 {
+		t.Fatalf("Expected Omnifocus to be left alone, got: %v %v", fake.Tags, fake.Operations)
+	}
+}
+
+func TestGetGitHubStateBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every page links to another, so only the budget stops paging.
+		w.Header().Set("Link", `<http://`+r.Host+r.URL.Path+`?page=2>; rel="next"`)
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	ghg, err := gh.NewGitHubGateway(context.Background(), "", server.URL+"/", gh.TransportOptions{MaxRequests: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := GetGitHubState(ghg, internal.GithubConfig{}, runOptions{}, nil, newSyncStats(context.Background(), "work"))
+	if err := state.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, cat := range state.Categories() {
+		if !state.Incomplete[cat.Name] {
+			t.Fatalf("Expected %s to be incomplete, got: %v", cat.Name, state.Incomplete)
+		}
+	}
+}
+
+func TestGetGitHubStateFailedCategory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/notifications"):
+			http.Error(w, `{"message": "Missing the notifications scope"}`, http.StatusForbidden)
+		case strings.HasSuffix(r.URL.Path, "/search/issues"):
+			w.Write([]byte(`{"items": []}`))
+		case strings.HasSuffix(r.URL.Path, "/issues"):
+			w.Write([]byte(`[{"number": 1, "repository": {"full_name": "acme/tools"}}]`))
+		case strings.HasSuffix(r.URL.Path, "/user"):
+			w.Write([]byte(`{"login": "octocat"}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+	ghg, err := gh.NewGitHubGateway(context.Background(), "", server.URL+"/", gh.TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := GetGitHubState(ghg, internal.GithubConfig{}, runOptions{}, nil, newSyncStats(context.Background(), "work"))
+	if len(state.Failed) != 1 || state.Failed["notifications"] == nil || !state.Incomplete["notifications"] {
+		t.Fatalf("Expected only notifications to fail, got: %v", state.Failed)
+	}
+	if len(state.Issues) != 1 || state.Incomplete["issues"] {
+		t.Errorf("Expected issues to be fetched anyway, got: %v", state.Issues)
+	}
+	if err := state.Err(); err == nil || !strings.Contains(err.Error(), "couldn't fetch notifications") {
+		t.Errorf("Expected the notifications error, got: %v", err)
+	}
+}
+
+func TestGetGitHubStateMissingScope(t *testing.T) {
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("X-OAuth-Scopes", "public_repo, user")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/search/issues"):
+			w.Write([]byte(`{"items": []}`))
+		case strings.HasSuffix(r.URL.Path, "/user"):
+			w.Write([]byte(`{"login": "octocat"}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+	ghg, err := gh.NewGitHubGateway(context.Background(), "", server.URL+"/", gh.TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := newSyncStats(context.Background(), "work")
+	state := GetGitHubState(ghg, internal.GithubConfig{}, runOptions{}, nil, stats)
+	if err := state.Failed["notifications"]; err == nil || !strings.Contains(err.Error(), "notifications scope") {
+		t.Errorf("Expected notifications to fail for the notifications scope, got: %v", err)
+	}
+	if slices.Contains(paths, "/api/v3/notifications") {
+		t.Errorf("Expected notifications not to be fetched, got: %v", paths)
+	}
+	// public_repo still finds items in public repos, so the categories that
+	// search private ones are only warned about.
+	for _, cat := range []string{"issues", "tracked items", "PRs", "authored PRs", "failing checks"} {
+		if err := state.Failed[cat]; err != nil {
+			t.Errorf("Expected %s to be fetched, got: %v", cat, err)
+		}
+	}
+	if len(stats.warnings) != 1 || !strings.Contains(stats.warnings[0], "lacks the repo scope") {
+		t.Errorf("Expected a warning about the repo scope, got: %v", stats.warnings)
+	}
+}
+
+func TestGetGitHubStateMentions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/search/issues") && strings.Contains(r.URL.Query().Get("q"), "mentions:"):
+			w.Write([]byte(`{"items": [
+				{"number": 1, "repository": {"full_name": "acme/tools"}},
+				{"number": 2, "repository": {"full_name": "acme/tools"}}
+			]}`))
+		case strings.HasSuffix(r.URL.Path, "/search/issues"):
+			w.Write([]byte(`{"items": []}`))
+		case strings.HasSuffix(r.URL.Path, "/notifications"):
+			w.Write([]byte(`[{"id": "5", "unread": true, "repository": {"full_name": "acme/tools", "name": "tools", "owner": {"login": "acme"}},
+				"subject": {"type": "Issue", "url": "http://` + r.Host + `/api/v3/repos/acme/tools/issues/2"}}]`))
+		case strings.HasSuffix(r.URL.Path, "/user"):
+			w.Write([]byte(`{"login": "octocat"}`))
+		case strings.HasSuffix(r.URL.Path, "/issues/2"):
+			w.Write([]byte(`{"html_url": "https://github.com/acme/tools/issues/2"}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+	ghg, err := gh.NewGitHubGateway(context.Background(), "", server.URL+"/", gh.TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desired := GetGitHubState(ghg, internal.GithubConfig{MentionsTag: "mentioned"}, runOptions{}, nil, newSyncStats(context.Background(), "work"))
+	if err := desired.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(desired.Notifications) != 1 || desired.Notifications[0].Key() != "acme/tools#2" {
+		t.Fatalf("Expected the notification for acme/tools#2, got: %v", desired.Notifications)
+	}
+	if len(desired.Mentions) != 2 {
+		t.Fatalf("Expected both mentions, got: %v", desired.Mentions)
+	}
+
+	st, err := state.Load(path.Join(t.TempDir(), "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mentions := slices.Clone(desired.Mentions)
+	handleDismissed(st, &desired, OFCurrentState{}, time.Now())
+	if len(desired.Mentions) != 1 || desired.Mentions[0].Key() != "acme/tools#1" {
+		t.Errorf("Expected only the mention without a notification, got: %v", desired.Mentions)
+	}
+	// Once the notification's read, its mention doesn't get a task.
+	read := GHDesiredState{Mentions: mentions}
+	handleDismissed(st, &read, OFCurrentState{}, time.Now())
+	if len(read.Mentions) != 1 || read.Mentions[0].Key() != "acme/tools#1" {
+		t.Errorf("Expected the read notification's mention to stay seen to, got: %v", read.Mentions)
+	}
+}
+
+func TestGetGitHubStateCategories(t *testing.T) {
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	ghg, err := gh.NewGitHubGateway(context.Background(), "", server.URL+"/", gh.TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := runOptions{categories: []string{"notifications"}}
+	state := GetGitHubState(ghg, internal.GithubConfig{}, opts, nil, newSyncStats(context.Background(), "work"))
+	if err := state.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, cat := range state.Categories() {
+		if state.Incomplete[cat.Name] == opts.syncs(cat.Name) {
+			t.Errorf("Expected only categories not synced to be incomplete, got: %v", state.Incomplete)
+		}
+	}
+	if len(paths) != 1 || paths[0] != "/api/v3/notifications" {
+		t.Errorf("Expected only notifications to be fetched, got: %v", paths)
+	}
+}
+
+func TestCapCategories(t *testing.T) {
+	day := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s := GHDesiredState{
+		// Searched items come after REST ones, so aren't in order.
+		PRs:        []gh.GitHubItem{{K: "acme/tools#3", UpdatedAt: day}, {K: "acme/tools#1", UpdatedAt: day.Add(-time.Hour)}, {K: "acme/tools#2", UpdatedAt: day.Add(time.Hour)}},
+		Issues:     []gh.GitHubItem{{K: "acme/tools#4"}},
+		Incomplete: map[string]bool{},
+		Capped:     map[string][]string{},
+	}
+	stats := newSyncStats(context.Background(), "work")
+	capCategories(&s, internal.GithubConfig{MaxItemsPerCategory: 2}, stats)
+	if len(s.PRs) != 2 || s.PRs[0].Key() != "acme/tools#2" || s.PRs[1].Key() != "acme/tools#3" {
+		t.Errorf("Expected the 2 most recently updated PRs, got: %v", s.PRs)
+	}
+	if s.Incomplete["PRs"] || !slices.Equal(s.Capped["PRs"], []string{"acme/tools#1"}) {
+		t.Errorf("Expected acme/tools#1 to be capped, got: %v %v", s.Incomplete, s.Capped)
+	}
+	if len(s.Issues) != 1 || len(s.Capped["issues"]) != 0 {
+		t.Errorf("Expected issues untouched, got: %v %v", s.Issues, s.Capped)
+	}
+	if len(stats.warnings) != 1 || !strings.Contains(stats.warnings[0], "2 of 3 PRs") {
+		t.Errorf("Expected a warning about PRs, got: %v", stats.warnings)
+	}
+
+	// Tasks for capped items are left alone, but others not in desired
+	// are completed.
+	current := []omnifocus.Task{{Name: "acme/tools#1 Capped"}, {Name: "acme/tools#2 Synced"}, {Name: "acme/tools#9 Closed"}}
+	changes := delta.KeysOnly(toSet(s.PRs), toSet(withoutCapped(current, s.Capped["PRs"])))
+	if len(changes.Removes) != 1 || changes.Removes[0].Key() != "acme/tools#9" {
+		t.Errorf("Expected only the closed item's task to be completed, got: %+v", changes)
+	}
+}
+
+func TestWithTasks(t *testing.T) {
+	desired := []gh.GitHubItem{{K: "acme/tools#1"}, {K: "acme/tools#2"}}
+	current := []omnifocus.Task{{Name: "acme/tools#2 Still open"}, {Name: "acme/tools#3 Closed"}}
+	kept := withTasks(desired, current)
+	if len(kept) != 1 || kept[0].Key() != "acme/tools#2" {
+		t.Fatalf("Expected only the item with a task, got: %v", kept)
+	}
+	changes := delta.KeysOnly(toSet(kept), toSet(current))
+	if len(changes.Adds) != 0 || len(changes.Removes) != 1 || changes.Removes[0].Key() != "acme/tools#3" {
+		t.Fatalf("Expected the closed item's task to be completed and nothing added, got: %+v", changes)
+	}
+}
+
+func TestApplyDeltaRetitles(t *testing.T) {
+	retitled, added, completed := []string{}, []string{}, []string{}
+	actions := taskActions{
+		add: func(item gh.GitHubItem) error {
+			added = append(added, item.Key())
+			return nil
+		},
+		complete: func(t omnifocus.Task) error {
+			completed = append(completed, t.Key())
+			return nil
+		},
+		retitle: func(t omnifocus.Task, item gh.GitHubItem) error {
+			retitled = append(retitled, t.Key()+" "+item.Title)
+			return nil
+		},
+	}
+	desired := []gh.GitHubItem{
+		{K: "acme/tools#1", Repo: "acme/tools", Title: "New title"},
+		{K: "acme/tools#2", Repo: "acme/tools", Title: "Same", Labels: []string{"bug"}},
+	}
+	current := []omnifocus.Task{
+		{ID: "a", Name: "acme/tools#1 Old title", Tags: []string{"acme/tools"}},
+		{ID: "b", Name: "acme/tools#2 Same", Tags: []string{"acme/tools"}},
+	}
+	err := applyDelta(newSyncStats(context.Background(), "work"), "issues", false, desired, current, nil, actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(retitled, []string{"acme/tools#1 New title"}) {
+		t.Errorf("Expected acme/tools#1 to be renamed in place, got: %v", retitled)
+	}
+	if !slices.Equal(added, []string{"acme/tools#2"}) || !slices.Equal(completed, []string{"acme/tools#2"}) {
+		t.Errorf("Expected only acme/tools#2 to be re-created for its tags, got: %v %v", added, completed)
+	}
+}
+
+func TestWithoutSkipped(t *testing.T) {
+	current := []omnifocus.Task{
+		{ID: "a", Name: "acme/tools#1/ci/5 lint failed: Fix"},
+		{ID: "b", Name: "acme/tools#12/ci/5 lint failed: Other"},
+		{ID: "c", Name: "acme/tools#1 Fix"},
+	}
+	left := withoutSkipped(current, []string{"acme/tools#1"})
+	if len(left) != 2 || left[0].ID != "b" || left[1].ID != "c" {
+		t.Fatalf("Expected only the task under acme/tools#1 to be left out, got: %v", left)
+	}
+}
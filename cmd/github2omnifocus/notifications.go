@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+// handleCoveredNotifications applies c.CoveredNotifications to notifications
+// about issues and PRs that already have a task of their own. They're
+// removed from desired so they don't get a task as well and, if merging,
+// a link to each is added to the note of the task covering it.
+func handleCoveredNotifications(og omnifocus.Gateway, c internal.GithubConfig, desired *GHDesiredState, current OFCurrentState) error {
+	if c.CoveredNotifications == "" {
+		return nil
+	}
+	covered := coveredNotifications(desired)
+	if c.CoveredNotifications != internal.CoveredNotificationsMerge {
+		return nil
+	}
+	tasks := map[string]omnifocus.Task{}
+	for _, ts := range [][]omnifocus.Task{current.Issues, current.PRs, current.AuthoredPRs} {
+		for _, t := range ts {
+			tasks[t.Key()] = t
+		}
+	}
+	for _, n := range covered {
+		t, ok := tasks[n.Key()]
+		// A task being added this run gets the link next time.
+		if !ok || n.HTMLURL == "" || strings.Contains(t.Note, n.HTMLURL) {
+			continue
+		}
+		log.Printf("Adding notification for %s to its task", n.Key())
+		err := og.AppendNote(t, "New activity: "+n.HTMLURL)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleStaleNotifications completes, or with DropStaleNotifications drops,
+// the tasks for notifications that have been unread for
+// StaleNotificationDays since they were first seen, optionally marking
+// them read on GitHub. They're removed from desired and current, and
+// remembered in st so they aren't added again until there's new activity.
+// Failing to mark one read is only warned about, as the task is still
+// worth completing.
+func handleStaleNotifications(ghg *gh.GitHubGateway, og omnifocus.Gateway, c internal.GithubConfig, st *state.Store, desired *GHDesiredState, current *OFCurrentState, now time.Time) error {
+	if c.StaleNotificationDays == 0 {
+		return nil
+	}
+	staleAfter := time.Duration(c.StaleNotificationDays) * 24 * time.Hour
+	notifications := []gh.GitHubItem{}
+	for _, n := range desired.Notifications {
+		k := n.Key()
+		st.Revive(k, n.UpdatedAt, now)
+		if st.IsStale(k) {
+			continue
+		}
+		if n.Read || now.Sub(st.FirstSeenAt(k, now)) < staleAfter {
+			notifications = append(notifications, n)
+			continue
+		}
+		log.Printf("Notification for %s has been unread for over %d days", k, c.StaleNotificationDays)
+		if c.MarkStaleNotificationsRead {
+			err := ghg.MarkNotificationAsRead(n.ID)
+			if err != nil {
+				log.Printf("Warning: couldn't mark the notification for %s as read: %v", k, err)
+			}
+		}
+		i := slices.IndexFunc(current.Notifications, func(t omnifocus.Task) bool { return t.Key() == k })
+		if i >= 0 {
+			err := completeStale(og, c, current.Notifications[i], now)
+			if err != nil {
+				return err
+			}
+			current.Notifications = slices.Delete(current.Notifications, i, i+1)
+		}
+		st.MarkStale(k, n.UpdatedAt)
+	}
+	desired.Notifications = notifications
+	return nil
+}
+
+// completeStale completes or drops the task for a stale notification,
+// noting why first if AnnotateCompletedTasks is set.
+func completeStale(og omnifocus.Gateway, c internal.GithubConfig, t omnifocus.Task, now time.Time) error {
+	verb := "Completed"
+	if c.DropStaleNotifications {
+		verb = "Dropped"
+	}
+	if c.AnnotateCompletedTasks {
+		err := og.AppendNote(t, fmt.Sprintf("%s by github2omnifocus on %s: notification unread for %d days", verb, now.Format("2006-01-02"), c.StaleNotificationDays))
+		if err != nil {
+			return err
+		}
+	}
+	if c.DropStaleNotifications {
+		return og.DropIssue(t)
+	}
+	return og.CompleteNotification(t)
+}
+
+// coveredNotifications removes notifications with the same key as an issue
+// or PR from desired and returns them.
+func coveredNotifications(desired *GHDesiredState) []gh.GitHubItem {
+	keys := map[string]bool{}
+	for _, items := range [][]gh.GitHubItem{desired.Issues, desired.PRs, desired.AuthoredPRs} {
+		for _, item := range items {
+			keys[item.Key()] = true
+		}
+	}
+	covered := []gh.GitHubItem{}
+	notifications := []gh.GitHubItem{}
+	for _, n := range desired.Notifications {
+		if keys[n.Key()] {
+			covered = append(covered, n)
+		} else {
+			notifications = append(notifications, n)
+		}
+	}
+	desired.Notifications = notifications
+	return covered
+}
+
+// withoutOwnActivity drops notifications about the latest comment on
+// something when login wrote it, which is usually GitHub telling you about
+// your own comment.
+func withoutOwnActivity(notifications []gh.GitHubItem, login string) []gh.GitHubItem {
+	return slices.DeleteFunc(notifications, func(n gh.GitHubItem) bool {
+		if n.LatestCommenter != "" && strings.EqualFold(n.LatestCommenter, login) {
+			log.Printf("Dropping notification for %s about your own comment", n.Key())
+			return true
+		}
+		return false
+	})
+}
+
+// getNotifications fetches the notifications NotificationsFilter chooses.
+// With IncrementalNotificationsHours set, only notifications updated since
+// the last fetch are fetched and merged into those kept in st, with all of
+// them fetched at least every IncrementalNotificationsHours to catch those
+// read elsewhere. Without st, all of them are fetched.
+func getNotifications(ghg gh.GitHubGateway, c internal.GithubConfig, st *state.Store, now time.Time) ([]gh.GitHubItem, error) {
+	filter := notificationFilter(c, now)
+	full := time.Duration(c.IncrementalNotificationsHours) * time.Hour
+	if full == 0 || st == nil || now.Sub(st.NotificationsFull) >= full {
+		items, err := ghg.GetNotifications(filter)
+		if err != nil {
+			return nil, err
+		}
+		if st != nil {
+			st.Notifications, st.NotificationsSince, st.NotificationsFull = nil, time.Time{}, time.Time{}
+			if full > 0 {
+				st.Notifications, st.NotificationsSince, st.NotificationsFull = items, now, now
+			}
+		}
+		return slices.Clone(items), nil
+	}
+
+	updated, read, err := ghg.GetNotificationsSince(filter, st.NotificationsSince, now)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Fetched %d updated and %d read notifications since %s", len(updated), len(read), st.NotificationsSince.Format(time.RFC3339))
+	st.Notifications = mergeNotifications(st.Notifications, updated, read)
+	if !filter.ReadSince.IsZero() && !filter.All {
+		// Read notifications are only wanted while they're recent.
+		st.Notifications = slices.DeleteFunc(st.Notifications, func(n gh.GitHubItem) bool {
+			return n.Read && n.UpdatedAt.Before(filter.ReadSince)
+		})
+	}
+	st.NotificationsSince = now
+	return slices.Clone(st.Notifications), nil
+}
+
+// notificationFilter returns the filter for c's NotificationsFilter and
+// ReadNotificationsHours, as of now.
+func notificationFilter(c internal.GithubConfig, now time.Time) gh.NotificationFilter {
+	filter := gh.NotificationFilter{
+		Participating: c.NotificationsFilter == internal.NotificationsParticipating,
+		All:           c.NotificationsFilter == internal.NotificationsAll,
+	}
+	if c.ReadNotificationsHours > 0 {
+		filter.ReadSince = now.Add(-time.Duration(c.ReadNotificationsHours) * time.Hour)
+	}
+	return filter
+}
+
+// mergeNotifications updates cached notifications with those updated since
+// they were fetched, dropping those that have been read.
+func mergeNotifications(cached, unread []gh.GitHubItem, read []string) []gh.GitHubItem {
+	updated := map[string]bool{}
+	for _, n := range unread {
+		updated[n.ID] = true
+	}
+	merged := slices.Clone(unread)
+	for _, n := range cached {
+		if !updated[n.ID] && !slices.Contains(read, n.ID) {
+			merged = append(merged, n)
+		}
+	}
+	return merged
+}
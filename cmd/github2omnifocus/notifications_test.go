@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+func fakeTasks(t *testing.T, fake *omnifocus.FakeBackend) []omnifocus.Task {
+	tasks, err := fake.TasksForQuery(omnifocus.TaskQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tasks
+}
+
+func TestHandleCoveredNotifications(t *testing.T) {
+	fake, err := omnifocus.NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := omnifocus.Gateway{Backend: fake}
+	task, err := fake.AddTask(omnifocus.NewOmnifocusTask{Name: "acme/tools#1 Crash", Note: "https://github.com/acme/tools/issues/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment := "https://github.com/acme/tools/issues/1#issuecomment-5"
+	newDesired := func() GHDesiredState {
+		return GHDesiredState{
+			Issues: []gh.GitHubItem{{K: "acme/tools#1"}},
+			Notifications: []gh.GitHubItem{
+				{K: "acme/tools#1", HTMLURL: comment},
+				{K: "acme/tools#2", HTMLURL: "https://github.com/acme/tools/issues/2"},
+			},
+		}
+	}
+
+	desired := newDesired()
+	err = handleCoveredNotifications(og, internal.GithubConfig{}, &desired, OFCurrentState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(desired.Notifications) != 2 {
+		t.Fatalf("Expected notifications to be left alone, got: %v", desired.Notifications)
+	}
+
+	c := internal.GithubConfig{CoveredNotifications: internal.CoveredNotificationsMerge}
+	for range 2 {
+		desired = newDesired()
+		current := OFCurrentState{Issues: fakeTasks(t, fake)}
+		err = handleCoveredNotifications(og, c, &desired, current)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(desired.Notifications) != 1 || desired.Notifications[0].Key() != "acme/tools#2" {
+			t.Fatalf("Expected only acme/tools#2 to be left, got: %v", desired.Notifications)
+		}
+	}
+	tasks := fakeTasks(t, fake)
+	expected := task.Note + "\nNew activity: " + comment
+	if tasks[0].Note != expected {
+		t.Fatalf("Expected the link to be added once, got: %q", tasks[0].Note)
+	}
+}
+
+func TestMergeNotifications(t *testing.T) {
+	cached := []gh.GitHubItem{{ID: "1", Title: "old"}, {ID: "2"}, {ID: "3"}}
+	unread := []gh.GitHubItem{{ID: "1", Title: "new"}, {ID: "4"}}
+	merged := mergeNotifications(cached, unread, []string{"2"})
+
+	ids := []string{}
+	for _, n := range merged {
+		ids = append(ids, n.ID)
+	}
+	if want := "1 4 3"; strings.Join(ids, " ") != want {
+		t.Errorf("got %v, want %s", ids, want)
+	}
+	if merged[0].Title != "new" {
+		t.Errorf("updated notification not replaced: %+v", merged[0])
+	}
+}
+
+func TestWithoutOwnActivity(t *testing.T) {
+	notifications := []gh.GitHubItem{
+		{ID: "1", K: "acme/tools#1", LatestCommenter: "Me"},
+		{ID: "2", K: "acme/tools#2", LatestCommenter: "octocat"},
+		{ID: "3", K: "acme/tools#3"},
+	}
+	kept := withoutOwnActivity(notifications, "me")
+	if len(kept) != 2 || kept[0].ID != "2" || kept[1].ID != "3" {
+		t.Errorf("Expected only my comment dropped, got: %+v", kept)
+	}
+}
+
+func TestHandleStaleNotifications(t *testing.T) {
+	marked := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch && strings.Contains(r.URL.Path, "/notifications/threads/") {
+			marked = append(marked, path.Base(r.URL.Path))
+			w.WriteHeader(http.StatusResetContent)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	ghg, err := gh.NewGitHubGateway(context.Background(), "", server.URL+"/", gh.TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	fake, err := omnifocus.NewFakeBackend(path.Join(dir, "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := omnifocus.Gateway{AppTag: "github", NotificationTag: "notification", NotificationsProject: "GitHub Notifications", Backend: fake}
+	for _, k := range []string{"acme/tools#1", "acme/tools#2"} {
+		err = og.AddNotification(gh.GitHubItem{K: k, Repo: "acme/tools"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	notifications, err := og.GetNotifications()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := state.Load(path.Join(dir, "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	st.Observe([]string{"acme/tools#1"}, seen)
+	now := seen.Add(8 * 24 * time.Hour)
+	st.Observe([]string{"acme/tools#1", "acme/tools#2"}, now)
+
+	c := internal.GithubConfig{StaleNotificationDays: 7, DropStaleNotifications: true, MarkStaleNotificationsRead: true}
+	newDesired := func(updatedAt time.Time) GHDesiredState {
+		return GHDesiredState{Notifications: []gh.GitHubItem{
+			{K: "acme/tools#1", ID: "11", UpdatedAt: updatedAt},
+			{K: "acme/tools#2", ID: "12", UpdatedAt: now},
+		}}
+	}
+	desired := newDesired(seen)
+	current := OFCurrentState{Notifications: notifications}
+	err = handleStaleNotifications(&ghg, og, c, st, &desired, &current, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(desired.Notifications) != 1 || desired.Notifications[0].Key() != "acme/tools#2" {
+		t.Fatalf("Expected only acme/tools#2 to be desired, got: %v", desired.Notifications)
+	}
+	if len(current.Notifications) != 1 || current.Notifications[0].Key() != "acme/tools#2" {
+		t.Fatalf("Expected acme/tools#1's task to be removed from current, got: %v", current.Notifications)
+	}
+	if len(marked) != 1 || marked[0] != "11" {
+		t.Errorf("Expected thread 11 to be marked read, got: %v", marked)
+	}
+	for _, task := range fake.Tasks {
+		if strings.HasPrefix(task.Name, "acme/tools#1") && !task.Dropped {
+			t.Errorf("Expected acme/tools#1's task to be dropped, got: %+v", task)
+		}
+	}
+
+	// Still stale on the next sync, until there's new activity.
+	desired = newDesired(seen)
+	err = handleStaleNotifications(&ghg, og, c, st, &desired, &OFCurrentState{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(desired.Notifications) != 1 {
+		t.Fatalf("Expected acme/tools#1 to stay stale, got: %v", desired.Notifications)
+	}
+	desired = newDesired(now)
+	err = handleStaleNotifications(&ghg, og, c, st, &desired, &OFCurrentState{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(desired.Notifications) != 2 {
+		t.Fatalf("Expected acme/tools#1 to be added again after new activity, got: %v", desired.Notifications)
+	}
+
+	// Failing to mark a notification read doesn't stop it being handled.
+	later := now.Add(8 * 24 * time.Hour)
+	desired = newDesired(now)
+	server.Close()
+	err = handleStaleNotifications(&ghg, og, c, st, &desired, &OFCurrentState{}, later)
+	if err != nil {
+		t.Fatalf("Expected marking read failing to only be warned about, got: %v", err)
+	}
+	if len(desired.Notifications) != 0 || !st.IsStale("acme/tools#1") || !st.IsStale("acme/tools#2") {
+		t.Fatalf("Expected both notifications to be stale, got: %v", desired.Notifications)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/delta"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+// plannedOp is a change a sync would make to Omnifocus.
+type plannedOp struct {
+	Category string `json:"category"`
+	// Op is add, complete, or update for a task that would be completed
+	// and added again.
+	Op     string `json:"op"`
+	Key    string `json:"key"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+// Values for plannedOp.Op.
+const (
+	plannedAdd      = "add"
+	plannedComplete = "complete"
+	plannedUpdate   = "update"
+)
+
+type accountPlan struct {
+	Account    string      `json:"account"`
+	Operations []plannedOp `json:"operations"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// plan implements the plan command, which prints the changes a sync would
+// make to Omnifocus without making them.
+func plan(ctx context.Context, c internal.Config, opts runOptions, output string, w io.Writer) error {
+	plans := []accountPlan{}
+	failed := 0
+	for _, account := range slices.Sorted(maps.Keys(c)) {
+		p := accountPlan{Account: account, Operations: []plannedOp{}}
+		ops, err := planAccount(ctx, account, c[account], opts)
+		if err != nil {
+			logFailure(account, err)
+			p.Error = err.Error()
+			failed++
+		} else {
+			p.Operations = ops
+		}
+		plans = append(plans, p)
+	}
+
+	var err error
+	if output == outputJSON {
+		err = writeJSON(w, struct {
+			Version  string        `json:"version"`
+			Accounts []accountPlan `json:"accounts"`
+		}{Version, plans})
+	} else {
+		err = writePlan(w, plans)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d accounts couldn't be planned", failed, len(plans))
+	}
+	return nil
+}
+
+func writePlan(w io.Writer, plans []accountPlan) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:gomnd
+	for _, p := range plans {
+		switch {
+		case p.Error != "":
+			fmt.Fprintf(tw, "%s: failed: %s\n", p.Account, p.Error)
+		case len(p.Operations) == 0:
+			fmt.Fprintf(tw, "%s: no changes\n", p.Account)
+		default:
+			fmt.Fprintf(tw, "%s: %d changes\n", p.Account, len(p.Operations))
+		}
+		for _, op := range p.Operations {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", op.Op, op.Category, op.Key, op.Title, op.Reason)
+		}
+	}
+	return tw.Flush()
+}
+
+// planAccount works out the changes syncing the account would make. It
+// reads GitHub and Omnifocus as a sync does, but changes neither, so
+// uncompleted tasks aren't acted on and covered notifications aren't added
+// to notes.
+func planAccount(ctx context.Context, account string, c internal.GithubConfig, opts runOptions) ([]plannedOp, error) {
+	stats := newSyncStats(ctx, account)
+	defer stats.end()
+
+	cacheDir, err := internal.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	st, err := state.Load(state.Path(cacheDir, account))
+	if err != nil {
+		return nil, err
+	}
+	backend, err := accountBackend(c, opts)
+	if err != nil {
+		return nil, err
+	}
+	og := newOmnifocusGateway(account, c, st, backend)
+	ghg, err := newGitHubGateway(stats.ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	currentState, err := GetOFState(og, stats)
+	if err != nil {
+		return nil, err
+	}
+	// The state isn't saved, so incremental notifications aren't used.
+	desiredState := GetGitHubState(ghg, c, opts, nil, stats)
+	if err := desiredState.Err(); err != nil {
+		return nil, err
+	}
+	if c.CoveredNotifications != "" {
+		coveredNotifications(&desiredState)
+	}
+
+	open := []string{}
+	for _, cat := range currentState.categories() {
+		for _, t := range cat.tasks {
+			open = append(open, t.Key())
+		}
+	}
+	st.ForgetCompletions(desiredState.Keys(), open, time.Now())
+
+	ops := []plannedOp{}
+	if c.CompleteDuplicateTasks {
+		for _, t := range currentState.Duplicates {
+			ops = append(ops, plannedOp{Op: plannedComplete, Key: t.Key(), Title: t.Name, Reason: "duplicate"})
+		}
+	}
+	current := currentState.categories()
+	for i, cat := range desiredState.Categories() {
+		if !opts.syncs(cat.Name) {
+			continue
+		}
+		tasks := current[i].tasks
+		if c.UncompletedAction != "" {
+			// These are kept rather than completed again.
+			tasks = slices.DeleteFunc(slices.Clone(tasks), func(t omnifocus.Task) bool {
+				return st.WasCompleted(t.Key()) || st.IsKept(t.Key())
+			})
+		}
+		tasks = withoutCapped(tasks, desiredState.Capped[cat.Name])
+		tasks = withoutSkipped(tasks, desiredState.Skipped[cat.Name])
+		keyOnly, tasks := compared(c, desiredState.Incomplete[cat.Name], cat.Name, cat.Items, tasks)
+		items := cat.Items
+		if c.FreezeAdditions || opts.freezeAdditions {
+			items = withTasks(items, tasks)
+		}
+		var changes delta.Changes[gh.GitHubItem, omnifocus.Task]
+		if keyOnly {
+			changes = delta.KeysOnly(toSet(items), toSet(tasks))
+		} else {
+			changes = delta.Delta(toSet(items), toSet(tasks), ignoredTags(c))
+		}
+
+		catOps := []plannedOp{}
+		for _, item := range changes.Adds {
+			catOps = append(catOps, plannedOp{cat.Name, plannedAdd, item.Key(), item.Title, delta.Missing.String()})
+		}
+		for _, t := range changes.Removes {
+			catOps = append(catOps, plannedOp{cat.Name, plannedComplete, t.Key(), t.Name, delta.Extra.String()})
+		}
+		for _, m := range changes.Modifies {
+			catOps = append(catOps, plannedOp{cat.Name, plannedUpdate, m.Desired.Key(), m.Desired.Title, m.Explain()})
+		}
+		// Changes come out in no particular order.
+		slices.SortFunc(catOps, func(a, b plannedOp) int { return strings.Compare(a.Key, b.Key) })
+		ops = append(ops, catOps...)
+	}
+	return ops, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+)
+
+func TestPlan(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/search/"):
+			w.Write([]byte(`{"items": []}`))
+			return
+		case strings.HasSuffix(r.URL.Path, "/user"):
+			w.Write([]byte(`{"login": "me"}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	fake, err := omnifocus.NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fake.AddTask(omnifocus.NewOmnifocusTask{Name: "acme/tools#1 Fix it", ProjectName: "Reviews", Tags: []string{"github", "review"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := internal.Config{"work": {
+		APIURL:        server.URL + "/",
+		AccessToken:   "token",
+		AppTag:        "github",
+		ReviewTag:     "review",
+		ReviewProject: "Reviews",
+	}}
+
+	var out bytes.Buffer
+	err = plan(context.Background(), c, runOptions{backend: fake}, outputJSON, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Accounts []accountPlan `json:"accounts"`
+	}
+	err = json.Unmarshal(out.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := plannedOp{Category: "PRs", Op: plannedComplete, Key: "acme/tools#1", Title: "acme/tools#1 Fix it", Reason: "extra"}
+	if len(got.Accounts) != 1 || len(got.Accounts[0].Operations) != 1 || got.Accounts[0].Operations[0] != want {
+		t.Fatalf("Unexpected plan: %+v", got.Accounts)
+	}
+	if len(fake.Operations) != 1 || fake.Tasks[0].Completed {
+		t.Errorf("Expected Omnifocus to be left alone, got: %+v", fake.Operations)
+	}
+
+	out.Reset()
+	err = plan(context.Background(), c, runOptions{backend: fake}, outputText, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "work: 1 changes\n  complete  PRs") {
+		t.Errorf("Unexpected plan:\n%s", out.String())
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	runtimepprof "runtime/pprof"
+	"time"
+)
+
+// pprofHandler serves the runtime's profiles under /debug/pprof/, as
+// net/http/pprof does on the default mux.
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// servePprof serves the runtime's profiles at addr until ctx is done, so a
+// daemon's slow syncs can be profiled while it's running.
+func servePprof(ctx context.Context, addr string) error {
+	l, err := listenLocal(addr)
+	if err != nil {
+		return err
+	}
+	// Profiles can take a while to collect, so there's no write timeout.
+	server := &http.Server{Handler: localHostOnly(pprofHandler()), ReadHeaderTimeout: 10 * time.Second} //nolint:gomnd
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go server.Serve(l) //nolint:errcheck
+	return nil
+}
+
+// startCPUProfile profiles the process's CPU use until the returned func is
+// called, when the profile is written to p.
+func startCPUProfile(p string) (func(), error) {
+	f, err := os.Create(p)
+	if err != nil {
+		return nil, fmt.Errorf("error creating CPU profile: %v", err)
+	}
+	err = runtimepprof.StartCPUProfile(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error starting CPU profile: %v", err)
+	}
+	return func() {
+		runtimepprof.StopCPUProfile()
+		f.Close()
+	}, nil
+}
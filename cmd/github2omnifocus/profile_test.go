@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestPprofHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	pprofHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine") {
+		t.Fatalf("Expected a goroutine profile, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestStartCPUProfile(t *testing.T) {
+	p := path.Join(t.TempDir(), "cpu.pprof")
+	stop, err := startCPUProfile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() == 0 {
+		t.Fatal("Expected a profile to be written")
+	}
+}
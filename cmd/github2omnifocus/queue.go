@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/delta"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+)
+
+// errQueued is returned by queued actions in place of applying them.
+var errQueued = errors.New("queued for the next run")
+
+// opQueue holds the operations that couldn't be applied to Omnifocus, e.g.
+// as it quit or asked for permission mid-sync, so they're replayed on the
+// next run.
+type opQueue struct {
+	path string
+	Ops  []queuedOp `json:"ops"`
+	// err is the first error applying an operation this run. Once set,
+	// later operations are queued without being tried.
+	err error
+}
+
+type queuedOp struct {
+	Category string         `json:"category"`
+	Op       string         `json:"op"`
+	Item     gh.GitHubItem  `json:"item,omitempty"`
+	Task     omnifocus.Task `json:"task,omitempty"`
+}
+
+// Values for queuedOp.Op.
+const (
+	queuedAdd      = "add"
+	queuedComplete = "complete"
+)
+
+func (op queuedOp) String() string {
+	key := op.Item.Key()
+	if op.Op == queuedComplete {
+		key = op.Task.Key()
+	}
+	return fmt.Sprintf("%s %s %s", op.Op, op.Category, key)
+}
+
+// queuePath returns the location of the queue for account within the
+// app's cache dir.
+func queuePath(cacheDir, account string) string {
+	return path.Join(cacheDir, "queue", account+".json")
+}
+
+// loadQueue reads the queue at p, which is empty if the file doesn't exist.
+func loadQueue(p string) (*opQueue, error) {
+	q := &opQueue{path: p}
+	bytes, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading queue from %s: %v", p, err)
+	}
+	err = json.Unmarshal(bytes, q)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling queue JSON from %s: %v", p, err)
+	}
+	return q, nil
+}
+
+// save writes the queue, removing its file when it's empty.
+func (q *opQueue) save() error {
+	if len(q.Ops) == 0 {
+		err := os.Remove(q.path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	bytes, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path.Dir(q.path), 0o700)
+	if err != nil {
+		return fmt.Errorf("error creating queue dir: %v", err)
+	}
+	return os.WriteFile(q.path, bytes, 0o600)
+}
+
+// replay applies the queued operations for category that are still needed,
+// in the order they were queued. Adds already made, or for items no longer
+// on GitHub, and completions already made are dropped. It returns desired
+// and current without the items and tasks replayed, so the delta doesn't
+// apply them again.
+func (q *opQueue) replay(stats *syncStats, category string, desired []gh.GitHubItem, current []omnifocus.Task, actions taskActions) ([]gh.GitHubItem, []omnifocus.Task, error) {
+	remaining := []queuedOp{}
+	for n, op := range q.Ops {
+		if op.Category != category {
+			remaining = append(remaining, op)
+			continue
+		}
+		switch op.Op {
+		case queuedAdd:
+			if slices.ContainsFunc(current, func(t omnifocus.Task) bool { return t.Key() == op.Item.Key() }) {
+				log.Printf("queued add %s: already made", op.Item.Key())
+				continue
+			}
+			i := slices.IndexFunc(desired, func(item gh.GitHubItem) bool { return item.Key() == op.Item.Key() })
+			if i == -1 {
+				log.Printf("queued add %s: no longer needed", op.Item.Key())
+				continue
+			}
+			log.Printf("queued add %s: replaying", op.Item.Key())
+			if err := actions.add(desired[i]); err != nil {
+				q.Ops = append(remaining, q.Ops[n:]...)
+				return desired, current, err
+			}
+			stats.count(category, delta.Add)
+			desired = slices.Delete(slices.Clone(desired), i, i+1)
+		case queuedComplete:
+			i := slices.IndexFunc(current, func(t omnifocus.Task) bool { return t.ID == op.Task.ID })
+			if i == -1 {
+				log.Printf("queued complete %s: already made", op.Task.Key())
+				continue
+			}
+			log.Printf("queued complete %s: replaying", op.Task.Key())
+			if err := actions.complete(current[i]); err != nil {
+				q.Ops = append(remaining, q.Ops[n:]...)
+				return desired, current, err
+			}
+			stats.count(category, delta.Remove)
+			current = slices.Delete(slices.Clone(current), i, i+1)
+		}
+	}
+	q.Ops = remaining
+	return desired, current, nil
+}
+
+// wrap returns actions that, once one operation has failed, queue the
+// operations for category rather than trying them. Updates and renames are
+// skipped instead, as they're worked out afresh each run.
+func (q *opQueue) wrap(category string, actions taskActions) taskActions {
+	wrapped := taskActions{
+		add: func(item gh.GitHubItem) error {
+			return q.try(queuedOp{Category: category, Op: queuedAdd, Item: item}, func() error {
+				return actions.add(item)
+			})
+		},
+		complete: func(t omnifocus.Task) error {
+			return q.try(queuedOp{Category: category, Op: queuedComplete, Task: t}, func() error {
+				return actions.complete(t)
+			})
+		},
+	}
+	if actions.update != nil {
+		wrapped.update = func(pairs []delta.Pair[gh.GitHubItem, omnifocus.Task]) error {
+			if q.err != nil {
+				return nil
+			}
+			return actions.update(pairs)
+		}
+	}
+	if actions.retitle != nil {
+		// A failed rename isn't queued, but stops later operations being
+		// tried, like a failed add or completion.
+		wrapped.retitle = func(t omnifocus.Task, item gh.GitHubItem) error {
+			if q.err != nil {
+				return nil
+			}
+			err := actions.retitle(t, item)
+			if err != nil {
+				log.Printf("Queueing the rest of the sync for the next run, as applying it failed: %v", err)
+				q.err = err
+				return errQueued
+			}
+			return nil
+		}
+	}
+	return wrapped
+}
+
+func (q *opQueue) try(op queuedOp, apply func() error) error {
+	if q.err == nil {
+		err := apply()
+		if err == nil {
+			return nil
+		}
+		log.Printf("Queueing the rest of the sync for the next run, as applying it failed: %v", err)
+		q.err = err
+	}
+	q.Ops = append(q.Ops, op)
+	return errQueued
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+func TestQueueFailedOperations(t *testing.T) {
+	p := path.Join(t.TempDir(), "queue", "work.json")
+	q, err := loadQueue(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := []string{}
+	actions := q.wrap("issues", taskActions{
+		add: func(item gh.GitHubItem) error {
+			if len(added) == 1 {
+				return errors.New("Omnifocus quit")
+			}
+			added = append(added, item.Key())
+			return nil
+		},
+		complete: func(omnifocus.Task) error {
+			t.Fatal("Expected complete to be queued")
+			return nil
+		},
+	})
+	stats := newSyncStats(context.Background(), "work")
+	desired := []gh.GitHubItem{{K: "acme/tools#1"}, {K: "acme/tools#2"}, {K: "acme/tools#3"}}
+	current := []omnifocus.Task{{ID: "a", Name: "acme/tools#4 Done"}}
+	err = applyDelta(stats, "issues", true, desired, current, nil, actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.err == nil || len(added) != 1 || len(q.Ops) != 3 {
+		t.Fatalf("Expected one add then the rest queued, got: %v %v", added, q.Ops)
+	}
+	if stats.adds["issues"] != 1 || stats.removes["issues"] != 0 {
+		t.Fatalf("Expected only applied operations to be counted, got: %v %v", stats.adds, stats.removes)
+	}
+	if q.Ops[2].Op != queuedComplete {
+		t.Fatalf("Expected the complete to be queued last, got: %v", q.Ops)
+	}
+}
+
+func TestReplayQueue(t *testing.T) {
+	p := path.Join(t.TempDir(), "queue", "work.json")
+	q := &opQueue{path: p, Ops: []queuedOp{
+		{Category: "issues", Op: queuedAdd, Item: gh.GitHubItem{K: "acme/tools#1"}},
+		{Category: "issues", Op: queuedAdd, Item: gh.GitHubItem{K: "acme/tools#2"}},
+		{Category: "PRs", Op: queuedAdd, Item: gh.GitHubItem{K: "acme/tools#5"}},
+		{Category: "issues", Op: queuedAdd, Item: gh.GitHubItem{K: "acme/tools#3"}},
+		{Category: "issues", Op: queuedComplete, Task: omnifocus.Task{ID: "a", Name: "acme/tools#4 Done"}},
+	}}
+	err := q.save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err = loadQueue(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, completed := []string{}, []string{}
+	replayActions := taskActions{
+		add: func(item gh.GitHubItem) error {
+			added = append(added, item.Key())
+			return nil
+		},
+		complete: func(t omnifocus.Task) error {
+			completed = append(completed, t.Key())
+			return nil
+		},
+	}
+	// acme/tools#2's add half worked, and #3 was closed since.
+	desired := []gh.GitHubItem{{K: "acme/tools#1"}, {K: "acme/tools#2"}}
+	current := []omnifocus.Task{{ID: "a", Name: "acme/tools#4 Done"}, {ID: "b", Name: "acme/tools#2 Added"}}
+	stats := newSyncStats(context.Background(), "work")
+	desired, current, err = q.replay(stats, "issues", desired, current, replayActions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 1 || added[0] != "acme/tools#1" || len(completed) != 1 || completed[0] != "acme/tools#4" {
+		t.Fatalf("Expected acme/tools#1 to be added and #4 completed, got: %v %v", added, completed)
+	}
+	if len(current) != 1 || len(desired) != 1 {
+		t.Fatalf("Expected replayed items and tasks to be left out, got: %v %v", desired, current)
+	}
+	if len(q.Ops) != 1 || q.Ops[0].Category != "PRs" {
+		t.Fatalf("Expected only the PR to be left queued, got: %v", q.Ops)
+	}
+}
+
+// quitBackend fails to add tasks, as when Omnifocus quits mid-sync.
+type quitBackend struct {
+	*omnifocus.FakeBackend
+}
+
+func (quitBackend) AddTask(omnifocus.NewOmnifocusTask) (omnifocus.Task, error) {
+	return omnifocus.Task{}, errors.New("Omnifocus quit")
+}
+
+// issueServer serves GitHub with acme/tools#1 as the only issue.
+func issueServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/search/issues"):
+			_, _ = io.WriteString(w, `{"items": [{"number": 1, "title": "Bug", "html_url": "https://github.com/acme/tools/issues/1", "repository_url": "https://api.github.com/repos/acme/tools"}]}`)
+		case strings.HasSuffix(r.URL.Path, "/user"):
+			_, _ = io.WriteString(w, `{"login": "me"}`)
+		default:
+			_, _ = io.WriteString(w, `[]`)
+		}
+	}))
+}
+
+func TestSyncSavesStateWhenQueued(t *testing.T) {
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	server := issueServer()
+	defer server.Close()
+	fake, err := omnifocus.NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := internal.GithubConfig{AccessToken: "token", APIURL: server.URL + "/", AppTag: "github", AssignedTag: "assigned", AssignedProject: "GitHub Assigned"}
+	_, err = sync_github(context.Background(), "work", c, runOptions{backend: quitBackend{fake}, categories: []string{"issues"}})
+	if err == nil || !strings.Contains(err.Error(), "queued for the next run") {
+		t.Fatalf("Expected the add to be queued, got: %v", err)
+	}
+	st, err := state.Load(state.Path(path.Join(cacheHome, "github2omnifocus"), "work"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.FirstSeen["acme/tools#1"]; !ok {
+		t.Fatalf("Expected the state to be saved, got: %+v", st)
+	}
+}
+
+func TestReplayQueueFrozen(t *testing.T) {
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	server := issueServer()
+	defer server.Close()
+	fake, err := omnifocus.NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := queuePath(path.Join(cacheHome, "github2omnifocus"), "work")
+	q := &opQueue{path: p, Ops: []queuedOp{{Category: "issues", Op: queuedAdd, Item: gh.GitHubItem{K: "acme/tools#1"}}}}
+	err = q.save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := internal.GithubConfig{AccessToken: "token", APIURL: server.URL + "/", AppTag: "github", AssignedTag: "assigned", AssignedProject: "GitHub Assigned"}
+	_, err = sync_github(context.Background(), "work", c, runOptions{backend: fake, categories: []string{"issues"}, freezeAdditions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Tasks) != 0 {
+		t.Fatalf("Expected the queued add not to be made while frozen, got: %v", fake.Tasks)
+	}
+	q, err = loadQueue(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Ops) != 0 {
+		t.Fatalf("Expected the queued add to be dropped, got: %v", q.Ops)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+// handleRenamedRepos re-keys the tasks for items in repos that have been
+// renamed or transferred, so they match their items' new keys rather than
+// being completed and added again. Only repos with tasks but no desired
+// items are checked, as only their items can have changed keys. Tasks in
+// incomplete categories are left alone, as their items may just not have
+// been fetched.
+func handleRenamedRepos(ghg *gh.GitHubGateway, og omnifocus.Gateway, st *state.Store, desired GHDesiredState, current *OFCurrentState) error {
+	desiredRepos := map[string]bool{}
+	for _, k := range desired.Keys() {
+		desiredRepos[strings.ToLower(repoOfKey(k))] = true
+	}
+	repos := []string{}
+	for _, cat := range current.categories() {
+		if desired.Incomplete[cat.name] {
+			continue
+		}
+		for _, t := range cat.tasks {
+			repo := repoOfKey(t.Key())
+			if repo != t.Key() && !desiredRepos[strings.ToLower(repo)] && !slices.Contains(repos, repo) {
+				repos = append(repos, repo)
+			}
+		}
+	}
+	if len(repos) == 0 {
+		return nil
+	}
+	renamed, err := ghg.RenamedRepos(repos)
+	if err != nil {
+		return err
+	}
+	for _, cat := range current.categories() {
+		// The categories share their tasks with current, so renaming
+		// them here renames them there.
+		for i, t := range cat.tasks {
+			repo := repoOfKey(t.Key())
+			newRepo, ok := renamed[repo]
+			if !ok || desired.Incomplete[cat.name] {
+				continue
+			}
+			key := newRepo + strings.TrimPrefix(t.Key(), repo)
+			log.Printf("%s was renamed to %s, re-keying %s", repo, newRepo, t.Key())
+			cat.tasks[i], err = og.Rekey(t, key)
+			if err != nil {
+				return err
+			}
+			st.Rename(t.Key(), key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+func TestHandleRenamedRepos(t *testing.T) {
+	checked := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		checked = append(checked, r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/repos/acme/old") {
+			_, _ = io.WriteString(w, `{"full_name": "acme/new"}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	ghg, err := gh.NewGitHubGateway(context.Background(), "", server.URL+"/", gh.TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	fake, err := omnifocus.NewFakeBackend(path.Join(dir, "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := omnifocus.Gateway{AppTag: "github", AssignedTag: "assigned", AssignedProject: "GitHub Assigned", Backend: fake}
+	err = og.AddIssue(gh.GitHubItem{K: "acme/old#1", Title: "Fix the build", Repo: "acme/old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := state.Load(path.Join(dir, "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	st.Observe([]string{"acme/old#1"}, seen)
+
+	current := OFCurrentState{
+		Issues:        issues,
+		Notifications: []omnifocus.Task{{Name: "acme/tools#2 Still open"}},
+	}
+	desired := GHDesiredState{
+		Issues:        []gh.GitHubItem{{K: "acme/new#1", Title: "Fix the build", Repo: "acme/new"}},
+		Notifications: []gh.GitHubItem{{K: "acme/tools#2", Title: "Still open", Repo: "acme/tools"}},
+	}
+	err = handleRenamedRepos(&ghg, og, st, desired, &current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if current.Issues[0].Name != "acme/new#1 Fix the build" || fake.Tasks[0].Name != "acme/new#1 Fix the build" {
+		t.Fatalf("Expected the task to be re-keyed, got: %v and %+v", current.Issues[0], fake.Tasks[0])
+	}
+	if !st.FirstSeenAt("acme/new#1", time.Now()).Equal(seen) {
+		t.Fatal("Expected acme/new#1 to keep acme/old#1's first-seen time")
+	}
+	if len(checked) != 1 {
+		t.Fatalf("Expected only acme/old to be checked, got: %v", checked)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+// maxOutstanding is how many outstanding items a report lists.
+const maxOutstanding = 20
+
+// report implements the report command, which writes a Markdown summary of
+// each account's activity over the past days, from its journal and state.
+func report(c internal.Config, args []string, w io.Writer, now time.Time) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	days := fs.Int("days", 7, "report on the past `n` days")
+	account := fs.String("account", "", "report only on the account called `name`")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	accounts := slices.Sorted(maps.Keys(c))
+	if *account != "" {
+		if _, ok := c[*account]; !ok {
+			return fmt.Errorf("no account called %q", *account)
+		}
+		accounts = []string{*account}
+	}
+
+	cacheDir, err := internal.CacheDir()
+	if err != nil {
+		return err
+	}
+	since := now.AddDate(0, 0, -*days)
+	fmt.Fprintf(w, "# github2omnifocus report: %s to %s\n", since.Format(time.DateOnly), now.Format(time.DateOnly))
+	for _, name := range accounts {
+		// Earlier entries are needed for when completed tasks were added.
+		entries, err := state.ReadJournal(state.JournalPath(cacheDir, name), time.Time{})
+		if err != nil {
+			return err
+		}
+		st, err := state.Load(state.Path(cacheDir, name))
+		if err != nil {
+			return err
+		}
+		writeAccountReport(w, name, entries, st.FirstSeen, since, now)
+	}
+	return nil
+}
+
+// reportCount is the tasks created and completed for a category or repo.
+type reportCount struct {
+	created, completed int
+}
+
+// writeAccountReport writes the Markdown report for an account, given its
+// journal entries and the first-seen times of its open items.
+func writeAccountReport(w io.Writer, account string, entries []state.Entry, firstSeen map[string]time.Time, since, now time.Time) {
+	byCategory := map[string]*reportCount{}
+	byRepo := map[string]*reportCount{}
+	count := func(m map[string]*reportCount, k string) *reportCount {
+		if m[k] == nil {
+			m[k] = &reportCount{}
+		}
+		return m[k]
+	}
+	// added holds the latest add of each key, for how long tasks took to
+	// complete and what outstanding items are.
+	added := map[string]state.Entry{}
+	var reviewTime time.Duration
+	reviews := 0
+	for i, e := range entries {
+		if e.Op == state.JournalAdd {
+			added[e.Key] = e
+		}
+		if e.At.Before(since) {
+			continue
+		}
+		// A task completed and added again straight away was updated.
+		if e.Op == state.JournalComplete && i+1 < len(entries) && entries[i+1].Key == e.Key && entries[i+1].Op == state.JournalAdd && entries[i+1].At.Sub(e.At) < time.Minute {
+			continue
+		}
+		if e.Op == state.JournalAdd && i > 0 && entries[i-1].Key == e.Key && entries[i-1].Op == state.JournalComplete && e.At.Sub(entries[i-1].At) < time.Minute {
+			continue
+		}
+		switch e.Op {
+		case state.JournalAdd:
+			count(byCategory, e.Category).created++
+			count(byRepo, e.Repo).created++
+		case state.JournalComplete:
+			count(byCategory, e.Category).completed++
+			count(byRepo, e.Repo).completed++
+			if a, ok := added[e.Key]; ok && e.Category == "PRs" {
+				reviewTime += e.At.Sub(a.At)
+				reviews++
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\n## %s\n\n", account)
+	if len(byCategory) == 0 {
+		fmt.Fprintf(w, "No tasks were created or completed.\n")
+	} else {
+		writeCountTable(w, "Category", byCategory)
+		fmt.Fprintf(w, "\n")
+		writeCountTable(w, "Repo", byRepo)
+	}
+	if reviews > 0 {
+		fmt.Fprintf(w, "\nReviews took %s on average to complete, over %d reviews.\n", formatAge(reviewTime/time.Duration(reviews)), reviews)
+	}
+
+	fmt.Fprintf(w, "\n### Outstanding\n\n")
+	keys := slices.SortedFunc(maps.Keys(firstSeen), func(a, b string) int {
+		if c := firstSeen[a].Compare(firstSeen[b]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	if len(keys) == 0 {
+		fmt.Fprintf(w, "Nothing is outstanding.\n")
+	}
+	for i, k := range keys {
+		if i == maxOutstanding {
+			fmt.Fprintf(w, "- and %d more\n", len(keys)-maxOutstanding)
+			break
+		}
+		line := k
+		if a, ok := added[k]; ok && a.Title != "" {
+			line = fmt.Sprintf("%s: %s", a.Category, a.Title)
+		}
+		fmt.Fprintf(w, "- %s, open for %s\n", line, formatAge(now.Sub(firstSeen[k])))
+	}
+}
+
+func writeCountTable(w io.Writer, heading string, counts map[string]*reportCount) {
+	fmt.Fprintf(w, "| %s | Created | Completed |\n|---|---|---|\n", heading)
+	for _, k := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Fprintf(w, "| %s | %d | %d |\n", k, counts[k].created, counts[k].completed)
+	}
+}
+
+// formatAge formats d in days and hours, or hours and minutes if it's less
+// than a day.
+func formatAge(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%dd %dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	}
+	return fmt.Sprintf("%dh %dm", d/time.Hour, d%time.Hour/time.Minute)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+func TestWriteAccountReport(t *testing.T) {
+	now := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+	since := now.AddDate(0, 0, -7)
+	entries := []state.Entry{
+		// Added before the week, completed during it.
+		{At: since.Add(-24 * time.Hour), Op: state.JournalAdd, Category: "PRs", Key: "acme/tools#1", Repo: "acme/tools", Title: "Review me"},
+		{At: since.Add(24 * time.Hour), Op: state.JournalComplete, Category: "PRs", Key: "acme/tools#1", Repo: "acme/tools"},
+		{At: since.Add(25 * time.Hour), Op: state.JournalAdd, Category: "issues", Key: "acme/web#2", Repo: "acme/web", Title: "Crash"},
+		// Updated, so neither created nor completed.
+		{At: since.Add(26 * time.Hour), Op: state.JournalComplete, Category: "issues", Key: "acme/web#2", Repo: "acme/web"},
+		{At: since.Add(26*time.Hour + time.Second), Op: state.JournalAdd, Category: "issues", Key: "acme/web#2", Repo: "acme/web", Title: "Crash!"},
+	}
+	firstSeen := map[string]time.Time{"acme/web#2": since.Add(25 * time.Hour), "acme/old#3": since.Add(-30 * 24 * time.Hour)}
+
+	var buf bytes.Buffer
+	writeAccountReport(&buf, "work", entries, firstSeen, since, now)
+	got := buf.String()
+	for _, want := range []string{
+		"## work",
+		"| PRs | 0 | 1 |",
+		"| issues | 1 | 0 |",
+		"| acme/web | 1 | 0 |",
+		"Reviews took 2d 0h on average to complete, over 1 reviews.",
+		"- acme/old#3, open for 37d 0h\n- issues: Crash!, open for 5d 23h\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in report, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestJournaled(t *testing.T) {
+	stats := newSyncStats(context.Background(), "work")
+	actions := journaled(stats, "PRs", taskActions{
+		add:      func(gh.GitHubItem) error { return nil },
+		complete: func(omnifocus.Task) error { return errors.New("Omnifocus quit") },
+	})
+	_ = actions.add(gh.GitHubItem{K: "acme/tools#1", Repo: "acme/tools", Title: "Review me"})
+	_ = actions.complete(omnifocus.Task{Name: "acme/tools#2 Review me too"})
+	if len(stats.journal) != 1 || stats.journal[0].Key != "acme/tools#1" || stats.journal[0].Op != state.JournalAdd {
+		t.Fatalf("Expected only the add to be journaled, got: %+v", stats.journal)
+	}
+}
+
+func TestRepoOfKey(t *testing.T) {
+	for key, want := range map[string]string{"acme/tools#12": "acme/tools", "acme/tools@v1.0": "acme/tools", "acme/tools": "acme/tools"} {
+		if got := repoOfKey(key); got != want {
+			t.Errorf("%s: got %s, want %s", key, got, want)
+		}
+	}
+}
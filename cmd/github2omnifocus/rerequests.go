@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"slices"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+// handleReRequests finds PRs whose review was requested again after they
+// were first seen. Each is treated as seen afresh: any completion of its
+// task is forgotten, and its existing task is completed and removed from
+// current so that delta adds it again with a new due date. Only PRs
+// updated since they were last checked have their events fetched.
+func handleReRequests(ghg *gh.GitHubGateway, og omnifocus.Gateway, st *state.Store, desired GHDesiredState, current *OFCurrentState) error {
+	if desired.Incomplete["PRs"] {
+		return nil
+	}
+	var login string
+	for _, pr := range desired.PRs {
+		k := pr.Key()
+		firstSeen, ok := st.FirstSeen[k]
+		if !ok || !pr.UpdatedAt.After(firstSeen) || !pr.UpdatedAt.After(st.ReviewChecked[k]) {
+			continue
+		}
+		if login == "" {
+			var err error
+			login, err = ghg.Login()
+			if err != nil {
+				return err
+			}
+		}
+		requested, err := ghg.LastReviewRequest(pr, login)
+		if err != nil {
+			return err
+		}
+		st.ReviewChecked[k] = pr.UpdatedAt
+		if !requested.After(firstSeen) {
+			continue
+		}
+		log.Printf("Review of %s was requested again", k)
+		st.ReviewRequested(k, requested)
+		i := slices.IndexFunc(current.PRs, func(t omnifocus.Task) bool { return t.Key() == k })
+		if i < 0 {
+			continue
+		}
+		err = og.CompletePR(current.PRs[i])
+		if err != nil {
+			return err
+		}
+		current.PRs = slices.Delete(current.PRs, i, i+1)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+func TestHandleReRequests(t *testing.T) {
+	checked := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/user"):
+			_, _ = io.WriteString(w, `{"login": "me"}`)
+		case strings.HasSuffix(r.URL.Path, "/events"):
+			checked = append(checked, r.URL.Path)
+			_, _ = io.WriteString(w, `[
+				{"event": "review_requested", "created_at": "2024-05-01T08:00:00Z", "requested_reviewer": {"login": "me"}},
+				{"event": "review_requested", "created_at": "2024-05-03T08:00:00Z", "requested_reviewer": {"login": "Me"}},
+				{"event": "review_requested", "created_at": "2024-05-04T08:00:00Z", "requested_reviewer": {"login": "someone"}}
+			]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ghg, err := gh.NewGitHubGateway(context.Background(), "", server.URL+"/", gh.TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	fake, err := omnifocus.NewFakeBackend(path.Join(dir, "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := omnifocus.Gateway{AppTag: "github", ReviewTag: "review", ReviewProject: "GitHub Reviews", Backend: fake}
+	err = og.AddPR(gh.GitHubItem{K: "acme/tools#1", Title: "Add a flag", Repo: "acme/tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prs, err := og.GetPRs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := state.Load(path.Join(dir, "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	st.Observe([]string{"acme/tools#1", "acme/tools#2"}, seen)
+	st.MarkCompleted("acme/tools#1", seen)
+
+	current := OFCurrentState{PRs: prs}
+	desired := GHDesiredState{
+		PRs: []gh.GitHubItem{
+			{K: "acme/tools#1", Title: "Add a flag", Repo: "acme/tools", Number: 1, UpdatedAt: seen.Add(48 * time.Hour)},
+			// Not updated since it was first seen, so not checked.
+			{K: "acme/tools#2", Title: "Fix a typo", Repo: "acme/tools", Number: 2, UpdatedAt: seen.Add(-time.Hour)},
+		},
+	}
+	err = handleReRequests(&ghg, og, st, desired, &current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requested := time.Date(2024, 5, 3, 8, 0, 0, 0, time.UTC)
+	if !st.FirstSeenAt("acme/tools#1", time.Now()).Equal(requested) || st.WasCompleted("acme/tools#1") {
+		t.Fatalf("Expected acme/tools#1 to be seen afresh from its re-request, got: %+v", st)
+	}
+	if len(current.PRs) != 0 || !fake.Tasks[0].Completed {
+		t.Fatalf("Expected the existing task to be completed so it's added again, got: %v and %+v", current.PRs, fake.Tasks[0])
+	}
+	if len(checked) != 1 {
+		t.Fatalf("Expected only acme/tools#1 to be checked, got: %v", checked)
+	}
+
+	// Checked PRs aren't checked again until they're updated.
+	err = handleReRequests(&ghg, og, st, desired, &current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checked) != 1 {
+		t.Fatalf("Expected no further checks, got: %v", checked)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
+
+// Exit codes let wrapper scripts and launchd tell runs apart. Without
+// --detailed-exit-codes, a run that applied changes exits with
+// exitNoChanges so existing setups treating non-zero as failure keep
+// working.
+const (
+	exitNoChanges      = 0
+	exitTotalFailure   = 1 // also what log.Fatal exits with
+	exitChangesApplied = 2
+	exitPartialFailure = 3
+)
+
+// exitCode works out how the process should exit given each account's
+// sync. An account where only some categories failed to be fetched counts
+// towards a partial failure, as the rest of it was synced.
+func exitCode(stats []*syncStats, detailed bool) int {
+	failed, partial, changed := 0, 0, false
+	for _, s := range stats {
+		switch {
+		case s.err != nil && s.partial:
+			partial++
+		case s.err != nil:
+			failed++
+		}
+		if len(s.adds) > 0 || len(s.removes) > 0 {
+			changed = true
+		}
+	}
+	switch {
+	case failed > 0 && failed == len(stats):
+		return exitTotalFailure
+	case failed > 0 || partial > 0:
+		return exitPartialFailure
+	case changed && detailed:
+		return exitChangesApplied
+	}
+	return exitNoChanges
+}
+
+// syncResult is the machine-readable summary written by --result-file.
+type syncResult struct {
+	Version  string          `json:"version"`
+	Started  time.Time       `json:"started"`
+	Finished time.Time       `json:"finished"`
+	ExitCode int             `json:"exitCode"`
+	Accounts []accountResult `json:"accounts"`
+}
+
+type accountResult struct {
+	Account   string           `json:"account"`
+	Error     string           `json:"error,omitempty"`
+	Skipped   string           `json:"skipped,omitempty"`
+	Added     map[string]int   `json:"added"`
+	Completed map[string]int   `json:"completed"`
+	PhasesMS  map[string]int64 `json:"phasesMS"`
+	Queued    int              `json:"queued,omitempty"`
+	RateLimit *gh.Rate         `json:"rateLimit,omitempty"`
+	// TokenExpires is when the account's token expires, if it does.
+	TokenExpires *time.Time `json:"tokenExpires,omitempty"`
+	Warnings     []string   `json:"warnings,omitempty"`
+}
+
+func newSyncResult(started time.Time, code int, stats []*syncStats) syncResult {
+	r := syncResult{
+		Version:  Version,
+		Started:  started,
+		Finished: time.Now(),
+		ExitCode: code,
+		Accounts: []accountResult{},
+	}
+	for _, s := range stats {
+		a := accountResult{
+			Account:      s.account,
+			Added:        s.adds,
+			Completed:    s.removes,
+			Skipped:      s.skipped,
+			PhasesMS:     map[string]int64{},
+			Queued:       len(s.queued),
+			RateLimit:    s.rate,
+			TokenExpires: s.tokenExpiry,
+			Warnings:     s.warnings,
+		}
+		if s.err != nil {
+			a.Error = s.err.Error()
+		}
+		for _, p := range s.phases {
+			a.PhasesMS[p.name] = p.duration.Milliseconds()
+		}
+		r.Accounts = append(r.Accounts, a)
+	}
+	return r
+}
+
+func writeResultFile(p string, r syncResult) error {
+	bytes, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, bytes, 0o600)
+}
+
+// Values for --output.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// writeJSON writes v to w as indented JSON, as commands do with
+// --output=json.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/delta"
+)
+
+func TestExitCode(t *testing.T) {
+	ok := newSyncStats(context.Background(), "ok")
+	changed := newSyncStats(context.Background(), "changed")
+	changed.count("issues", delta.Add)
+	failed := newSyncStats(context.Background(), "failed")
+	failed.err = errors.New("boom")
+	categoryFailed := newSyncStats(context.Background(), "category failed")
+	categoryFailed.count("issues", delta.Add)
+	categoryFailed.err = errors.New("couldn't fetch notifications: boom")
+	categoryFailed.partial = true
+
+	cases := []struct {
+		name     string
+		stats    []*syncStats
+		detailed bool
+		expected int
+	}{
+		{"no changes", []*syncStats{ok}, true, exitNoChanges},
+		{"changes", []*syncStats{ok, changed}, true, exitChangesApplied},
+		{"changes without detailed codes", []*syncStats{changed}, false, exitNoChanges},
+		{"partial failure", []*syncStats{changed, failed}, true, exitPartialFailure},
+		{"total failure", []*syncStats{failed}, false, exitTotalFailure},
+		{"category failure", []*syncStats{categoryFailed}, false, exitPartialFailure},
+		{"category and total failure", []*syncStats{categoryFailed, failed}, true, exitPartialFailure},
+	}
+	for _, c := range cases {
+		if code := exitCode(c.stats, c.detailed); code != c.expected {
+			t.Fatalf("%s: expected %d, got: %d", c.name, c.expected, code)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+// recordCompletions wraps complete so that each task it completes is
+// remembered in st, so it can be noticed if it's marked incomplete again.
+// Tasks kept open instead, as errKept says, aren't.
+func recordCompletions(st *state.Store, complete func(omnifocus.Task) error) func(omnifocus.Task) error {
+	return func(t omnifocus.Task) error {
+		err := complete(t)
+		if errors.Is(err, errKept) {
+			return nil
+		}
+		if err == nil {
+			st.MarkCompleted(t.Key(), time.Now())
+		}
+		return err
+	}
+}
+
+// handleUncompleted finds tasks that the app completed but which have been
+// marked incomplete again in Omnifocus, meaning they're still needed. Unless
+// UncompletedAction is unset, these tasks are kept: they're removed from
+// current so they won't be completed again. For issues and PRs the
+// action can also reopen or comment on the item on GitHub. Tasks kept for
+// other reasons, such as by UnmergedPRAction, are removed from current too.
+func handleUncompleted(ghg *gh.GitHubGateway, c internal.GithubConfig, st *state.Store, desired GHDesiredState, current *OFCurrentState, now time.Time) {
+	categories := []struct {
+		tasks *[]omnifocus.Task
+		// onGitHub is true if the tasks are for issues or PRs that can be
+		// reopened or commented on.
+		onGitHub bool
+	}{
+		{&current.Issues, true},
+		{&current.PRs, true},
+		{&current.AuthoredPRs, true},
+		{&current.FailingChecks, false},
+		{&current.TrackedItems, false},
+		{&current.WatchedReleases, false},
+		{&current.Mentions, false},
+		{&current.Notifications, false},
+	}
+
+	open := []string{}
+	for _, cat := range categories {
+		for _, t := range *cat.tasks {
+			open = append(open, t.Key())
+		}
+	}
+	st.ForgetCompletions(desired.Keys(), open, now)
+
+	for _, cat := range categories {
+		tasks := []omnifocus.Task{}
+		for _, t := range *cat.tasks {
+			k := t.Key()
+			if c.UncompletedAction != "" && st.WasCompleted(k) {
+				log.Printf("Task for %s was marked incomplete, keeping it", k)
+				if cat.onGitHub {
+					uncompletedOnGitHub(ghg, c, k)
+				}
+				st.Keep(k, now)
+			}
+			if st.IsKept(k) {
+				continue
+			}
+			tasks = append(tasks, t)
+		}
+		*cat.tasks = tasks
+	}
+}
+
+// handleDismissed finds tasks completed by hand for items that stay wanted
+// so would otherwise be added again: watched releases, until there's a
+// newer release, and mentions, until the issue or PR is closed. A mention
+// with a notification is left to the notification's task, and stays seen
+// to once the notification has been read. Dismissed items are removed from
+// desired and remembered in st.
+func handleDismissed(st *state.Store, desired *GHDesiredState, current OFCurrentState, now time.Time) {
+	categories := []struct {
+		items *[]gh.GitHubItem
+		tasks []omnifocus.Task
+	}{
+		{&desired.WatchedReleases, current.WatchedReleases},
+		{&desired.Mentions, current.Mentions},
+	}
+
+	notified := map[string]bool{}
+	for _, n := range desired.Notifications {
+		notified[n.Key()] = true
+	}
+	for _, m := range desired.Mentions {
+		if notified[m.Key()] {
+			st.Dismiss(m.Key(), now)
+		}
+	}
+
+	keys, open := []string{}, []string{}
+	for _, cat := range categories {
+		for _, item := range *cat.items {
+			keys = append(keys, item.Key())
+		}
+		for _, t := range cat.tasks {
+			open = append(open, t.Key())
+		}
+	}
+	st.NoteDismissed(keys, open, now)
+
+	for _, cat := range categories {
+		*cat.items = slices.DeleteFunc(*cat.items, func(item gh.GitHubItem) bool {
+			return st.IsDismissed(item.Key())
+		})
+	}
+}
+
+// uncompletedOnGitHub reopens or comments on the issue or PR for key, as
+// set by UncompletedAction. Failures are logged rather than returned, as
+// the task is kept either way.
+func uncompletedOnGitHub(ghg *gh.GitHubGateway, c internal.GithubConfig, key string) {
+	repo, number, ok := gh.ParseIssueKey(key)
+	if !ok {
+		return
+	}
+	var err error
+	switch c.UncompletedAction {
+	case internal.UncompletedActionReopen:
+		log.Printf("Reopening %s", key)
+		err = ghg.ReopenIssue(repo, number)
+	case internal.UncompletedActionComment:
+		log.Printf("Commenting on %s", key)
+		err = ghg.CommentOnIssue(repo, number, c.Comment())
+	}
+	if err != nil {
+		log.Printf("Could not update %s on GitHub: %v", key, err)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/omnifocus"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+func TestHandleUncompletedKeep(t *testing.T) {
+	st, err := state.Load(path.Join(t.TempDir(), "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	st.MarkCompleted("acme/tools#1", now)
+
+	current := OFCurrentState{
+		Issues: []omnifocus.Task{
+			{Name: "acme/tools#1 Still needed"},
+			{Name: "acme/tools#2 Closed"},
+		},
+	}
+	c := internal.GithubConfig{UncompletedAction: internal.UncompletedActionKeep}
+	handleUncompleted(nil, c, st, GHDesiredState{}, &current, now)
+
+	if len(current.Issues) != 1 || current.Issues[0].Key() != "acme/tools#2" {
+		t.Fatalf("Expected only acme/tools#2 to be left to complete, got: %v", current.Issues)
+	}
+	if !st.IsKept("acme/tools#1") {
+		t.Fatal("Expected acme/tools#1 to be kept")
+	}
+}
+
+func TestHandleUncompletedUnset(t *testing.T) {
+	st, err := state.Load(path.Join(t.TempDir(), "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	st.MarkCompleted("acme/tools#1", now)
+
+	current := OFCurrentState{
+		Issues: []omnifocus.Task{{Name: "acme/tools#1 Still needed"}},
+	}
+	handleUncompleted(nil, internal.GithubConfig{}, st, GHDesiredState{}, &current, now)
+
+	if len(current.Issues) != 1 {
+		t.Fatalf("Expected task to be left to complete again, got: %v", current.Issues)
+	}
+}
+
+func TestHandleDismissed(t *testing.T) {
+	st, err := state.Load(path.Join(t.TempDir(), "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	desired := func() GHDesiredState {
+		return GHDesiredState{WatchedReleases: []gh.GitHubItem{{K: "acme/tools@v1.0.0"}}}
+	}
+	current := OFCurrentState{WatchedReleases: []omnifocus.Task{{Name: "acme/tools@v1.0.0 Upgrade to tools v1.0.0"}}}
+
+	d := desired()
+	handleDismissed(st, &d, current, now)
+	if len(d.WatchedReleases) != 1 {
+		t.Fatalf("Expected the release with an open task to be kept, got: %v", d.WatchedReleases)
+	}
+
+	// The task was completed by hand.
+	for range 2 {
+		d = desired()
+		handleDismissed(st, &d, OFCurrentState{}, now)
+		if len(d.WatchedReleases) != 0 {
+			t.Fatalf("Expected the dismissed release not to be added again, got: %v", d.WatchedReleases)
+		}
+	}
+}
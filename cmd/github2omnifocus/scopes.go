@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+// scopeNeed is a token scope a category needs. Any one of scopes will do,
+// as some scopes include others. A token lacking a partial need still
+// finds some of the category's items, so it's warned about rather than the
+// category failing.
+type scopeNeed struct {
+	scopes  []string
+	why     string
+	partial bool
+}
+
+// neededScopes returns the token scopes each category needs with c. Items
+// in public repos can be read without any scope, so a token with only
+// public_repo, or none, still finds those.
+func neededScopes(c internal.GithubConfig) map[string][]scopeNeed {
+	needs := map[string][]scopeNeed{
+		"notifications": {{scopes: []string{"notifications", "repo"}, why: "to read notifications"}},
+	}
+	if c.RepoVisibility != internal.RepoVisibilityPublic {
+		for _, cat := range []string{"issues", "tracked items", "PRs", "authored PRs", "failing checks", "mentions"} {
+			needs[cat] = append(needs[cat], scopeNeed{scopes: []string{"repo"}, why: "to find items in private repos", partial: true})
+		}
+	}
+	if c.ProjectPriorityField != "" || c.ProjectIterationField != "" {
+		for _, cat := range []string{"issues", "PRs"} {
+			needs[cat] = append(needs[cat], scopeNeed{scopes: []string{"read:project", "project"}, why: "to read project fields"})
+		}
+	}
+	return needs
+}
+
+// missingScopes returns an error for each category in needs that the
+// token's scopes don't cover, and separately the scopes each category only
+// partially covered by them lacks.
+func missingScopes(scopes []string, needs map[string][]scopeNeed) (missing map[string]error, partial map[string][]string) {
+	missing = map[string]error{}
+	partial = map[string][]string{}
+	for cat, catNeeds := range needs {
+		for _, n := range catNeeds {
+			if slices.ContainsFunc(n.scopes, func(s string) bool { return slices.Contains(scopes, s) }) {
+				continue
+			}
+			if n.partial {
+				partial[cat] = append(partial[cat], fmt.Sprintf("the %s scope, which is needed %s", n.scopes[0], n.why))
+				continue
+			}
+			missing[cat] = fmt.Errorf("the GitHub token lacks the %s scope, which is needed %s", n.scopes[0], n.why)
+			break
+		}
+	}
+	return missing, partial
+}
+
+// partialScopeWarnings describes what categories will miss for lacking the
+// scopes in partial, one warning per lacking scope.
+func partialScopeWarnings(partial map[string][]string) []string {
+	cats := map[string][]string{}
+	for cat, lacks := range partial {
+		for _, l := range lacks {
+			cats[l] = append(cats[l], cat)
+		}
+	}
+	warnings := []string{}
+	for l, c := range cats {
+		slices.Sort(c)
+		warnings = append(warnings, fmt.Sprintf("%s may be missing items, as the GitHub token lacks %s", strings.Join(c, ", "), l))
+	}
+	slices.Sort(warnings)
+	return warnings
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+func TestMissingScopes(t *testing.T) {
+	needs := neededScopes(internal.GithubConfig{ProjectPriorityField: "Priority"})
+	missing, partial := missingScopes([]string{"repo"}, needs)
+	if len(missing) != 2 || missing["issues"] == nil || missing["PRs"] == nil || len(partial) != 0 {
+		t.Fatalf("Expected only the project field categories to miss a scope, got: %v %v", missing, partial)
+	}
+	if missing, _ := missingScopes([]string{"repo", "project"}, needs); len(missing) != 0 {
+		t.Fatalf("Expected project to cover read:project, got: %v", missing)
+	}
+
+	public := neededScopes(internal.GithubConfig{RepoVisibility: internal.RepoVisibilityPublic})
+	missing, partial = missingScopes([]string{}, public)
+	if len(missing) != 1 || missing["notifications"] == nil || len(partial) != 0 {
+		t.Fatalf("Expected public repos to need no scope, got: %v %v", missing, partial)
+	}
+}
+
+func TestMissingScopesPublicRepo(t *testing.T) {
+	missing, partial := missingScopes([]string{"public_repo", "notifications"}, neededScopes(internal.GithubConfig{}))
+	if len(missing) != 0 {
+		t.Fatalf("Expected a public_repo token not to fail any category, got: %v", missing)
+	}
+	if len(partial) != 6 || partial["issues"] == nil {
+		t.Fatalf("Expected the categories that search private repos to be partial, got: %v", partial)
+	}
+	warnings := partialScopeWarnings(map[string][]string{
+		"issues": {"the repo scope, which is needed to find items in private repos"},
+		"PRs":    {"the repo scope, which is needed to find items in private repos"},
+	})
+	want := []string{"PRs, issues may be missing items, as the GitHub token lacks the repo scope, which is needed to find items in private repos"}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Fatalf("Expected one warning for the lacking scope, got: %v", warnings)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/delta"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// phase is a named part of a sync and how long it took in total.
+type phase struct {
+	name     string
+	duration time.Duration
+}
+
+// syncStats records how long each phase of an account's sync took, and how
+// many operations were applied to each category, so that slow syncs can be
+// attributed to GitHub or Omnifocus.
+//
+// Each phase is also recorded as a span, a child of a span for the whole
+// account's sync, for when tracing is set up.
+type syncStats struct {
+	account string
+	phases  []phase
+	adds    map[string]int
+	removes map[string]int
+	// err is why the account's sync failed, if it did.
+	err error
+	// partial is set when err is only for categories that couldn't be
+	// fetched, with the others synced.
+	partial bool
+	// skipped is why the account wasn't synced, if it wasn't.
+	skipped string
+	// rate is GitHub's rate limit as of the sync's last request.
+	rate *gh.Rate
+	// tokenExpiry is when the account's token expires, if it does.
+	tokenExpiry *time.Time
+	// warnings are problems that didn't stop the sync but need seeing to.
+	warnings []string
+	// queued holds the operations left for the next run.
+	queued []queuedOp
+	// journal holds the tasks added and completed, for the account's
+	// journal.
+	journal []state.Entry
+	// targets holds where the tasks for the desired items go, to check
+	// for items other accounts sync to the same place.
+	targets map[taskTarget][]itemSource
+
+	// ctx carries the account's span.
+	ctx  context.Context
+	span trace.Span
+}
+
+func newSyncStats(ctx context.Context, account string) *syncStats {
+	ctx, span := tracer.Start(ctx, "sync", trace.WithAttributes(attribute.String("account", account)))
+	return &syncStats{
+		account: account,
+		adds:    map[string]int{},
+		removes: map[string]int{},
+		ctx:     ctx,
+		span:    span,
+	}
+}
+
+// end finishes the account's span, recording the operation counts on it.
+func (s *syncStats) end() {
+	for category, n := range s.adds {
+		s.span.SetAttributes(attribute.Int("added."+category, n))
+	}
+	for category, n := range s.removes {
+		s.span.SetAttributes(attribute.Int("completed."+category, n))
+	}
+	if s.err != nil {
+		s.span.RecordError(s.err)
+		s.span.SetStatus(codes.Error, s.err.Error())
+	}
+	s.span.End()
+}
+
+// track runs f and adds the time it took to the phase called name.
+func (s *syncStats) track(name string, f func() error) error {
+	_, span := tracer.Start(s.ctx, name)
+	start := time.Now()
+	err := f()
+	d := time.Since(start)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	log.Printf("[%s] %s took %s", s.account, name, d.Round(time.Millisecond))
+
+	for i := range s.phases {
+		if s.phases[i].name == name {
+			s.phases[i].duration += d
+			return err
+		}
+	}
+	s.phases = append(s.phases, phase{name: name, duration: d})
+	return err
+}
+
+// count records that an operation of type op was applied to category.
+func (s *syncStats) count(category string, op delta.OperationType) {
+	switch op {
+	case delta.Add:
+		s.adds[category]++
+	case delta.Remove:
+		s.removes[category]++
+	}
+}
+
+// total returns the time spent in all phases.
+func (s *syncStats) total() time.Duration {
+	var t time.Duration
+	for _, p := range s.phases {
+		t += p.duration
+	}
+	return t
+}
+
+// logSummary logs a table of phase timings and operation counts for each
+// account synced.
+func logSummary(stats []*syncStats) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0) //nolint:gomnd
+	for _, s := range stats {
+		fmt.Fprintf(w, "%s\t\t\n", s.account)
+		if s.err != nil {
+			fmt.Fprintf(w, "  failed\t%v\t\n", s.err)
+		}
+		if s.skipped != "" {
+			fmt.Fprintf(w, "  skipped\t%s\t\n", s.skipped)
+		}
+		for _, p := range s.phases {
+			fmt.Fprintf(w, "  %s\t%s\t\n", p.name, p.duration.Round(time.Millisecond))
+		}
+		fmt.Fprintf(w, "  total\t%s\t\n", s.total().Round(time.Millisecond))
+		for category, n := range s.adds {
+			fmt.Fprintf(w, "  %s added\t%d\t\n", category, n)
+		}
+		for category, n := range s.removes {
+			fmt.Fprintf(w, "  %s completed\t%d\t\n", category, n)
+		}
+	}
+	w.Flush()
+
+	log.Printf("Sync summary:")
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		log.Print(line)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v41/github"
+)
+
+const (
+	releaseOwner = "rhyshort"
+	releaseRepo  = "github-to-omnifocus"
+	// checksumsAsset lists the SHA-256 of each binary asset, in the format
+	// written by shasum -a 256.
+	checksumsAsset = "checksums.txt"
+)
+
+// compareVersions compares release versions such as v1.2.3 or
+// v1.3.0-rc1, returning -1, 0 or 1 as a is older than, the same as or
+// newer than b. A pre-release is older than its release, and pre-releases
+// are compared as strings. ok is false if either isn't such a version,
+// as for a development build.
+func compareVersions(a, b string) (cmp int, ok bool) {
+	av, apre, ok := parseVersion(a)
+	if !ok {
+		return 0, false
+	}
+	bv, bpre, ok := parseVersion(b)
+	if !ok {
+		return 0, false
+	}
+	for i := range av {
+		if av[i] != bv[i] {
+			if av[i] < bv[i] {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	switch {
+	case apre == bpre:
+		return 0, true
+	case apre == "":
+		return 1, true
+	case bpre == "":
+		return -1, true
+	}
+	return strings.Compare(apre, bpre), true
+}
+
+// parseVersion splits a version such as v1.2.3-rc1 into its major, minor
+// and patch numbers and its pre-release, if any.
+func parseVersion(v string) (nums [3]int, pre string, ok bool) {
+	v, pre, _ = strings.Cut(strings.TrimPrefix(v, "v"), "-")
+	parts := strings.Split(v, ".")
+	if len(parts) != len(nums) {
+		return nums, "", false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nums, "", false
+		}
+		nums[i] = n
+	}
+	return nums, pre, true
+}
+
+// releaseAssetName is the name of the binary for this platform in a
+// release, as built by `make release`.
+func releaseAssetName() string {
+	return fmt.Sprintf("github2omnifocus-%s-%s", runtime.GOOS, runtime.GOARCH)
+}
+
+// selfUpdate implements the update command: it replaces the running binary
+// with the one from the project's latest GitHub release, after verifying
+// its checksum. A binary that's newer than the release, or a development
+// build whose version can't be compared, is only replaced with --force.
+func selfUpdate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	force := fs.Bool("force", false, "replace the binary even if it's newer than the latest release")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	client := github.NewClient(nil)
+	release, _, err := client.Repositories.GetLatestRelease(ctx, releaseOwner, releaseRepo)
+	if err != nil {
+		return fmt.Errorf("error checking for latest release: %v", err)
+	}
+	cmp, ok := compareVersions(Version, release.GetTagName())
+	switch {
+	case ok && cmp == 0:
+		log.Printf("Already running the latest version, %s.", Version)
+		return nil
+	case *force:
+	case !ok:
+		return fmt.Errorf("can't tell whether %s is newer than the latest release, %s; use --force to update anyway", Version, release.GetTagName())
+	case cmp > 0:
+		return fmt.Errorf("%s is newer than the latest release, %s; use --force to downgrade", Version, release.GetTagName())
+	}
+	log.Printf("Updating from %s to %s.", Version, release.GetTagName())
+
+	var binaryURL, checksumsURL string
+	for _, a := range release.Assets {
+		switch a.GetName() {
+		case releaseAssetName():
+			binaryURL = a.GetBrowserDownloadURL()
+		case checksumsAsset:
+			checksumsURL = a.GetBrowserDownloadURL()
+		}
+	}
+	if binaryURL == "" || checksumsURL == "" {
+		return fmt.Errorf("release %s has no %s binary or %s", release.GetTagName(), releaseAssetName(), checksumsAsset)
+	}
+
+	checksums, err := download(ctx, checksumsURL)
+	if err != nil {
+		return err
+	}
+	expected, err := findChecksum(string(checksums), releaseAssetName())
+	if err != nil {
+		return err
+	}
+	binary, err := download(ctx, binaryURL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(binary)
+	if hex.EncodeToString(sum[:]) != expected {
+		return fmt.Errorf("checksum mismatch for %s, not updating", releaseAssetName())
+	}
+
+	return replaceExecutable(binary)
+}
+
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// findChecksum returns the checksum for name from a shasum-style listing.
+func findChecksum(checksums, name string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name { //nolint:gomnd
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in %s", name, checksumsAsset)
+}
+
+// replaceExecutable swaps the running binary for binary. The new file is
+// written alongside the old one and renamed over it, so a failure part way
+// through leaves the existing binary intact.
+func replaceExecutable(binary []byte) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return err
+	}
+	tmp := exe + ".new"
+	err = os.WriteFile(tmp, binary, 0o755) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("error writing new binary: %v", err)
+	}
+	err = os.Rename(tmp, exe)
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error replacing %s: %v", exe, err)
+	}
+	log.Printf("Updated %s.", exe)
+	return nil
+}
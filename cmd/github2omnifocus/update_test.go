@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestFindChecksum(t *testing.T) {
+	checksums := "ABC123  github2omnifocus-darwin-amd64\ndef456 *github2omnifocus-darwin-arm64\n"
+	sum, err := findChecksum(checksums, "github2omnifocus-darwin-amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != "abc123" {
+		t.Fatalf("Didn't get expected checksum, got: %s", sum)
+	}
+	sum, err = findChecksum(checksums, "github2omnifocus-darwin-arm64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != "def456" {
+		t.Fatalf("Didn't get expected checksum, got: %s", sum)
+	}
+	_, err = findChecksum(checksums, "github2omnifocus-linux-amd64")
+	if err == nil {
+		t.Fatal("Expected error for missing checksum")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		cmp  int
+		ok   bool
+	}{
+		{"v1.2.3", "v1.2.3", 0, true},
+		{"v1.2.3", "v1.10.0", -1, true},
+		{"v2.0.0", "v1.9.9", 1, true},
+		{"v1.3.0-rc1", "v1.3.0", -1, true},
+		{"v1.3.0", "v1.3.0-rc1", 1, true},
+		{"v1.3.0-rc1", "v1.3.0-rc2", -1, true},
+		{"1.2.3", "v1.2.3", 0, true},
+		{"development", "v1.2.3", 0, false},
+		{"v1.2.3", "nightly", 0, false},
+	}
+	for _, c := range cases {
+		cmp, ok := compareVersions(c.a, c.b)
+		if cmp != c.cmp || ok != c.ok {
+			t.Errorf("Comparing %s with %s, expected %d %v, got: %d %v", c.a, c.b, c.cmp, c.ok, cmp, ok)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Commit and BuildDate can be overridden at build time, as the makefile
+// does. Otherwise they're taken from the VCS information Go embeds in the
+// binary, where available.
+var (
+	Commit    = ""
+	BuildDate = ""
+)
+
+// githubAPIVersion is the GitHub REST API version that go-github targets.
+const githubAPIVersion = "v3"
+
+// buildInfo describes the running binary, for bug reports.
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	GoGitHub  string `json:"goGitHub"`
+	GitHubAPI string `json:"githubAPI"`
+}
+
+func getBuildInfo() buildInfo {
+	bi := buildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		GoGitHub:  "unknown",
+		GitHubAPI: githubAPIVersion,
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return bi
+	}
+	for _, s := range info.Settings {
+		switch {
+		case s.Key == "vcs.revision" && bi.Commit == "":
+			bi.Commit = s.Value
+		case s.Key == "vcs.time" && bi.BuildDate == "":
+			bi.BuildDate = s.Value
+		}
+	}
+	for _, dep := range info.Deps {
+		if strings.HasPrefix(dep.Path, "github.com/google/go-github/") {
+			bi.GoGitHub = dep.Version
+		}
+	}
+	return bi
+}
+
+func (bi buildInfo) String() string {
+	return fmt.Sprintf(
+		"version: %s; commit: %s; built: %s; %s; go-github: %s (GitHub API %s)",
+		bi.Version, orUnknown(bi.Commit), orUnknown(bi.BuildDate), bi.GoVersion, bi.GoGitHub, bi.GitHubAPI,
+	)
+}
+
+// printVersion implements the version command.
+func printVersion(output string) error {
+	bi := getBuildInfo()
+	if output == outputJSON {
+		return writeJSON(os.Stdout, bi)
+	}
+	fmt.Printf("github2omnifocus %s\n", bi.Version)
+	fmt.Printf("  commit:     %s\n", orUnknown(bi.Commit))
+	fmt.Printf("  built:      %s\n", orUnknown(bi.BuildDate))
+	fmt.Printf("  go:         %s\n", bi.GoVersion)
+	fmt.Printf("  go-github:  %s\n", bi.GoGitHub)
+	fmt.Printf("  GitHub API: %s\n", bi.GitHubAPI)
+	return nil
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
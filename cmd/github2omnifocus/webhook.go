@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second} //nolint:gomnd
+
+// notifyWebhook posts a summary of an account's sync, started at started,
+// to its WebhookURL, if it has one and WebhookOn says to. Failures are
+// logged, as they don't affect the sync.
+func notifyWebhook(c internal.GithubConfig, started time.Time, s *syncStats) {
+	if c.WebhookURL == "" || !shouldNotify(c.WebhookOn, s) {
+		return
+	}
+
+	stats := []*syncStats{s}
+	result := newSyncResult(started, exitCode(stats, false), stats)
+	var payload any = result
+	switch webhookFormat(c) {
+	case internal.WebhookFormatSlack:
+		payload = map[string]string{"text": webhookText(result.Accounts[0])}
+	case internal.WebhookFormatDiscord:
+		payload = map[string]string{"content": webhookText(result.Accounts[0])}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("[%s] Error encoding webhook payload: %v", s.account, err)
+		return
+	}
+	resp, err := webhookClient.Post(c.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// Errors from the client include the URL, which is secret.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		log.Printf("[%s] Error posting to webhook: %v", s.account, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 { //nolint:gomnd
+		log.Printf("[%s] Error posting to webhook: %s", s.account, resp.Status)
+	}
+}
+
+// shouldNotify reports whether a sync should be posted or emailed about,
+// given the WebhookOn or EmailOn value on.
+func shouldNotify(on string, s *syncStats) bool {
+	switch on {
+	case internal.NotifyOnAlways:
+		return true
+	case internal.NotifyOnErrors:
+		return s.err != nil
+	}
+	return s.err != nil || len(s.adds) > 0 || len(s.removes) > 0
+}
+
+// webhookFormat returns c's WebhookFormat, working it out from the URL
+// when it's not set.
+func webhookFormat(c internal.GithubConfig) string {
+	if c.WebhookFormat != "" {
+		return c.WebhookFormat
+	}
+	u, err := url.Parse(c.WebhookURL)
+	if err != nil {
+		return internal.WebhookFormatJSON
+	}
+	switch u.Hostname() {
+	case "hooks.slack.com":
+		return internal.WebhookFormatSlack
+	case "discord.com", "discordapp.com":
+		return internal.WebhookFormatDiscord
+	}
+	return internal.WebhookFormatJSON
+}
+
+// webhookText summarises an account's sync as a chat message.
+func webhookText(r accountResult) string {
+	switch {
+	case r.Error != "":
+		return fmt.Sprintf("github2omnifocus: %s sync failed: %s", r.Account, r.Error)
+	case r.Skipped != "":
+		return fmt.Sprintf("github2omnifocus: %s sync skipped: %s", r.Account, r.Skipped)
+	}
+	changes := []string{}
+	for _, op := range []struct {
+		name   string
+		counts map[string]int
+	}{{"added", r.Added}, {"completed", r.Completed}} {
+		parts := []string{}
+		for _, category := range slices.Sorted(maps.Keys(op.counts)) {
+			parts = append(parts, fmt.Sprintf("%d %s", op.counts[category], category))
+		}
+		if len(parts) > 0 {
+			changes = append(changes, op.name+" "+strings.Join(parts, ", "))
+		}
+	}
+	if len(changes) == 0 {
+		changes = append(changes, "no changes")
+	}
+	changes = append(changes, r.Warnings...)
+	return fmt.Sprintf("github2omnifocus: %s synced: %s", r.Account, strings.Join(changes, "; "))
+}
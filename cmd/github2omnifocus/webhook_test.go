@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+func TestNotifyWebhook(t *testing.T) {
+	posts := []map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		posts = append(posts, body)
+	}))
+	defer server.Close()
+
+	unchanged := newSyncStats(context.Background(), "work")
+	changed := newSyncStats(context.Background(), "work")
+	changed.adds["PRs"] = 2
+	failed := newSyncStats(context.Background(), "work")
+	failed.err = errors.New("Omnifocus quit")
+
+	c := internal.GithubConfig{WebhookURL: server.URL, WebhookFormat: internal.WebhookFormatSlack}
+	for _, s := range []*syncStats{unchanged, changed, failed} {
+		notifyWebhook(c, time.Now(), s)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("Expected posts for the changed and failed syncs, got: %v", posts)
+	}
+	if posts[0]["text"] != "github2omnifocus: work synced: added 2 PRs" || posts[1]["text"] != "github2omnifocus: work sync failed: Omnifocus quit" {
+		t.Errorf("Unexpected posts: %v", posts)
+	}
+
+	posts = nil
+	c.WebhookFormat, c.WebhookOn = internal.WebhookFormatJSON, internal.NotifyOnErrors
+	for _, s := range []*syncStats{unchanged, changed, failed} {
+		notifyWebhook(c, time.Now(), s)
+	}
+	if len(posts) != 1 || posts[0]["exitCode"] != float64(exitTotalFailure) {
+		t.Errorf("Expected the failed sync's result, got: %v", posts)
+	}
+}
+
+func TestWebhookFormat(t *testing.T) {
+	for u, want := range map[string]string{
+		"https://hooks.slack.com/services/T0/B0/x": internal.WebhookFormatSlack,
+		"https://discord.com/api/webhooks/1/x":     internal.WebhookFormatDiscord,
+		"https://example.com/hook":                 internal.WebhookFormatJSON,
+	} {
+		if got := webhookFormat(internal.GithubConfig{WebhookURL: u}); got != want {
+			t.Errorf("%s: got %s, want %s", u, got, want)
+		}
+	}
+}
+
+func TestWebhookText(t *testing.T) {
+	r := accountResult{Account: "work", Added: map[string]int{"issues": 1, "PRs": 2}, Completed: map[string]int{"notifications": 3}}
+	want := "github2omnifocus: work synced: added 2 PRs, 1 issues; completed 3 notifications"
+	if got := webhookText(r); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWebhookTextWarnings(t *testing.T) {
+	r := accountResult{Account: "work", Warnings: []string{"GitHub token expires 2024-06-01 12:00:00"}}
+	want := "github2omnifocus: work synced: no changes; GitHub token expires 2024-06-01 12:00:00"
+	if got := webhookText(r); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -5,19 +5,57 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"maps"
+	"net"
+	"net/url"
 	"os"
+	"os/exec"
 	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/cron"
 )
 
 type Config = map[string]GithubConfig
 
+// appDir is the name of the app's directory within config and cache dirs.
+const appDir = "github2omnifocus"
+
+// defaultsKey is the config file key whose values are applied to every
+// account before the account's own values.
+const defaultsKey = "defaults"
+
+// includeKey is the config file key listing other config files to merge
+// in before the file's own values.
+const includeKey = "include"
+
 type GithubConfig struct {
 	// API URL for GitHub
 	APIURL string
 	// Personal Access token
 	AccessToken string
+	// True to use the token the gh CLI is logged in with, instead of
+	// AccessToken
+	AuthFromGhCli bool
+	// Proxy used for requests to GitHub, if not set via the environment
+	ProxyURL string
+	// PEM file of extra CA certificates to trust for GitHub
+	CACertFile string
+	// Disable TLS certificate verification for GitHub (not recommended)
+	InsecureSkipVerify bool
 	// OF Tag applied to every task managed by the app (so we never mess with other tasks)
 	AppTag string
+	// True if, with several accounts, AppTag and the main projects should
+	// be derived from the account's name unless they're set, e.g.
+	// github-work and "GitHub Work: Reviews"
+	DeriveAccountNames bool
 	// OF Project that assigned issues are added to
 	AssignedProject string
 	// OF Tag for assigned items
@@ -30,39 +68,395 @@ type GithubConfig struct {
 	NotificationsProject string
 	// OF Tag for notifications
 	NotificationTag string
+	// OF Project for release notifications, if they shouldn't go in
+	// NotificationsProject
+	ReleasesProject string
+	// OF Project for security advisory and Dependabot alert notifications,
+	// if they shouldn't go in NotificationsProject
+	SecurityProject string
+	// True if notifications and mentions should go in a project per repo,
+	// within a folder named NotificationsProject
+	NotificationsByRepo bool
 	// True if due date of today should be set on notifications
 	SetNotificationsDueDate bool
 	// True if app should attempt to set correct deadline for Task master apps
 	SetTaskmasterDueDate bool
 	// Tag used to id task master task
 	TaskMasterTaskTag string
+	// Names for the months, January first, matched against tags for task
+	// master deadlines as well as English abbreviations such as Jan
+	TaskMasterMonths []string
 	// Project used to show pending code changes ie those I have written
 	PendingChangesProject string
 	// Tag used to id pending code changes ie those I have written
 	PendingChangesTag string
+	// Project for failing GitHub Actions workflows on my open PRs; the
+	// category is disabled when empty
+	FailingChecksProject string
+	// Tag used to id failing workflow tasks
+	FailingChecksTag string
+	// Repos, such as "acme/tools", whose latest release should be a task
+	// in WatchedReleasesProject, tagged with WatchedReleasesTag
+	WatchedReleases        []string
+	WatchedReleasesProject string
+	WatchedReleasesTag     string
+	// Tag for tasks, in NotificationsProject, for open issues and PRs you're
+	// mentioned in but didn't open and aren't assigned or asked to review;
+	// the category is disabled when empty
+	MentionsTag string
+	// If non-zero, watched release tasks are completed this many days
+	// after the release, if a newer release hasn't completed them already
+	WatchedReleaseDays int
+	// True if extra OF tasks sharing a key with another task should be
+	// completed, rather than only reported in the log
+	CompleteDuplicateTasks bool
+	// If non-zero, review tasks are due this many hours after the review
+	// request was first synced
+	ReviewDueInHours int
+	// Overrides ReviewDueInHours for repos matching a pattern, such as
+	// "acme/payments" or "acme/*"; the longest matching pattern wins
+	ReviewDueInHoursByRepo map[string]int
+	// If non-zero, issue tasks without another deadline are due this many
+	// days after the issue was first synced
+	IssueDueInDays int
+	// Single select field, such as "Priority", of the Projects (v2) issues
+	// and PRs are in, whose values set how urgent their tasks are. Values
+	// in ProjectPriorityFlagged flag the task, and ProjectPriorityDueInHours
+	// makes the task due this many hours after it was first synced, if
+	// that's sooner than it would be otherwise
+	ProjectPriorityField      string
+	ProjectPriorityFlagged    []string
+	ProjectPriorityDueInHours map[string]int
+	// Iteration field, such as "Sprint", of the Projects (v2) issues and
+	// PRs are in. Their tasks are deferred until the iteration starts, and
+	// due by its end
+	ProjectIterationField string
+	// If non-zero, task titles longer than this many characters are cut
+	// short; the item's key is always kept whole
+	MaxTitleLength int
+	// Regex matched against issue bodies and labels, whose first group is
+	// the issue's due date as YYYY-MM-DD, e.g. `due[:/]\s*(\d{4}-\d{2}-\d{2})`
+	DueDatePattern string
+	// Child tasks to create under each review task, e.g. "read description"
+	ReviewChecklist []string
+	// True to leave out PRs whose review was only requested from one of
+	// your teams
+	DirectReviewRequestsOnly bool
+	// "public" or "private" to sync only items in public or private
+	// repositories, or "" for both
+	RepoVisibility string
+	// True to leave out items in forks
+	ExcludeForks bool
+	// True if the topics of an issue or PR's repository should be added to
+	// its task's tags, along with its labels
+	RepoTopicsAsTags bool
+	// If set, review tasks are tagged with a size, e.g. size:S, being the
+	// name of the smallest threshold of changed lines the PR fits in, or
+	// size:XL if none, e.g. {"XS": 10, "S": 50, "M": 250, "L": 1000}
+	PRSizeThresholds map[string]int
+	// Emoji to prefix to the titles of tasks for items with a label, e.g.
+	// {"bug": "🐛", "sev1": "🔥"}
+	LabelEmoji map[string]string
+	// True if issue and review tasks should be tagged with their author,
+	// e.g. author:alice
+	AuthorTags bool
+	// Case for the tags from labels, repos and milestones on new tasks:
+	// "lower", "title", or "" to keep their case, e.g. "title" tags both
+	// bug and Bug labels Bug
+	TagCase string
+	// True if issue tasks should have a child task for each unchecked item
+	// in the task list in the issue's description
+	IssueTaskLists bool
+	// Assigned issues with one of these labels, e.g. "epic", are tracking
+	// issues, whose task list items become tasks in a project per issue
+	TrackingIssueLabels []string
+	// Tag used to id tracking issue task list item tasks
+	TrackingTag string
+	// Folder for tracking issue projects, if they shouldn't be top level
+	TrackingFolder string
+	// Type of the projects created for tracking issues: "parallel",
+	// "sequential" or "single actions"
+	TrackingProjectType string
+	// Types of other projects by name, with the same values as
+	// TrackingProjectType, e.g. {"GitHub Reviews": "sequential"}. Projects
+	// that don't exist are created. NotificationsProject is a single action
+	// list unless given a type here, with "" leaving it as it is
+	ProjectTypes map[string]string
+	// If non-zero, projects github2omnifocus creates, for tracking issues,
+	// ProjectTypes or NotificationsByRepo, come up for review in Omnifocus
+	// every this many days
+	ProjectReviewIntervalDays int
+	// If non-zero, days after a project is created that it's first
+	// reviewed, rather than after ProjectReviewIntervalDays
+	ProjectFirstReviewDays int
+	// True if authored PR tasks should have a child task for each reviewer
+	// whose review is still awaited
+	TrackPendingReviewers bool
+	// True if authored PR tasks should be tagged with who the PR is waiting
+	// on: waiting:me if changes were requested, a check failed or it has
+	// conflicts, and waiting:reviewers otherwise
+	TrackWaitingOn bool
+	// If non-zero, authored PR tasks waiting on reviewers are deferred this
+	// many hours from when they're added
+	WaitingOnReviewersDeferHours int
+	// True if tasks should only be added and completed, never re-created
+	// because their tags or title differ from GitHub's
+	KeyOnlyComparison bool
+	// Categories, e.g. "notifications", to compare by key only when
+	// KeyOnlyComparison isn't set
+	KeyOnlyCategories []string
+	// True if no tasks should be added, only completed, say while on
+	// leave
+	FreezeAdditions bool
+	// True if task notes should record the account and API URL the task
+	// was synced from, and when it was last synced
+	NoteMetadata bool
+	// True if a line saying why should be added to a task's note before
+	// it's completed
+	AnnotateCompletedTasks bool
+	// True if tasks for issues closed as not planned should be dropped
+	// rather than completed
+	DropNotPlannedIssues bool
+	// What to do with the tasks for PRs closed without merging, by
+	// category, "reviews" or "authored": "drop" to drop the task, "keep" to
+	// leave it open with a note saying why, or "" to complete it, e.g.
+	// {"authored": "keep"}
+	UnmergedPRAction map[string]string
+	// What to do when a task the app completed is marked incomplete again:
+	// "" to complete it again, "keep" to leave it, or "reopen" or "comment"
+	// to also reopen or comment on the issue or PR
+	UncompletedAction string
+	// Comment left when UncompletedAction is "comment"
+	UncompletedComment string
+	// If non-zero, only notifications updated since the last sync are
+	// fetched, with all of them fetched at least this often to catch those
+	// read elsewhere
+	IncrementalNotificationsHours int
+	// Which notifications to fetch: "" for every unread one,
+	// "participating" for unread ones about threads you're participating
+	// in, or "all" to include read ones too
+	NotificationsFilter string
+	// If non-zero, read notifications updated in the last this many hours
+	// are fetched as well as unread ones
+	ReadNotificationsHours int
+	// If non-zero, tasks for notifications still unread this many days
+	// after they were first seen are completed, and not added again until
+	// there's new activity
+	StaleNotificationDays int
+	// True if stale notifications' tasks should be dropped rather than
+	// completed
+	DropStaleNotifications bool
+	// True if stale notifications should be marked read on GitHub
+	MarkStaleNotificationsRead bool
+	// If non-zero, the most requests made to GitHub in a sync. Once it's
+	// reached, the sync carries on with what's been fetched, only adding
+	// tasks for categories that weren't fully fetched
+	MaxRequestsPerSync int
+	// Days before an expiring token expires to start warning about it,
+	// 7 if unset
+	TokenExpiryWarningDays int
+	// How GitHub orders assigned issues and search results, such as review
+	// requests: "created", "updated" or "comments", or GitHub's default
+	// if unset
+	SearchSort string
+	// "asc" or "desc" for SearchSort's order, "desc" if unset
+	SearchOrder string
+	// If non-zero, the most items synced in each category, being the first
+	// in SearchSort's order, or the most recently updated if SearchSort is
+	// unset; tasks for the rest are left as they are
+	MaxItemsPerCategory int
+	// Search query templates replacing the defaults by name: "issues" for
+	// assigned issues, "reviews", "authored" or "mentions". {{.User}} is
+	// your login, e.g. {"reviews": "type:pr state:open review-requested:{{.User}} -label:on-hold"}
+	SearchQueries map[string]string
+	// How to drive Omnifocus: "jxa", "applescript" for older versions of
+	// Omnifocus, or "" to use AppleScript only if JXA doesn't work
+	OmnifocusDriver string
+	// The name of the Omnifocus document to sync with, such as a separate
+	// database for testing, or "" for the default document
+	OmnifocusDocument string
+	// What to do when Omnifocus isn't running: "" to sync anyway, "launch"
+	// to launch it, or "skip" to skip the account's sync until the next run
+	OmnifocusNotRunning string
+	// Cron expressions for when the daemon syncs the account, e.g.
+	// "*/10 9-17 * * 1-5" and "0 * * * *" for every ten minutes in working
+	// hours and hourly otherwise. If unset, it syncs every --interval
+	Schedule []string
+	// What to do with notifications about an issue or PR that has its own
+	// task: "" to add a notification task as well, "suppress" to not, or
+	// "merge" to add a link to the notification to the task's note instead
+	CoveredNotifications string
+	// True to drop notifications about your own latest comment
+	SuppressOwnActivity bool
+	// URL to post a summary of each of the account's syncs to, such as a
+	// Slack or Discord incoming webhook
+	WebhookURL string
+	// What to post to WebhookURL: "slack" or "discord" for a message, or
+	// "json" for the sync's result as written by --result-file. If "", it's
+	// worked out from WebhookURL
+	WebhookFormat string
+	// When to post to WebhookURL: "" after syncs that changed something or
+	// failed, "always", or "errors" for only after failures
+	WebhookOn string
+	// SMTP server, as host:port, to email a summary of each of the
+	// account's syncs through. It must support STARTTLS unless it's local
+	SMTPServer string
+	// Username and password for SMTPServer, if it needs them
+	SMTPUsername string
+	SMTPPassword string
+	// Addresses the summary is emailed from and to
+	EmailFrom string
+	EmailTo   []string
+	// When to email the summary, with the same values as WebhookOn
+	EmailOn string
 }
 
-// LoadConfig loads JSON config from ~/.config/github2omnifocus/config.json
-func LoadConfig2() (Config, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return make(Config), fmt.Errorf("could not find home dir: %v", err)
+// Values for OmnifocusDriver.
+const (
+	OmnifocusDriverJXA         = "jxa"
+	OmnifocusDriverAppleScript = "applescript"
+)
+
+// Values for OmnifocusNotRunning.
+const (
+	OmnifocusNotRunningLaunch = "launch"
+	OmnifocusNotRunningSkip   = "skip"
+)
+
+// Values for CoveredNotifications.
+const (
+	CoveredNotificationsSuppress = "suppress"
+	CoveredNotificationsMerge    = "merge"
+)
+
+// Values for NotificationsFilter.
+const (
+	NotificationsParticipating = "participating"
+	NotificationsAll           = "all"
+)
+
+// Values for RepoVisibility.
+const (
+	RepoVisibilityPublic  = "public"
+	RepoVisibilityPrivate = "private"
+)
+
+// Values for WebhookFormat.
+const (
+	WebhookFormatSlack   = "slack"
+	WebhookFormatDiscord = "discord"
+	WebhookFormatJSON    = "json"
+)
+
+// Values for WebhookOn and EmailOn.
+const (
+	NotifyOnAlways = "always"
+	NotifyOnErrors = "errors"
+)
+
+// Values for UnmergedPRAction.
+const (
+	UnmergedPRActionDrop = "drop"
+	UnmergedPRActionKeep = "keep"
+)
+
+// Values for TrackingProjectType and ProjectTypes.
+const (
+	ProjectTypeParallel      = "parallel"
+	ProjectTypeSequential    = "sequential"
+	ProjectTypeSingleActions = "single actions"
+)
+
+// Values for TagCase.
+const (
+	TagCaseLower = "lower"
+	TagCaseTitle = "title"
+)
+
+// Values for UncompletedAction.
+const (
+	UncompletedActionKeep    = "keep"
+	UncompletedActionReopen  = "reopen"
+	UncompletedActionComment = "comment"
+)
+
+// defaultUncompletedComment is used when UncompletedComment isn't set.
+const defaultUncompletedComment = "This still needs attention, so I've marked it as not done in Omnifocus."
+
+// ConfigPath returns the path of the config file to load. An explicit path,
+// e.g. from a command line flag, is used as given. Otherwise the G2O_CONFIG
+// environment variable is used, which is either an absolute path or a file
+// name, defaulting to config.json, looked for in each of configDirs.
+func ConfigPath(explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
 	}
 
 	configFile := os.Getenv("G2O_CONFIG")
+	if path.IsAbs(configFile) {
+		return configFile, nil
+	}
 	if configFile == "" {
 		configFile = "config.json"
 	}
-	configPath := path.Join(home, ".config", "github2omnifocus", configFile)
 
-	var bytes []byte
-	bytes, err = ioutil.ReadFile(configPath)
+	dirs, err := configDirs()
 	if err != nil {
-		return make(Config), fmt.Errorf("expected config.json at %s: %v", configPath, err)
+		return "", err
+	}
+	for _, dir := range dirs {
+		p := path.Join(dir, configFile)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
 	}
+	// None exist, so report the preferred location in the error when the
+	// file can't be read.
+	return path.Join(dirs[0], configFile), nil
+}
 
-	c := make(Config)
-	err = json.Unmarshal(bytes, &c)
+// configDirs returns the directories searched for config files, most
+// preferred first: $XDG_CONFIG_HOME/github2omnifocus when set, then
+// ~/.config/github2omnifocus, then the OS's config dir, which on macOS is
+// ~/Library/Application Support.
+func configDirs() ([]string, error) {
+	dirs := []string{}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); path.IsAbs(xdg) {
+		dirs = append(dirs, path.Join(xdg, appDir))
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("could not find home dir: %v", err)
+	}
+	dirs = append(dirs, path.Join(home, ".config", appDir))
+	if userConfig, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, path.Join(userConfig, appDir))
+	}
+	return slices.Compact(dirs), nil
+}
+
+// CacheDir returns the directory used for state kept between runs:
+// $XDG_CACHE_HOME/github2omnifocus when set, otherwise within the OS's
+// cache dir, which on macOS is ~/Library/Caches.
+func CacheDir() (string, error) {
+	if xdg := os.Getenv("XDG_CACHE_HOME"); path.IsAbs(xdg) {
+		return path.Join(xdg, appDir), nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find cache dir: %v", err)
+	}
+	return path.Join(dir, appDir), nil
+}
+
+// LoadConfig loads JSON config from configPath, see ConfigPath.
+func LoadConfig2(configPath string) (Config, error) {
+	rc, err := loadRawConfig(configPath, map[string]bool{})
+	if err != nil {
+		return make(Config), err
+	}
+
+	c, err := buildConfig(rc)
 	if err != nil {
 		return c, fmt.Errorf("error unmarshalling config JSON from %s: %v", configPath, err)
 	}
@@ -71,7 +465,9 @@ func LoadConfig2() (Config, error) {
 
 	for _, v := range c {
 		log.Printf("  GitHub API server: %s", v.APIURL)
-		if v.AccessToken != "" {
+		if v.AuthFromGhCli {
+			log.Printf("  GitHub token: from gh CLI")
+		} else if v.AccessToken != "" {
 			log.Printf("  GitHub token: *****")
 		} else {
 			log.Printf("  GitHub token: <none, likely error!>")
@@ -84,3 +480,510 @@ func LoadConfig2() (Config, error) {
 
 	return c, nil
 }
+
+// Token returns the access token to use for GitHub, either from the config
+// or, with AuthFromGhCli, from the gh CLI's stored credentials for the
+// APIURL's host.
+func (gc GithubConfig) Token() (string, error) {
+	if !gc.AuthFromGhCli {
+		return gc.AccessToken, nil
+	}
+	host, err := ghCliHost(gc.APIURL)
+	if err != nil {
+		return "", err
+	}
+	out, err := exec.Command("gh", "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting token from gh CLI for %s, try `gh auth login --hostname %s`: %v", host, host, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// KeyOnly reports whether tasks in category should be compared by key only,
+// ignoring differences in tags and title.
+func (gc GithubConfig) KeyOnly(category string) bool {
+	if gc.KeyOnlyComparison {
+		return true
+	}
+	return slices.ContainsFunc(gc.KeyOnlyCategories, func(c string) bool {
+		return strings.EqualFold(c, category)
+	})
+}
+
+// ReviewDueIn returns how long after a review request for a PR in repo is
+// first synced its task is due, or zero for no due date.
+func (gc GithubConfig) ReviewDueIn(repo string) time.Duration {
+	hours := gc.ReviewDueInHours
+	longest := -1
+	for pattern, h := range gc.ReviewDueInHoursByRepo {
+		if ok, _ := path.Match(pattern, repo); ok && len(pattern) > longest {
+			hours = h
+			longest = len(pattern)
+		}
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// NextSync returns when the account should next be synced by the daemon,
+// given it was last synced at last: the soonest time in its Schedule or,
+// without one, interval after last.
+func (gc GithubConfig) NextSync(last time.Time, interval time.Duration) time.Time {
+	if len(gc.Schedule) == 0 {
+		return last.Add(interval)
+	}
+	next := time.Time{}
+	for _, expr := range gc.Schedule {
+		// Already checked when the config was loaded.
+		s, err := cron.Parse(expr)
+		if err != nil {
+			continue
+		}
+		t := s.Next(last)
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next
+}
+
+// defaultTokenExpiryWarningDays is used when TokenExpiryWarningDays isn't
+// set.
+const defaultTokenExpiryWarningDays = 7
+
+// TokenExpiryWarning returns how long before the token expires to warn
+// about it.
+func (gc GithubConfig) TokenExpiryWarning() time.Duration {
+	days := gc.TokenExpiryWarningDays
+	if days == 0 {
+		days = defaultTokenExpiryWarningDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Sort returns how GitHub should order items. It's SearchSort and
+// SearchOrder, except that when MaxItemsPerCategory keeps only the first
+// items and SearchSort is unset, the most recently updated come first.
+func (gc GithubConfig) Sort() (sort, order string) {
+	if gc.SearchSort == "" && gc.MaxItemsPerCategory > 0 {
+		return "updated", gc.SearchOrder
+	}
+	return gc.SearchSort, gc.SearchOrder
+}
+
+// Comment returns the comment to leave when a task is marked incomplete
+// again.
+func (gc GithubConfig) Comment() string {
+	if gc.UncompletedComment == "" {
+		return defaultUncompletedComment
+	}
+	return gc.UncompletedComment
+}
+
+// ProjectTypesWithDefaults returns ProjectTypes, with NotificationsProject
+// a single action list unless it's given a type there, as notifications
+// are unrelated to each other. With NotificationsByRepo set,
+// NotificationsProject names a folder instead, so is left out.
+func (gc GithubConfig) ProjectTypesWithDefaults() map[string]string {
+	types := maps.Clone(gc.ProjectTypes)
+	if types == nil {
+		types = map[string]string{}
+	}
+	if _, ok := types[gc.NotificationsProject]; !ok && gc.NotificationsProject != "" && !gc.NotificationsByRepo {
+		types[gc.NotificationsProject] = ProjectTypeSingleActions
+	}
+	return types
+}
+
+// searchQueryNames are the names of the search queries SearchQueries can
+// replace.
+var searchQueryNames = []string{"issues", "reviews", "authored", "mentions"}
+
+// validate checks values that are only allowed to be one of a few options.
+func (gc GithubConfig) validate() error {
+	if len(gc.WatchedReleases) > 0 && (gc.WatchedReleasesProject == "" || gc.WatchedReleasesTag == "") {
+		return fmt.Errorf("WatchedReleasesProject and WatchedReleasesTag must be set to use WatchedReleases")
+	}
+	if len(gc.TaskMasterMonths) > 0 && len(gc.TaskMasterMonths) != 12 {
+		return fmt.Errorf("TaskMasterMonths must name all 12 months, got %d", len(gc.TaskMasterMonths))
+	}
+	if gc.WaitingOnReviewersDeferHours != 0 && !gc.TrackWaitingOn {
+		return fmt.Errorf("TrackWaitingOn must be set to use WaitingOnReviewersDeferHours")
+	}
+	if (gc.DropStaleNotifications || gc.MarkStaleNotificationsRead) && gc.StaleNotificationDays == 0 {
+		return fmt.Errorf("StaleNotificationDays must be set to use DropStaleNotifications or MarkStaleNotificationsRead")
+	}
+	if len(gc.TrackingIssueLabels) > 0 && gc.TrackingTag == "" {
+		return fmt.Errorf("TrackingTag must be set to use TrackingIssueLabels")
+	}
+	if gc.ProjectPriorityField == "" && (len(gc.ProjectPriorityFlagged) > 0 || len(gc.ProjectPriorityDueInHours) > 0) {
+		return fmt.Errorf("ProjectPriorityField must be set to use ProjectPriorityFlagged or ProjectPriorityDueInHours")
+	}
+	for pattern := range gc.ReviewDueInHoursByRepo {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad repo pattern %q in ReviewDueInHoursByRepo: %v", pattern, err)
+		}
+	}
+	if gc.DueDatePattern != "" {
+		re, err := regexp.Compile(gc.DueDatePattern)
+		if err != nil {
+			return fmt.Errorf("bad DueDatePattern: %v", err)
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("DueDatePattern must have a group matching the date")
+		}
+	}
+	for _, expr := range gc.Schedule {
+		if _, err := cron.Parse(expr); err != nil {
+			return err
+		}
+	}
+	switch gc.SearchSort {
+	case "", "created", "updated", "comments":
+	default:
+		return fmt.Errorf("SearchSort must be \"created\", \"updated\" or \"comments\", not %q", gc.SearchSort)
+	}
+	for name, query := range gc.SearchQueries {
+		if !slices.Contains(searchQueryNames, name) {
+			return fmt.Errorf("SearchQueries can only replace the %s queries, not %q", strings.Join(searchQueryNames, ", "), name)
+		}
+		if _, err := template.New(name).Parse(query); err != nil {
+			return fmt.Errorf("bad %s query in SearchQueries: %v", name, err)
+		}
+	}
+	switch gc.SearchOrder {
+	case "", "asc", "desc":
+	default:
+		return fmt.Errorf("SearchOrder must be \"asc\" or \"desc\", not %q", gc.SearchOrder)
+	}
+	switch gc.OmnifocusDriver {
+	case "", OmnifocusDriverJXA, OmnifocusDriverAppleScript:
+	default:
+		return fmt.Errorf("OmnifocusDriver must be %q or %q, not %q", OmnifocusDriverJXA, OmnifocusDriverAppleScript, gc.OmnifocusDriver)
+	}
+	switch gc.OmnifocusNotRunning {
+	case "", OmnifocusNotRunningLaunch, OmnifocusNotRunningSkip:
+	default:
+		return fmt.Errorf("OmnifocusNotRunning must be %q or %q, not %q", OmnifocusNotRunningLaunch, OmnifocusNotRunningSkip, gc.OmnifocusNotRunning)
+	}
+	switch gc.CoveredNotifications {
+	case "", CoveredNotificationsSuppress, CoveredNotificationsMerge:
+	default:
+		return fmt.Errorf("CoveredNotifications must be %q or %q, not %q", CoveredNotificationsSuppress, CoveredNotificationsMerge, gc.CoveredNotifications)
+	}
+	if gc.WebhookURL != "" {
+		u, err := url.Parse(gc.WebhookURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			// The URL isn't given, as webhook URLs are secret.
+			return fmt.Errorf("WebhookURL must be an http or https URL")
+		}
+	}
+	switch gc.NotificationsFilter {
+	case "", NotificationsParticipating, NotificationsAll:
+	default:
+		return fmt.Errorf("NotificationsFilter must be %q or %q, not %q", NotificationsParticipating, NotificationsAll, gc.NotificationsFilter)
+	}
+	switch gc.RepoVisibility {
+	case "", RepoVisibilityPublic, RepoVisibilityPrivate:
+	default:
+		return fmt.Errorf("RepoVisibility must be %q or %q, not %q", RepoVisibilityPublic, RepoVisibilityPrivate, gc.RepoVisibility)
+	}
+	switch gc.WebhookFormat {
+	case "", WebhookFormatSlack, WebhookFormatDiscord, WebhookFormatJSON:
+	default:
+		return fmt.Errorf("WebhookFormat must be %q, %q or %q, not %q", WebhookFormatSlack, WebhookFormatDiscord, WebhookFormatJSON, gc.WebhookFormat)
+	}
+	switch gc.WebhookOn {
+	case "", NotifyOnAlways, NotifyOnErrors:
+	default:
+		return fmt.Errorf("WebhookOn must be %q or %q, not %q", NotifyOnAlways, NotifyOnErrors, gc.WebhookOn)
+	}
+	if len(gc.EmailTo) > 0 {
+		if _, _, err := net.SplitHostPort(gc.SMTPServer); err != nil {
+			return fmt.Errorf("SMTPServer must be a host:port to send email to EmailTo, not %q", gc.SMTPServer)
+		}
+		if gc.EmailFrom == "" {
+			return fmt.Errorf("EmailFrom must be set to send email to EmailTo")
+		}
+	}
+	switch gc.EmailOn {
+	case "", NotifyOnAlways, NotifyOnErrors:
+	default:
+		return fmt.Errorf("EmailOn must be %q or %q, not %q", NotifyOnAlways, NotifyOnErrors, gc.EmailOn)
+	}
+	for category, action := range gc.UnmergedPRAction {
+		if category != "reviews" && category != "authored" {
+			return fmt.Errorf("UnmergedPRAction is for \"reviews\" or \"authored\", not %q", category)
+		}
+		switch action {
+		case "", UnmergedPRActionDrop, UnmergedPRActionKeep:
+		default:
+			return fmt.Errorf("UnmergedPRAction must be %q or %q, not %q", UnmergedPRActionDrop, UnmergedPRActionKeep, action)
+		}
+	}
+	if err := validateProjectType("TrackingProjectType", gc.TrackingProjectType); err != nil {
+		return err
+	}
+	for name, t := range gc.ProjectTypes {
+		if err := validateProjectType(fmt.Sprintf("ProjectTypes[%q]", name), t); err != nil {
+			return err
+		}
+	}
+	switch gc.TagCase {
+	case "", TagCaseLower, TagCaseTitle:
+	default:
+		return fmt.Errorf("TagCase must be %q or %q, not %q", TagCaseLower, TagCaseTitle, gc.TagCase)
+	}
+	switch gc.UncompletedAction {
+	case "", UncompletedActionKeep, UncompletedActionReopen, UncompletedActionComment:
+	default:
+		return fmt.Errorf("UncompletedAction must be %q, %q or %q, not %q", UncompletedActionKeep, UncompletedActionReopen, UncompletedActionComment, gc.UncompletedAction)
+	}
+	return nil
+}
+
+// validateProjectType checks t, the value of the setting name, is a
+// project type.
+func validateProjectType(name, t string) error {
+	switch t {
+	case "", ProjectTypeParallel, ProjectTypeSequential, ProjectTypeSingleActions:
+		return nil
+	}
+	return fmt.Errorf("%s must be %q, %q or %q, not %q", name, ProjectTypeParallel, ProjectTypeSequential, ProjectTypeSingleActions, t)
+}
+
+// ghCliHost returns the host the gh CLI knows a server by, which for
+// github.com is not the API host.
+func ghCliHost(apiURL string) (string, error) {
+	if apiURL == "" {
+		return "github.com", nil
+	}
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid APIURL %q: %v", apiURL, err)
+	}
+	if u.Host == "api.github.com" {
+		return "github.com", nil
+	}
+	return u.Host, nil
+}
+
+// rawConfig is a config file split into its sections (accounts and
+// defaults) and then into fields. Fields are kept as raw JSON so that
+// several files can be merged field by field before decoding.
+type rawConfig map[string]map[string]json.RawMessage
+
+// merge copies the fields of other into rc, overwriting fields rc already
+// has.
+func (rc rawConfig) merge(other rawConfig) {
+	for section, fields := range other {
+		if rc[section] == nil {
+			rc[section] = map[string]json.RawMessage{}
+		}
+		for k, v := range fields {
+			rc[section][k] = v
+		}
+	}
+}
+
+// loadRawConfig reads the config file at configPath along with any files it
+// lists in "include". Included files are merged in order, and then the
+// including file's own values are merged over them. Relative include paths
+// are relative to the including file, and https URLs are fetched. Files
+// encrypted with age are decrypted first. seen guards against include cycles.
+func loadRawConfig(configPath string, seen map[string]bool) (rawConfig, error) {
+	if seen[configPath] {
+		return nil, fmt.Errorf("config file %s includes itself", configPath)
+	}
+	seen[configPath] = true
+	defer delete(seen, configPath)
+
+	bytes, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("expected config.json at %s: %v", configPath, err)
+	}
+	bytes, err = decryptConfig(configPath, bytes)
+	if err != nil {
+		return nil, err
+	}
+	own, includes, err := decodeRawConfig(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling config JSON from %s: %v", configPath, err)
+	}
+
+	rc := rawConfig{}
+	for _, inc := range includes {
+		if isRemote(inc) {
+			remote, err := loadRemoteConfig(inc)
+			if err != nil {
+				return nil, err
+			}
+			rc.merge(remote)
+			continue
+		}
+		if !path.IsAbs(inc) {
+			inc = path.Join(path.Dir(configPath), inc)
+		}
+		included, err := loadRawConfig(inc, seen)
+		if err != nil {
+			return nil, err
+		}
+		rc.merge(included)
+	}
+	rc.merge(own)
+	return rc, nil
+}
+
+// decodeRawConfig splits a config file into its sections and the list of
+// files it includes.
+func decodeRawConfig(bytes []byte) (rawConfig, []string, error) {
+	raw := map[string]json.RawMessage{}
+	err := json.Unmarshal(bytes, &raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	includes := []string{}
+	if inc, ok := raw[includeKey]; ok {
+		err = json.Unmarshal(inc, &includes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", includeKey, err)
+		}
+		delete(raw, includeKey)
+	}
+
+	rc := rawConfig{}
+	for section, v := range raw {
+		fields := map[string]json.RawMessage{}
+		err = json.Unmarshal(v, &fields)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", section, err)
+		}
+		rc[section] = fields
+	}
+	return rc, includes, nil
+}
+
+// parseConfig unmarshals the accounts in a single config file, ignoring
+// any includes.
+func parseConfig(bytes []byte) (Config, error) {
+	rc, _, err := decodeRawConfig(bytes)
+	if err != nil {
+		return make(Config), err
+	}
+	return buildConfig(rc)
+}
+
+// buildConfig decodes each account in rc. If a "defaults" section is
+// present, each account starts from it and its own fields override the
+// defaults. With several accounts and DeriveAccountNames set, names neither
+// sets are derived from the account's, see deriveNames.
+func buildConfig(rc rawConfig) (Config, error) {
+	accounts := len(rc)
+	if _, ok := rc[defaultsKey]; ok {
+		accounts--
+	}
+	c := make(Config)
+	for name, fields := range rc {
+		if name == defaultsKey {
+			continue
+		}
+		merged := map[string]json.RawMessage{}
+		for k, v := range rc[defaultsKey] {
+			merged[k] = v
+		}
+		for k, v := range fields {
+			merged[k] = v
+		}
+
+		bytes, err := json.Marshal(merged)
+		if err != nil {
+			return make(Config), fmt.Errorf("%s: %v", name, err)
+		}
+		var gc GithubConfig
+		err = json.Unmarshal(bytes, &gc)
+		if err != nil {
+			return make(Config), fmt.Errorf("%s: %v", name, err)
+		}
+		if accounts > 1 && gc.DeriveAccountNames {
+			deriveNames(name, &gc, merged)
+		}
+		err = expandEnv(&gc)
+		if err != nil {
+			return make(Config), fmt.Errorf("%s: %v", name, err)
+		}
+		err = gc.validate()
+		if err != nil {
+			return make(Config), fmt.Errorf("%s: %v", name, err)
+		}
+		c[name] = gc
+	}
+	return c, nil
+}
+
+// deriveNames sets the app tag and main projects of the account called
+// name, unless set is one of the fields, to names such as github-work and
+// "GitHub Work: Reviews". This stops a second account sharing the first's
+// tasks, and completing them, by accident.
+func deriveNames(name string, gc *GithubConfig, set map[string]json.RawMessage) {
+	title := "GitHub"
+	if name != "" {
+		first, size := utf8.DecodeRuneInString(name)
+		title += " " + string(unicode.ToUpper(first)) + name[size:]
+	}
+	for field, v := range map[string]struct {
+		p       *string
+		derived string
+	}{
+		"AppTag":               {&gc.AppTag, "github-" + name},
+		"AssignedProject":      {&gc.AssignedProject, title + ": Assigned"},
+		"ReviewProject":        {&gc.ReviewProject, title + ": Reviews"},
+		"NotificationsProject": {&gc.NotificationsProject, title + ": Notifications"},
+	} {
+		if _, ok := set[field]; !ok {
+			*v.p = v.derived
+		}
+	}
+}
+
+var envVarRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${NAME} references in gc's string fields with the
+// value of the NAME environment variable. It's an error to reference a
+// variable that isn't set, as an empty token or project name would only
+// cause confusing failures later.
+func expandEnv(gc *GithubConfig) error {
+	expand := func(s string) (string, error) {
+		var err error
+		out := envVarRef.ReplaceAllStringFunc(s, func(ref string) string {
+			name := envVarRef.FindStringSubmatch(ref)[1]
+			v, ok := os.LookupEnv(name)
+			if !ok && err == nil {
+				err = fmt.Errorf("environment variable %s is not set", name)
+			}
+			return v
+		})
+		return out, err
+	}
+
+	v := reflect.ValueOf(gc).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch {
+		case f.Kind() == reflect.String:
+			s, err := expand(f.String())
+			if err != nil {
+				return fmt.Errorf("%s: %v", v.Type().Field(i).Name, err)
+			}
+			f.SetString(s)
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.String:
+			for j := 0; j < f.Len(); j++ {
+				s, err := expand(f.Index(j).String())
+				if err != nil {
+					return fmt.Errorf("%s: %v", v.Type().Field(i).Name, err)
+				}
+				f.Index(j).SetString(s)
+			}
+		}
+	}
+	return nil
+}
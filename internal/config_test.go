@@ -0,0 +1,460 @@
+package internal
+
+import (
+	"maps"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestParseConfigDefaults(t *testing.T) {
+	c, err := parseConfig([]byte(`{
+		"defaults": {"AppTag": "github", "ReviewProject": "Reviews", "SetNotificationsDueDate": true},
+		"work": {"AccessToken": "a", "ReviewProject": "Work Reviews", "SetNotificationsDueDate": false},
+		"home": {"AccessToken": "b"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 2 {
+		t.Fatalf("Expected 2 accounts, got: %d", len(c))
+	}
+	if c["work"].AppTag != "github" || c["home"].AppTag != "github" {
+		t.Fatalf("Expected AppTag from defaults, got: %v", c)
+	}
+	if c["work"].ReviewProject != "Work Reviews" {
+		t.Fatalf("Expected account to override ReviewProject, got: %s", c["work"].ReviewProject)
+	}
+	if c["home"].ReviewProject != "Reviews" {
+		t.Fatalf("Expected default ReviewProject, got: %s", c["home"].ReviewProject)
+	}
+	if c["work"].SetNotificationsDueDate || !c["home"].SetNotificationsDueDate {
+		t.Fatalf("Expected account to override SetNotificationsDueDate, got: %v", c)
+	}
+}
+
+func TestParseConfigNoDefaults(t *testing.T) {
+	c, err := parseConfig([]byte(`{"work": {"AccessToken": "a"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["work"].AccessToken != "a" {
+		t.Fatalf("Didn't get expected token, got: %s", c["work"].AccessToken)
+	}
+}
+
+func TestParseConfigDerivedNames(t *testing.T) {
+	c, err := parseConfig([]byte(`{
+		"defaults": {"NotificationsProject": "Inbox", "DeriveAccountNames": true},
+		"work": {"AccessToken": "a", "ReviewProject": "Code Reviews"},
+		"home": {"AccessToken": "b"},
+		"élan": {"AccessToken": "c"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, home := c["work"], c["home"]
+	if work.AppTag != "github-work" || home.AppTag != "github-home" {
+		t.Fatalf("Expected AppTag derived from the account, got: %s, %s", work.AppTag, home.AppTag)
+	}
+	if home.AssignedProject != "GitHub Home: Assigned" || home.ReviewProject != "GitHub Home: Reviews" {
+		t.Fatalf("Expected projects derived from the account, got: %v", home)
+	}
+	if work.ReviewProject != "Code Reviews" || work.NotificationsProject != "Inbox" || home.NotificationsProject != "Inbox" {
+		t.Fatalf("Expected set names to be kept, got: %v", c)
+	}
+	if c["élan"].ReviewProject != "GitHub Élan: Reviews" {
+		t.Fatalf("Expected a non-ASCII account's name to be capitalised, got: %s", c["élan"].ReviewProject)
+	}
+
+	// Without DeriveAccountNames, accounts keep sharing the defaults, as
+	// they did before names could be derived.
+	c, err = parseConfig([]byte(`{
+		"defaults": {"AppTag": "github"},
+		"work": {"AccessToken": "a"},
+		"home": {"AccessToken": "b"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["work"].AppTag != "github" || c["home"].AppTag != "github" || c["home"].ReviewProject != "" {
+		t.Fatalf("Expected no derived names without DeriveAccountNames, got: %v", c)
+	}
+
+	c, err = parseConfig([]byte(`{"work": {"AccessToken": "a"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["work"].AppTag != "" {
+		t.Fatalf("Expected no derived names for a single account, got: %s", c["work"].AppTag)
+	}
+}
+
+func TestLoadRawConfigIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, path.Join(dir, "team.json"), `{
+		"defaults": {"AppTag": "github", "ReviewProject": "Team Reviews"},
+		"work": {"APIURL": "https://github.example.com/api/v3", "AccessToken": "unset"}
+	}`)
+	writeFile(t, path.Join(dir, "secrets.json"), `{"work": {"AccessToken": "secret"}}`)
+	writeFile(t, path.Join(dir, "config.json"), `{
+		"include": ["team.json", "secrets.json"],
+		"defaults": {"ReviewProject": "My Reviews"}
+	}`)
+
+	rc, err := loadRawConfig(path.Join(dir, "config.json"), map[string]bool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := buildConfig(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := c["work"]
+	if work.AccessToken != "secret" {
+		t.Fatalf("Expected token from later include, got: %s", work.AccessToken)
+	}
+	if work.APIURL != "https://github.example.com/api/v3" || work.AppTag != "github" {
+		t.Fatalf("Expected values from team include, got: %v", work)
+	}
+	if work.ReviewProject != "My Reviews" {
+		t.Fatalf("Expected including file to override, got: %s", work.ReviewProject)
+	}
+}
+
+func TestLoadRawConfigIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, path.Join(dir, "a.json"), `{"include": ["b.json"]}`)
+	writeFile(t, path.Join(dir, "b.json"), `{"include": ["a.json"]}`)
+
+	_, err := loadRawConfig(path.Join(dir, "a.json"), map[string]bool{})
+	if err == nil {
+		t.Fatal("Expected error for include cycle")
+	}
+}
+
+func writeFile(t *testing.T, p, content string) {
+	err := os.WriteFile(p, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseConfigExpandsEnv(t *testing.T) {
+	t.Setenv("G2O_TEST_TOKEN", "secret")
+	c, err := parseConfig([]byte(`{"work": {"AccessToken": "${G2O_TEST_TOKEN}", "AppTag": "$notavar"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["work"].AccessToken != "secret" {
+		t.Fatalf("Expected expanded token, got: %s", c["work"].AccessToken)
+	}
+	if c["work"].AppTag != "$notavar" {
+		t.Fatalf("Expected bare $ to be left alone, got: %s", c["work"].AppTag)
+	}
+}
+
+func TestParseConfigUnsetEnv(t *testing.T) {
+	_, err := parseConfig([]byte(`{"work": {"AccessToken": "${G2O_TEST_UNSET_VAR}"}}`))
+	if err == nil {
+		t.Fatal("Expected error for unset environment variable")
+	}
+}
+
+func TestGhCliHost(t *testing.T) {
+	cases := map[string]string{
+		"":                                    "github.com",
+		"https://api.github.com":              "github.com",
+		"https://api.github.com/":             "github.com",
+		"https://github.mycompany.com/api/v3": "github.mycompany.com",
+	}
+	for apiURL, expected := range cases {
+		host, err := ghCliHost(apiURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if host != expected {
+			t.Fatalf("%s: expected %s, got: %s", apiURL, expected, host)
+		}
+	}
+}
+
+func TestConfigPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "")
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		explicit string
+		env      string
+		expected string
+	}{
+		{"", "", path.Join(home, ".config", "github2omnifocus", "config.json")},
+		{"", "work.json", path.Join(home, ".config", "github2omnifocus", "work.json")},
+		{"", "/dotfiles/g2o.json", "/dotfiles/g2o.json"},
+		{"other.json", "/dotfiles/g2o.json", "other.json"},
+	}
+	for _, c := range cases {
+		t.Setenv("G2O_CONFIG", c.env)
+		p, err := ConfigPath(c.explicit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != c.expected {
+			t.Fatalf("Expected %s, got: %s", c.expected, p)
+		}
+	}
+}
+
+func TestConfigPathXDG(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("G2O_CONFIG", "")
+
+	// Falls back to the preferred location when no file exists.
+	p, err := ConfigPath("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := path.Join(xdg, "github2omnifocus", "config.json")
+	if p != expected {
+		t.Fatalf("Expected %s, got: %s", expected, p)
+	}
+}
+
+func TestCacheDirXDG(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/tmp/cache")
+	dir, err := CacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != "/tmp/cache/github2omnifocus" {
+		t.Fatalf("Didn't get expected cache dir, got: %s", dir)
+	}
+}
+
+func TestKeyOnly(t *testing.T) {
+	gc := GithubConfig{KeyOnlyCategories: []string{"Notifications"}}
+	if !gc.KeyOnly("notifications") {
+		t.Fatal("Expected notifications to be key only")
+	}
+	if gc.KeyOnly("issues") {
+		t.Fatal("Expected issues not to be key only")
+	}
+	gc.KeyOnlyComparison = true
+	if !gc.KeyOnly("issues") {
+		t.Fatal("Expected every category to be key only")
+	}
+}
+
+func TestReviewDueIn(t *testing.T) {
+	gc := GithubConfig{
+		ReviewDueInHours: 24,
+		ReviewDueInHoursByRepo: map[string]int{
+			"acme/*":        8,
+			"acme/payments": 4,
+		},
+	}
+	cases := map[string]time.Duration{
+		"acme/payments": 4 * time.Hour,
+		"acme/web":      8 * time.Hour,
+		"other/repo":    24 * time.Hour,
+	}
+	for repo, expected := range cases {
+		if d := gc.ReviewDueIn(repo); d != expected {
+			t.Fatalf("Expected %s for %s, got: %s", expected, repo, d)
+		}
+	}
+	_, err := parseConfig([]byte(`{"work": {"ReviewDueInHoursByRepo": {"acme/[": 4}}}`))
+	if err == nil {
+		t.Fatal("Expected error for bad repo pattern")
+	}
+}
+
+func TestParseConfigDueDatePattern(t *testing.T) {
+	for _, pattern := range []string{`due:\\s*(`, `due:\\s*\\d{4}-\\d{2}-\\d{2}`} {
+		_, err := parseConfig([]byte(`{"work": {"DueDatePattern": "` + pattern + `"}}`))
+		if err == nil {
+			t.Fatalf("Expected error for DueDatePattern %s", pattern)
+		}
+	}
+	_, err := parseConfig([]byte(`{"work": {"DueDatePattern": "due:\\s*(\\d{4}-\\d{2}-\\d{2})"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNextSync(t *testing.T) {
+	last := time.Date(2024, 5, 1, 22, 5, 0, 0, time.UTC)
+	gc := GithubConfig{}
+	if next := gc.NextSync(last, 5*time.Minute); !next.Equal(last.Add(5 * time.Minute)) {
+		t.Fatalf("Expected interval after last sync, got: %s", next)
+	}
+	gc.Schedule = []string{"*/10 9-17 * * 1-5", "0 * * * *"}
+	if next := gc.NextSync(last, 5*time.Minute); !next.Equal(time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected hourly sync out of hours, got: %s", next)
+	}
+	last = time.Date(2024, 5, 1, 10, 5, 0, 0, time.UTC)
+	if next := gc.NextSync(last, 5*time.Minute); !next.Equal(time.Date(2024, 5, 1, 10, 10, 0, 0, time.UTC)) {
+		t.Fatalf("Expected sync every ten minutes in hours, got: %s", next)
+	}
+	_, err := parseConfig([]byte(`{"work": {"Schedule": ["*/10 9-17 * *"]}}`))
+	if err == nil {
+		t.Fatal("Expected error for bad Schedule")
+	}
+}
+
+func TestParseConfigUncompletedAction(t *testing.T) {
+	_, err := parseConfig([]byte(`{"work": {"UncompletedAction": "delete"}}`))
+	if err == nil {
+		t.Fatal("Expected error for unknown UncompletedAction")
+	}
+	c, err := parseConfig([]byte(`{"work": {"UncompletedAction": "comment"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["work"].Comment() != defaultUncompletedComment {
+		t.Fatalf("Expected default comment, got: %s", c["work"].Comment())
+	}
+}
+
+func TestParseConfigUnmergedPRAction(t *testing.T) {
+	_, err := parseConfig([]byte(`{"work": {"UnmergedPRAction": {"authored": "keep", "reviews": "drop"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, conf := range []string{`{"work": {"UnmergedPRAction": {"issues": "drop"}}}`, `{"work": {"UnmergedPRAction": {"authored": "delete"}}}`} {
+		if _, err := parseConfig([]byte(conf)); err == nil {
+			t.Errorf("Expected error for %s", conf)
+		}
+	}
+}
+
+func TestParseConfigWaitingOn(t *testing.T) {
+	_, err := parseConfig([]byte(`{"work": {"WaitingOnReviewersDeferHours": 24}}`))
+	if err == nil {
+		t.Fatal("Expected error for WaitingOnReviewersDeferHours without TrackWaitingOn")
+	}
+	_, err = parseConfig([]byte(`{"work": {"TrackWaitingOn": true, "WaitingOnReviewersDeferHours": 24}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseConfigEmail(t *testing.T) {
+	for _, conf := range []string{
+		`{"work": {"EmailTo": ["me@example.com"], "EmailFrom": "g2o@example.com", "SMTPServer": "smtp.example.com"}}`,
+		`{"work": {"EmailTo": ["me@example.com"], "SMTPServer": "smtp.example.com:587"}}`,
+		`{"work": {"EmailOn": "never"}}`,
+	} {
+		_, err := parseConfig([]byte(conf))
+		if err == nil {
+			t.Errorf("Expected error for %s", conf)
+		}
+	}
+	_, err := parseConfig([]byte(`{"work": {"EmailTo": ["me@example.com"], "EmailFrom": "g2o@example.com", "SMTPServer": "smtp.example.com:587"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseConfigTaskMasterMonths(t *testing.T) {
+	_, err := parseConfig([]byte(`{"work": {"TaskMasterMonths": ["Jan", "Feb"]}}`))
+	if err == nil {
+		t.Error("Expected error for too few months")
+	}
+	_, err = parseConfig([]byte(`{"work": {"TaskMasterMonths": ["jan", "feb", "mar", "apr", "mai", "jun", "jul", "aug", "sep", "okt", "nov", "dez"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseConfigSort(t *testing.T) {
+	c, err := parseConfig([]byte(`{"work": {"MaxItemsPerCategory": 50}, "home": {"SearchSort": "created", "SearchOrder": "asc"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sort, order := c["work"].Sort(); sort != "updated" || order != "" {
+		t.Errorf("Expected most recently updated first when capped, got: %s %s", sort, order)
+	}
+	if sort, order := c["home"].Sort(); sort != "created" || order != "asc" {
+		t.Errorf("Expected SearchSort and SearchOrder, got: %s %s", sort, order)
+	}
+	for _, conf := range []string{`{"work": {"SearchSort": "stars"}}`, `{"work": {"SearchOrder": "up"}}`} {
+		if _, err := parseConfig([]byte(conf)); err == nil {
+			t.Errorf("Expected error for %s", conf)
+		}
+	}
+}
+
+func TestParseConfigSearchQueries(t *testing.T) {
+	c, err := parseConfig([]byte(`{"work": {"SearchQueries": {"reviews": "type:pr state:open review-requested:{{.User}} -label:on-hold"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["work"].SearchQueries["reviews"] != "type:pr state:open review-requested:{{.User}} -label:on-hold" {
+		t.Errorf("Expected the reviews query, got: %v", c["work"].SearchQueries)
+	}
+	for _, conf := range []string{`{"work": {"SearchQueries": {"notifications": "is:unread"}}}`, `{"work": {"SearchQueries": {"reviews": "review-requested:{{.User"}}}`} {
+		if _, err := parseConfig([]byte(conf)); err == nil {
+			t.Errorf("Expected error for %s", conf)
+		}
+	}
+}
+
+func TestParseConfigProjectTypes(t *testing.T) {
+	_, err := parseConfig([]byte(`{"work": {"ProjectTypes": {"GitHub Notifications": "single action"}}}`))
+	if err == nil {
+		t.Fatal("Expected error for unknown project type")
+	}
+	c, err := parseConfig([]byte(`{"work": {"TrackingProjectType": "sequential", "ProjectTypes": {"GitHub Notifications": "single actions"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["work"].TrackingProjectType != ProjectTypeSequential || c["work"].ProjectTypes["GitHub Notifications"] != ProjectTypeSingleActions {
+		t.Errorf("Unexpected project types: %q, %v", c["work"].TrackingProjectType, c["work"].ProjectTypes)
+	}
+}
+
+func TestProjectTypesWithDefaults(t *testing.T) {
+	cases := []struct {
+		gc       GithubConfig
+		expected map[string]string
+	}{
+		{GithubConfig{NotificationsProject: "GitHub Notifications"}, map[string]string{"GitHub Notifications": ProjectTypeSingleActions}},
+		{GithubConfig{NotificationsProject: "GitHub Notifications", ProjectTypes: map[string]string{"GitHub Notifications": ""}}, map[string]string{"GitHub Notifications": ""}},
+		{GithubConfig{NotificationsProject: "GitHub Notifications", NotificationsByRepo: true}, map[string]string{}},
+	}
+	for _, c := range cases {
+		if types := c.gc.ProjectTypesWithDefaults(); !maps.Equal(types, c.expected) {
+			t.Errorf("Expected %v, got: %v", c.expected, types)
+		}
+	}
+}
+
+func TestParseConfigStaleNotifications(t *testing.T) {
+	_, err := parseConfig([]byte(`{"work": {"MarkStaleNotificationsRead": true}}`))
+	if err == nil {
+		t.Fatal("Expected error for MarkStaleNotificationsRead without StaleNotificationDays")
+	}
+	_, err = parseConfig([]byte(`{"work": {"StaleNotificationDays": 14, "MarkStaleNotificationsRead": true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseConfigTagCase(t *testing.T) {
+	_, err := parseConfig([]byte(`{"work": {"TagCase": "upper"}}`))
+	if err == nil {
+		t.Fatal("Expected error for unknown TagCase")
+	}
+	c, err := parseConfig([]byte(`{"work": {"TagCase": "title"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["work"].TagCase != TagCaseTitle {
+		t.Errorf("Expected title TagCase, got: %q", c["work"].TagCase)
+	}
+}
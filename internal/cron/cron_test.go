@@ -0,0 +1,45 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// A Wednesday.
+	after := time.Date(2024, 5, 1, 9, 7, 30, 0, time.UTC)
+	cases := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, 5, 1, 9, 8, 0, 0, time.UTC)},
+		{"*/10 * * * *", time.Date(2024, 5, 1, 9, 10, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{"*/10 9-17 * * 1-5", time.Date(2024, 5, 1, 9, 10, 0, 0, time.UTC)},
+		{"0 9 * * 6,7", time.Date(2024, 5, 4, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 0", time.Date(2024, 5, 5, 9, 0, 0, 0, time.UTC)},
+		{"30 8 1 * *", time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC)},
+		// Either day field matches when both are set.
+		{"0 0 15 * 5", time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next := s.Next(after); !next.Equal(c.expected) {
+			t.Fatalf("Expected %s for %q, got: %s", c.expected, c.expr, next)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := Parse(expr)
+		if err == nil {
+			t.Fatalf("Expected error for %q", expr)
+		}
+	}
+}
@@ -1,8 +1,8 @@
 // Package delta provides functions to create "deltas" between two sets, which
-// consist of add and remove operations to make a second set contain the same
-// items as the first set.
+// consist of add, remove and modify changes to make a second set contain the
+// same items as the first set.
 //
-// Within github2omnifocus, this is used to create the operations that bring the
+// Within github2omnifocus, this is used to create the changes that bring the
 // task list state in the local tool, Omnifocus, into line with the desired
 // state from GitHub.
 package delta
@@ -14,7 +14,7 @@ import (
 	"strings"
 )
 
-// OperationType states whether a DeltaOperation is add or remove.
+// OperationType states whether an applied change added or removed an item.
 type OperationType int
 
 const (
@@ -37,54 +37,119 @@ type Keyed interface {
 	GetTags() iter.Seq[string]
 }
 
-// A Operation states that Item should be added or removed from a set.
-type Operation struct {
-	Item Keyed
-	Type OperationType
+// Titled is optionally implemented by Keyed items that have a title, in
+// which case Delta also compares titles.
+type Titled interface {
+	GetTitle() string
 }
 
-// Delta returns a slice of DeltaOperations that, when applied to current,
-// will result in current containing the same items as desired.
-func Delta[D Keyed, C Keyed](desired map[string]D, current map[string]C, ignoreTags []string) []Operation {
-	ops := []Operation{}
+// Reason states why an item is in Changes.
+type Reason int
+
+const (
+	// Missing items are in desired but not current.
+	Missing Reason = iota + 1
+	// Extra items are in current but not desired.
+	Extra
+	// TagsChanged items are in both, but with different tags.
+	TagsChanged
+	// TitleChanged items are in both, but with different titles.
+	TitleChanged
+)
+
+func (r Reason) String() string {
+	reasons := [...]string{"missing", "extra", "tags-changed", "title-changed"}
+	if r < Missing || r > TitleChanged {
+		return fmt.Sprintf("Reason(%d)", int(r))
+	}
+	return reasons[r-1]
+}
+
+// Diff holds what differed between the desired and current item for
+// TagsChanged and TitleChanged modifications. Tags are lowercased and exclude
+// ignored tags, as compared.
+type Diff struct {
+	// MissingTags are desired but not on the current item.
+	MissingTags []string
+	// ExtraTags are on the current item but not desired.
+	ExtraTags []string
+	OldTitle  string
+	NewTitle  string
+}
+
+// Changes are what's needed to make current contain the same items as
+// desired. Adds are Missing from current and Removes are Extra in current.
+// Unchanged holds the items that already match; they aren't changes, but
+// are there for callers that refresh them anyway.
+type Changes[D Keyed, C Keyed] struct {
+	Adds      []D
+	Removes   []C
+	Modifies  []Modification[D, C]
+	Unchanged []Pair[D, C]
+}
+
+// A Pair is an item that's in both desired and current.
+type Pair[D Keyed, C Keyed] struct {
+	Desired D
+	Current C
+}
+
+// Len is the total number of changes.
+func (c Changes[D, C]) Len() int {
+	return len(c.Adds) + len(c.Removes) + len(c.Modifies)
+}
+
+// A Modification is an item that's in both desired and current, but differs
+// for Reason.
+type Modification[D Keyed, C Keyed] struct {
+	Pair[D, C]
+	Reason Reason
+	Diff   Diff
+}
+
+// Explain describes why the modification is needed, for logging.
+func (m Modification[D, C]) Explain() string {
+	if m.Reason == TitleChanged {
+		return fmt.Sprintf("%s: %q -> %q", m.Reason, m.Diff.OldTitle, m.Diff.NewTitle)
+	}
+	return fmt.Sprintf("%s: missing from task: %q; not expected on task: %q", m.Reason, m.Diff.MissingTags, m.Diff.ExtraTags)
+}
+
+// Delta returns the Changes that, when applied to current, will result in
+// current containing the same items as desired.
+func Delta[D Keyed, C Keyed](desired map[string]D, current map[string]C, ignoreTags []string) Changes[D, C] {
+	changes := Changes[D, C]{}
 	ignoreTags = toLower(ignoreTags)
 
 	// If it's in desired, and not in current: add it.
 	for k, v := range desired {
 		if c, ok := current[k]; !ok {
-			ops = append(ops, Operation{
-				Type: Add,
-				Item: v,
-			})
+			changes.Adds = append(changes.Adds, v)
 		} else {
-			// confirm the tags are the same if not, remove and re-add
-			// bit of a sledge hammer to crack a nut, but
-			// it works, improvement would be to manipulate the tags
-			// ignoring "special case" tags provided in the config.
-			// these special case include GHE assigned etc
-			// these aren't actually available
-			// on the task or the github issue, but from config.
-			// further improvement would be to add a new operation type to modify existing
-			// tasks
-
-			cTags := slices.Sorted(deleteFunc(lower(c.GetTags()),func(s string) bool {
+			// confirm the tags are the same if not, modify it.
+			// Special case tags provided in the config, such as GHE
+			// assigned etc, are ignored as these aren't actually
+			// available on the github issue, but from config.
+
+			cTags := slices.Sorted(deleteFunc(lower(c.GetTags()), func(s string) bool {
 				return slices.Contains(ignoreTags, s)
 			}))
 
 			vTags := slices.Sorted(lower(v.GetTags()))
 			// casing can break this, so we should set all cases to lower for the
 			// comparsion
+			m := Modification[D, C]{Pair: Pair[D, C]{Desired: v, Current: c}}
 			if slices.Compare(vTags, cTags) != 0 {
-				// introduce a new op, "modify"
-				// so we can update things inline, and not lose
-				// note content etc etc
-				ops = append(ops, Operation{
-					Type: Remove,
-					Item: c,
-				}, Operation{
-					Type: Add,
-					Item: v,
-				})
+				m.Reason = TagsChanged
+				m.Diff.MissingTags, m.Diff.ExtraTags = tagDiff(vTags, cTags)
+			} else if vt, ct, ok := titles(v, c); ok && vt != ct {
+				m.Reason = TitleChanged
+				m.Diff.OldTitle, m.Diff.NewTitle = ct, vt
+			}
+			if m.Reason != 0 {
+				changes.Modifies = append(changes.Modifies, m)
+			} else {
+				changes.Unchanged = append(changes.Unchanged, m.Pair)
 			}
 		}
 	}
@@ -92,17 +157,63 @@ func Delta[D Keyed, C Keyed](desired map[string]D, current map[string]C, ignoreT
 	// If it's in current, and not in desired: remove it.
 	for k, v := range current {
 		if _, ok := desired[k]; !ok {
-			ops = append(ops, Operation{
-				Type: Remove,
-				Item: v,
-			})
+			changes.Removes = append(changes.Removes, v)
 		}
 	}
 
-	return ops
+	return changes
+}
+
+// KeysOnly is like Delta, but only compares keys, so items in both desired
+// and current are never modified.
+func KeysOnly[D Keyed, C Keyed](desired map[string]D, current map[string]C) Changes[D, C] {
+	changes := Changes[D, C]{}
+	for k, v := range desired {
+		if c, ok := current[k]; !ok {
+			changes.Adds = append(changes.Adds, v)
+		} else {
+			changes.Unchanged = append(changes.Unchanged, Pair[D, C]{Desired: v, Current: c})
+		}
+	}
+	for k, v := range current {
+		if _, ok := desired[k]; !ok {
+			changes.Removes = append(changes.Removes, v)
+		}
+	}
+	return changes
+}
+
+// titles returns the titles of desired and current, if both have them.
+func titles(desired, current Keyed) (string, string, bool) {
+	dt, ok := desired.(Titled)
+	if !ok {
+		return "", "", false
+	}
+	ct, ok := current.(Titled)
+	if !ok {
+		return "", "", false
+	}
+	return dt.GetTitle(), ct.GetTitle(), true
+}
+
+// tagDiff returns the tags in desired but not current, and those in current
+// but not desired. Both must be sorted.
+func tagDiff(desired, current []string) (missing, extra []string) {
+	missing, extra = []string{}, []string{}
+	for _, t := range desired {
+		if _, found := slices.BinarySearch(current, t); !found {
+			missing = append(missing, t)
+		}
+	}
+	for _, t := range current {
+		if _, found := slices.BinarySearch(desired, t); !found {
+			extra = append(extra, t)
+		}
+	}
+	return missing, extra
 }
 
-func deleteFunc(itr iter.Seq[string], del func(string) bool) iter.Seq[string]{
+func deleteFunc(itr iter.Seq[string], del func(string) bool) iter.Seq[string] {
 	return func(yield func(string) bool) {
 		next, stop := iter.Pull(itr)
 		defer stop()
@@ -1,7 +1,9 @@
 package delta
 
 import (
-	"sort"
+	"iter"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -13,6 +15,10 @@ func (mk *MockKeyed) Key() string {
 	return mk.key
 }
 
+func (mk *MockKeyed) GetTags() iter.Seq[string] {
+	return slices.Values([]string{})
+}
+
 func TestDelta1NoChange(t *testing.T) {
 	current := map[string]Keyed{
 		"foo": &MockKeyed{key: "foo"},
@@ -22,9 +28,12 @@ func TestDelta1NoChange(t *testing.T) {
 		"foo": &MockKeyed{key: "foo"},
 		"bar": &MockKeyed{key: "bar"},
 	}
-	ops := Delta(desired, current)
-	if len(ops) != 0 {
-		t.Fatal("Did not receive empty operations slice")
+	changes := Delta(desired, current, nil)
+	if changes.Len() != 0 {
+		t.Fatal("Did not receive empty changes")
+	}
+	if len(changes.Unchanged) != 2 {
+		t.Fatal("Expected 2 unchanged items")
 	}
 }
 
@@ -36,14 +45,14 @@ func TestDelta2AddItem(t *testing.T) {
 		"foo": &MockKeyed{key: "foo"},
 		"bar": &MockKeyed{key: "bar"},
 	}
-	ops := Delta(desired, current)
-	if len(ops) != 1 {
+	changes := Delta(desired, current, nil)
+	if changes.Len() != 1 {
 		t.Fatal("Expected 1 add operation")
 	}
-	if ops[0].Type != Add {
+	if len(changes.Adds) != 1 {
 		t.Fatal("Expected 1 add operation")
 	}
-	if ops[0].Item.Key() != "foo" {
+	if changes.Adds[0].Key() != "foo" {
 		t.Fatal("Expected 1 add operation")
 	}
 }
@@ -56,14 +65,14 @@ func TestDelta3RemoveItem(t *testing.T) {
 	desired := map[string]Keyed{
 		"foo": &MockKeyed{key: "foo"},
 	}
-	ops := Delta(desired, current)
-	if len(ops) != 1 {
+	changes := Delta(desired, current, nil)
+	if changes.Len() != 1 {
 		t.Fatal("Expected 1 remove operation")
 	}
-	if ops[0].Type != Remove {
+	if len(changes.Removes) != 1 {
 		t.Fatal("Expected 1 remove operation")
 	}
-	if ops[0].Item.Key() != "bar" {
+	if changes.Removes[0].Key() != "bar" {
 		t.Fatal("Expected 1 remove operation")
 	}
 }
@@ -77,49 +86,138 @@ func TestDelta4AllChange(t *testing.T) {
 		"foo": &MockKeyed{key: "foo"},
 		"bar": &MockKeyed{key: "bar"},
 	}
-	ops := Delta(desired, current)
-	if len(ops) != 4 {
+	changes := Delta(desired, current, nil)
+	if changes.Len() != 4 {
 		t.Fatal("Expected 4 operations, 2 add, 2 remove")
 	}
 
-	sort.SliceStable(ops, func(i, j int) bool {
-		return ops[i].Item.Key() < ops[j].Item.Key()
-	})
+	byKey := func(a, b Keyed) int {
+		return strings.Compare(a.Key(), b.Key())
+	}
+	slices.SortFunc(changes.Adds, byKey)
+	slices.SortFunc(changes.Removes, byKey)
 
-	if ops[0].Type != Add {
+	if len(changes.Adds) != 2 {
 		t.Fatal("Expected 4 operations, 2 add, 2 remove")
 	}
-	if ops[0].Item.Key() != "bar" {
+	if changes.Adds[0].Key() != "bar" || changes.Adds[1].Key() != "foo" {
 		t.Fatal("Expected 4 operations, 2 add, 2 remove")
 	}
 
-	if ops[1].Type != Remove {
+	if len(changes.Removes) != 2 {
 		t.Fatal("Expected 4 operations, 2 add, 2 remove")
 	}
-	if ops[1].Item.Key() != "baz" {
+	if changes.Removes[0].Key() != "baz" || changes.Removes[1].Key() != "quux" {
 		t.Fatal("Expected 4 operations, 2 add, 2 remove")
 	}
+}
 
-	if ops[2].Type != Add {
-		t.Fatal("Expected 4 operations, 2 add, 2 remove")
+func TestDeltaNoItems(t *testing.T) {
+	current := map[string]Keyed{}
+	desired := map[string]Keyed{}
+	changes := Delta(desired, current, nil)
+	if changes.Len() != 0 {
+		t.Fatal("Did not receive empty changes")
 	}
-	if ops[2].Item.Key() != "foo" {
-		t.Fatal("Expected 4 operations, 2 add, 2 remove")
+}
+
+func TestTagDiff(t *testing.T) {
+	missing, extra := tagDiff([]string{"acme/tools", "bug", "p1"}, []string{"acme/tools", "p2"})
+	if !slices.Equal(missing, []string{"bug", "p1"}) {
+		t.Fatalf("Didn't get expected missing tags, got: %v", missing)
+	}
+	if !slices.Equal(extra, []string{"p2"}) {
+		t.Fatalf("Didn't get expected extra tags, got: %v", extra)
 	}
+}
 
-	if ops[3].Type != Remove {
-		t.Fatal("Expected 4 operations, 2 add, 2 remove")
+type MockTitled struct {
+	MockKeyed
+	title string
+	tags  []string
+}
+
+func (mt *MockTitled) GetTitle() string {
+	return mt.title
+}
+
+func (mt *MockTitled) GetTags() iter.Seq[string] {
+	return slices.Values(mt.tags)
+}
+
+func TestDeltaReasons(t *testing.T) {
+	current := map[string]Keyed{
+		"tags":  &MockTitled{MockKeyed: MockKeyed{key: "tags"}, title: "a", tags: []string{"github", "Bug"}},
+		"title": &MockTitled{MockKeyed: MockKeyed{key: "title"}, title: "old"},
+		"same":  &MockTitled{MockKeyed: MockKeyed{key: "same"}, title: "same"},
+		"extra": &MockKeyed{key: "extra"},
 	}
-	if ops[3].Item.Key() != "quux" {
-		t.Fatal("Expected 4 operations, 2 add, 2 remove")
+	desired := map[string]Keyed{
+		"tags":    &MockTitled{MockKeyed: MockKeyed{key: "tags"}, title: "a", tags: []string{"p1"}},
+		"title":   &MockTitled{MockKeyed: MockKeyed{key: "title"}, title: "new"},
+		"same":    &MockTitled{MockKeyed: MockKeyed{key: "same"}, title: "same"},
+		"missing": &MockKeyed{key: "missing"},
+	}
+	changes := Delta(desired, current, []string{"GitHub"})
+
+	if len(changes.Adds) != 1 || changes.Adds[0].Key() != "missing" {
+		t.Fatalf("Expected missing add, got: %v", changes.Adds)
+	}
+	if len(changes.Removes) != 1 || changes.Removes[0].Key() != "extra" {
+		t.Fatalf("Expected extra remove, got: %v", changes.Removes)
+	}
+	if len(changes.Modifies) != 2 {
+		t.Fatalf("Expected 2 modifications, got: %v", changes.Modifies)
+	}
+	if len(changes.Unchanged) != 1 || changes.Unchanged[0].Current.Key() != "same" {
+		t.Fatalf("Expected same to be unchanged, got: %v", changes.Unchanged)
+	}
+
+	for _, m := range changes.Modifies {
+		switch m.Current.Key() {
+		case "tags":
+			if m.Reason != TagsChanged {
+				t.Fatalf("Expected tags changed, got: %s", m.Reason)
+			}
+			if !slices.Equal(m.Diff.MissingTags, []string{"p1"}) || !slices.Equal(m.Diff.ExtraTags, []string{"bug"}) {
+				t.Fatalf("Didn't get expected tag diff, got: %+v", m.Diff)
+			}
+		case "title":
+			if m.Reason != TitleChanged {
+				t.Fatalf("Expected title changed, got: %s", m.Reason)
+			}
+			if m.Diff.OldTitle != "old" || m.Diff.NewTitle != "new" {
+				t.Fatalf("Didn't get expected title diff, got: %+v", m.Diff)
+			}
+		default:
+			t.Fatalf("Unexpected modification: %s", m.Current.Key())
+		}
+		if m.Desired.Key() != m.Current.Key() {
+			t.Fatalf("Modification mixes items: %s and %s", m.Desired.Key(), m.Current.Key())
+		}
 	}
 }
 
-func TestDeltaNoItems(t *testing.T) {
-	current := map[string]Keyed{}
-	desired := map[string]Keyed{}
-	ops := Delta(desired, current)
-	if len(ops) != 0 {
-		t.Fatal("Did not receive empty operations slice")
+func TestKeysOnly(t *testing.T) {
+	current := map[string]Keyed{
+		"tags":  &MockTitled{MockKeyed: MockKeyed{key: "tags"}, title: "a", tags: []string{"bug"}},
+		"extra": &MockKeyed{key: "extra"},
+	}
+	desired := map[string]Keyed{
+		"tags":    &MockTitled{MockKeyed: MockKeyed{key: "tags"}, title: "b", tags: []string{"p1"}},
+		"missing": &MockKeyed{key: "missing"},
+	}
+	changes := KeysOnly(desired, current)
+	if len(changes.Modifies) != 0 {
+		t.Fatalf("Expected no modifications, got: %v", changes.Modifies)
+	}
+	if len(changes.Unchanged) != 1 || changes.Unchanged[0].Desired.Key() != "tags" {
+		t.Fatalf("Expected tags to be unchanged, got: %v", changes.Unchanged)
+	}
+	if len(changes.Adds) != 1 || changes.Adds[0].Key() != "missing" {
+		t.Fatalf("Expected missing add, got: %v", changes.Adds)
+	}
+	if len(changes.Removes) != 1 || changes.Removes[0].Key() != "extra" {
+		t.Fatalf("Expected extra remove, got: %v", changes.Removes)
 	}
 }
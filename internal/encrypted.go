@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ageIdentityEnv names the environment variable holding the path of the age
+// identity file used to decrypt encrypted config files.
+const ageIdentityEnv = "G2O_AGE_IDENTITY"
+
+// ageHeader starts every binary age file.
+const ageHeader = "age-encryption.org/"
+
+// isEncrypted is true for config files encrypted with age, either binary
+// or armored.
+func isEncrypted(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(ageHeader)) || bytes.HasPrefix(bytes.TrimSpace(b), []byte(armor.Header))
+}
+
+// decryptConfig decrypts an age-encrypted config file in memory, using the
+// identities in the file named by G2O_AGE_IDENTITY. Other files are
+// returned as they are.
+func decryptConfig(configPath string, b []byte) ([]byte, error) {
+	if !isEncrypted(b) {
+		return b, nil
+	}
+	identityPath := os.Getenv(ageIdentityEnv)
+	if identityPath == "" {
+		return nil, fmt.Errorf("config file %s is encrypted, set %s to the path of your age identity file", configPath, ageIdentityEnv)
+	}
+	f, err := os.Open(identityPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening age identity file: %v", err)
+	}
+	defer f.Close()
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing age identity file %s: %v", identityPath, err)
+	}
+
+	var in io.Reader = bytes.NewReader(b)
+	if !bytes.HasPrefix(b, []byte(ageHeader)) {
+		in = armor.NewReader(bufio.NewReader(bytes.NewReader(bytes.TrimSpace(b))))
+	}
+	r, err := age.Decrypt(in, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting config file %s: %v", configPath, err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting config file %s: %v", configPath, err)
+	}
+	return plain, nil
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+func encryptConfig(t *testing.T, recipient age.Recipient, plain string, armored bool) string {
+	var buf bytes.Buffer
+	var out io.Writer = &buf
+	var a io.WriteCloser
+	if armored {
+		a = armor.NewWriter(&buf)
+		out = a
+	}
+	w, err := age.Encrypt(out, recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.WriteString(w, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if a != nil {
+		if err := a.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.String()
+}
+
+func TestLoadRawConfigEncrypted(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeFile(t, path.Join(dir, "key.txt"), "# created: today\n"+identity.String()+"\n")
+	t.Setenv(ageIdentityEnv, path.Join(dir, "key.txt"))
+
+	for _, armored := range []bool{false, true} {
+		writeFile(t, path.Join(dir, "config.json.age"), encryptConfig(t, identity.Recipient(), `{"work": {"AccessToken": "secret"}}`, armored))
+		rc, err := loadRawConfig(path.Join(dir, "config.json.age"), map[string]bool{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := buildConfig(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c["work"].AccessToken != "secret" {
+			t.Fatalf("Expected decrypted token (armored %v), got: %v", armored, c["work"])
+		}
+	}
+}
+
+func TestDecryptConfigErrors(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeFile(t, path.Join(dir, "key.txt"), other.String()+"\n")
+	encrypted := []byte(encryptConfig(t, identity.Recipient(), `{}`, false))
+
+	t.Setenv(ageIdentityEnv, "")
+	_, err = decryptConfig("config.json.age", encrypted)
+	if err == nil {
+		t.Fatal("Expected error without an identity file")
+	}
+
+	t.Setenv(ageIdentityEnv, path.Join(dir, "key.txt"))
+	_, err = decryptConfig("config.json.age", encrypted)
+	if err == nil {
+		t.Fatal("Expected error with the wrong identity")
+	}
+
+	plain, err := decryptConfig("config.json", []byte(`{}`))
+	if err != nil || string(plain) != `{}` {
+		t.Fatalf("Expected plain config unchanged, got: %s, %v", plain, err)
+	}
+}
@@ -0,0 +1,52 @@
+package gh
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrBudgetExhausted is returned for requests made once an account's
+// request budget for the sync is used up.
+var ErrBudgetExhausted = errors.New("GitHub request budget for this sync used up")
+
+// requestBudget counts down the requests left for a sync.
+type requestBudget struct {
+	max  int64
+	used atomic.Int64
+}
+
+// spend uses up a request, returning false if there were none left.
+func (b *requestBudget) spend() bool {
+	n := b.used.Add(1)
+	if n == b.max+1 {
+		log.Printf("Warning: used all %d GitHub requests allowed by MaxRequestsPerSync, the rest of the sync will use what's been fetched", b.max)
+	}
+	return n <= b.max
+}
+
+func (b *requestBudget) exhausted() bool {
+	return b != nil && b.used.Load() > b.max
+}
+
+// budgetTransport fails requests once the budget is used up, rather than
+// making them.
+type budgetTransport struct {
+	base   http.RoundTripper
+	budget *requestBudget
+}
+
+func (t budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.budget.spend() {
+		return nil, ErrBudgetExhausted
+	}
+	return t.base.RoundTrip(req)
+}
+
+// BudgetExhausted is true once the gateway has been stopped from making a
+// request by TransportOptions.MaxRequests. Results fetched since may be
+// incomplete.
+func (ghg GitHubGateway) BudgetExhausted() bool {
+	return ghg.budget.exhausted()
+}
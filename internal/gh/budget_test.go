@@ -0,0 +1,32 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMaxRequests(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// Every page links to another, so only the budget stops paging.
+		w.Header().Set("Link", fmt.Sprintf(`<%s/api/v3/issues?page=%d>; rel="next"`, "http://"+r.Host, requests+1))
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{MaxRequests: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ghg.GetIssues()
+	if err == nil {
+		t.Fatal("Expected error once the budget was used up")
+	}
+	if requests != 3 || !ghg.BudgetExhausted() {
+		t.Fatalf("Expected 3 requests and the budget to be used up, got: %d %v", requests, ghg.BudgetExhausted())
+	}
+}
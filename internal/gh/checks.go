@@ -0,0 +1,78 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// failedConclusions are the workflow run conclusions we treat as needing
+// attention.
+var failedConclusions = map[string]bool{
+	"failure":         true,
+	"timed_out":       true,
+	"startup_failure": true,
+}
+
+// GetFailingWorkflows returns an item for each GitHub Actions workflow whose
+// latest run against the head commit of one of prs failed. Once a workflow
+// goes green again, or the PR is closed, its item is no longer returned.
+// A PR whose runs can't be fetched, such as in a repo whose Actions can't
+// be read, is logged and returned in skipped rather than failing the rest.
+func (ghg *GitHubGateway) GetFailingWorkflows(prs []GitHubItem) (items []GitHubItem, skipped []string, err error) {
+	items = []GitHubItem{}
+	for _, pr := range prs {
+		runs, sha, err := ghg.workflowRuns(pr)
+		if errors.Is(err, ErrBudgetExhausted) {
+			return nil, nil, err
+		}
+		if err != nil {
+			log.Printf("Skipping failing checks for %s: %v", pr.Key(), err)
+			skipped = append(skipped, pr.Key())
+			continue
+		}
+
+		// Runs are returned newest first, so the first run we see for each
+		// workflow is its latest.
+		seen := map[int64]bool{}
+		for _, run := range runs {
+			if run.GetHeadSHA() != sha || seen[run.GetWorkflowID()] {
+				continue
+			}
+			seen[run.GetWorkflowID()] = true
+			if !failedConclusions[run.GetConclusion()] {
+				continue
+			}
+			items = append(items, GitHubItem{
+				Title:   fmt.Sprintf("%s failed: %s", run.GetName(), pr.Title),
+				HTMLURL: run.GetHTMLURL(),
+				APIURL:  run.GetURL(),
+				K:       fmt.Sprintf("%s/ci/%d", pr.Key(), run.GetWorkflowID()),
+				Repo:    pr.Repo,
+				Number:  pr.Number,
+			})
+		}
+	}
+	return items, skipped, nil
+}
+
+// workflowRuns returns the latest workflow runs for pr's branch, newest
+// first, and the SHA of the PR's head commit.
+func (ghg *GitHubGateway) workflowRuns(pr GitHubItem) ([]*github.WorkflowRun, string, error) {
+	owner, repo := pr.ownerRepo()
+	log.Printf("Getting workflow runs for %s", pr.Key())
+	p, _, err := ghg.c.PullRequests.Get(ghg.ctx, owner, repo, pr.Number)
+	if err != nil {
+		return nil, "", fmt.Errorf("error retrieving PR %s: %w", pr.Key(), err)
+	}
+	runs, _, err := ghg.c.Actions.ListRepositoryWorkflowRuns(ghg.ctx, owner, repo, &github.ListWorkflowRunsOptions{
+		Branch:      p.GetHead().GetRef(),
+		ListOptions: github.ListOptions{PerPage: paginationPerPage},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("error retrieving workflow runs for %s: %w", pr.Key(), err)
+	}
+	return runs.WorkflowRuns, p.GetHead().GetSHA(), nil
+}
@@ -0,0 +1,58 @@
+package gh
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGetFailingWorkflows(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/tools/pulls/1"):
+			_, _ = io.WriteString(w, `{"number": 1, "head": {"sha": "new", "ref": "fix"}}`)
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/tools/pulls/2"):
+			_, _ = io.WriteString(w, `{"number": 2, "head": {"sha": "green", "ref": "feature"}}`)
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/tools/actions/runs") && r.URL.Query().Get("branch") == "fix":
+			// Newest first: the build was fixed, lint still fails, and
+			// the test failure was for an older commit.
+			_, _ = io.WriteString(w, `{"workflow_runs": [
+				{"workflow_id": 10, "name": "build", "head_sha": "new", "conclusion": "success"},
+				{"workflow_id": 20, "name": "lint", "head_sha": "new", "conclusion": "failure", "html_url": "https://github.com/acme/tools/actions/runs/3"},
+				{"workflow_id": 10, "name": "build", "head_sha": "new", "conclusion": "failure"},
+				{"workflow_id": 30, "name": "test", "head_sha": "old", "conclusion": "failure"}
+			]}`)
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/tools/actions/runs"):
+			_, _ = io.WriteString(w, `{"workflow_runs": [{"workflow_id": 10, "name": "build", "head_sha": "green", "conclusion": "success"}]}`)
+		default:
+			// No access to acme/private's Actions.
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prs := []GitHubItem{
+		{K: "acme/tools#1", Repo: "acme/tools", Number: 1, Title: "Fix"},
+		{K: "acme/private#5", Repo: "acme/private", Number: 5, Title: "Secret"},
+		{K: "acme/tools#2", Repo: "acme/tools", Number: 2, Title: "Feature"},
+	}
+	items, skipped, err := ghg.GetFailingWorkflows(prs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Key() != "acme/tools#1/ci/20" || items[0].Title != "lint failed: Fix" || items[0].HTMLURL != "https://github.com/acme/tools/actions/runs/3" {
+		t.Errorf("Expected only lint's latest run to have failed, got: %v", items)
+	}
+	if !slices.Equal(skipped, []string{"acme/private#5"}) {
+		t.Errorf("Expected acme/private#5 to be skipped, got: %v", skipped)
+	}
+}
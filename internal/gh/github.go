@@ -11,7 +11,11 @@ import (
 	"log"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
 
 	"github.com/google/go-github/v41/github"
 	"golang.org/x/oauth2"
@@ -19,6 +23,10 @@ import (
 
 var paginationPerPage = 30
 
+// shortSHALength is how many characters of a commit's SHA are used in its
+// notification's key, as in GitHub's own short links.
+const shortSHALength = 7
+
 // GitHubItem is a simple, unified structure we can use to represent issues,
 // PRs and notifications containing only the information the rest of the
 // program requires.
@@ -31,6 +39,49 @@ type GitHubItem struct {
 	Repo      string
 	ID        string
 	Milestone string
+	// SubjectType is the notification subject type, e.g. Issue or Release.
+	// It is empty for items that aren't notifications.
+	SubjectType string
+	// Number is the issue or PR number, when the item has one.
+	Number int
+	// PendingReviewers are the users and teams, e.g. @octocat, whose
+	// review of a PR is still awaited. It's only set for authored PRs
+	// when tracking reviewers.
+	PendingReviewers []string
+	// WaitingOn is who an authored PR is waiting on, WaitingOnAuthor or
+	// WaitingOnReviewers. It's only set when tracking this.
+	WaitingOn string
+	// Body is the Markdown body of an issue. It's only set for assigned
+	// issues.
+	Body string
+	// Parent is the key and title of the tracking issue a task list item
+	// is from.
+	Parent string
+	// Author and Assignees are the logins of an issue or PR's author and
+	// assignees.
+	Author    string
+	Assignees []string
+	// Additions, Deletions and ChangedFiles give the size of a review PR.
+	// They're only set when sizing PRs.
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+	// LatestCommenter is the login of whoever wrote the latest comment a
+	// notification is about. It's empty for notifications about something
+	// other than a comment.
+	LatestCommenter string
+	// Read is true for notifications that have been read, and UpdatedAt
+	// is when a notification, or a searched for PR, was last updated or a
+	// release published.
+	Read      bool
+	UpdatedAt time.Time
+	// ProjectFields are the values of single select fields, such as
+	// Priority, in the Projects (v2) an issue or PR is in. They're only set
+	// when looking up project fields.
+	ProjectFields map[string]string
+	// ProjectIterations are the values of iteration fields, such as
+	// Sprint, in the same way.
+	ProjectIterations map[string]Iteration
 }
 
 func (item GitHubItem) GetTags() iter.Seq[string] {
@@ -45,6 +96,50 @@ func (item GitHubItem) String() string {
 	return fmt.Sprintf("GitHubItem: [%s] %s %s (%s)", item.Key(), item.Title, slices.Collect(item.GetTags()), item.HTMLURL)
 }
 
+// WithAuthorLabel returns the item with an author:<login> label, so its
+// task is tagged with who wrote it.
+func (item GitHubItem) WithAuthorLabel() GitHubItem {
+	if item.Author != "" {
+		item.Labels = append(slices.Clone(item.Labels), "author:"+item.Author)
+	}
+	return item
+}
+
+// WithLabelEmoji returns the item with the emoji for each of its labels in
+// emoji prefixed to its title, e.g. "🐛 Crash on start" for a bug.
+func (item GitHubItem) WithLabelEmoji(emoji map[string]string) GitHubItem {
+	prefix := ""
+	for _, l := range item.Labels {
+		if e, ok := emoji[l]; ok && !strings.Contains(prefix, e) {
+			prefix += e
+		}
+	}
+	if prefix != "" {
+		item.Title = prefix + " " + item.Title
+	}
+	return item
+}
+
+// HasLabel is true if the item has one of labels, ignoring case.
+func (item GitHubItem) HasLabel(labels []string) bool {
+	return slices.ContainsFunc(item.Labels, func(l string) bool {
+		return slices.ContainsFunc(labels, func(want string) bool {
+			return strings.EqualFold(l, want)
+		})
+	})
+}
+
+// GetTitle meets the delta.Titled interface.
+func (item GitHubItem) GetTitle() string {
+	return item.Title
+}
+
+// IsSecurityAlert is true for notifications about security advisories and
+// Dependabot alerts.
+func (item GitHubItem) IsSecurityAlert() bool {
+	return isSecurityAlertType(item.SubjectType)
+}
+
 // Key meets the Keyed interface used for creating delta operations in
 // github2omnifocus. For the desired state, this is a unique key for
 // the item derived from the GitHub data.
@@ -52,16 +147,60 @@ func (item GitHubItem) Key() string {
 	return item.K
 }
 
+// ownerRepo splits the item's Repo into its owner and repository name.
+func (item GitHubItem) ownerRepo() (string, string) {
+	owner, repo, _ := strings.Cut(item.Repo, "/")
+	return owner, repo
+}
+
 type GitHubGateway struct {
 	ctx context.Context
 	c   *github.Client
+	// topics caches repository topics by repo for the run.
+	topics map[string][]string
+	// repos caches repositories by name for the run.
+	repos map[string]*github.Repository
+	// login caches the token's user's login for the run, once fetched.
+	login *string
+	// budget, if set, limits the requests made in the run.
+	budget *requestBudget
+	// rate is the rate limit as of the last response.
+	rate *atomic.Pointer[Rate]
+	// tokenExpiry is when the token expires, as of the last response.
+	tokenExpiry *atomic.Pointer[time.Time]
+	// tokenScopes are the token's scopes, as of the last response.
+	tokenScopes *atomic.Pointer[[]string]
+	// sort and order are how issues and search results are ordered, see
+	// SetSort.
+	sort, order string
+	// queries are search query templates replacing the defaults, see
+	// SetQueries.
+	queries map[string]string
 }
 
-func NewGitHubGateway(ctx context.Context, accessToken, apiURL string) (GitHubGateway, error) {
+func NewGitHubGateway(ctx context.Context, accessToken, apiURL string, opts TransportOptions) (GitHubGateway, error) {
+	rt, err := newRoundTripper(opts)
+	if err != nil {
+		return GitHubGateway{}, err
+	}
+	rate := &atomic.Pointer[Rate]{}
+	rt = rateTransport{base: rt, rate: rate}
+	tokenExpiry := &atomic.Pointer[time.Time]{}
+	tokenScopes := &atomic.Pointer[[]string]{}
+	rt = tokenTransport{base: rt, expiry: tokenExpiry, scopes: tokenScopes}
+	var budget *requestBudget
+	if opts.MaxRequests > 0 {
+		budget = &requestBudget{max: int64(opts.MaxRequests)}
+		rt = budgetTransport{base: rt, budget: budget}
+	}
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: accessToken},
 	)
-	tc := oauth2.NewClient(ctx, ts)
+	// oauth2 builds its client on top of the client in the context.
+	tc := oauth2.NewClient(
+		context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: rt}),
+		ts,
+	)
 
 	// Passing APIURL as the uploadURL (2nd param) technically doesn't
 	// work but we never upload so we're okay
@@ -72,90 +211,221 @@ func NewGitHubGateway(ctx context.Context, accessToken, apiURL string) (GitHubGa
 	}
 
 	return GitHubGateway{
-		ctx: ctx,
-		c:   client,
+		ctx:         ctx,
+		c:           client,
+		topics:      map[string][]string{},
+		repos:       map[string]*github.Repository{},
+		login:       new(string),
+		budget:      budget,
+		rate:        rate,
+		tokenExpiry: tokenExpiry,
+		tokenScopes: tokenScopes,
 	}, nil
 }
 
-// GetIssues downloads and returns the issues for the user authenticated
-// to c, transformed to GitHubItems.
-func (ghg *GitHubGateway) GetIssues() ([]GitHubItem, error) {
-	opt := &github.IssueListOptions{
-		ListOptions: github.ListOptions{PerPage: paginationPerPage},
+// SetSort sets how issues and search results are ordered: by "created",
+// "updated" or "comments", "asc" or "desc". Empty values leave it to
+// GitHub.
+func (ghg *GitHubGateway) SetSort(sort, order string) {
+	ghg.sort = sort
+	ghg.order = order
+}
+
+// SetQueries replaces search queries by name with templates, which can use
+// {{.User}} for the user's login. The names are "issues" for assigned
+// issues, "reviews" for review requests, "authored" for the user's PRs and
+// "mentions".
+func (ghg *GitHubGateway) SetQueries(queries map[string]string) {
+	ghg.queries = queries
+}
+
+// queryData is what search query templates are given. The user's login is
+// only fetched if a template uses it.
+type queryData struct {
+	ghg *GitHubGateway
+}
+
+func (d queryData) User() (string, error) {
+	return d.ghg.Login()
+}
+
+// query returns the search query named name, from the template set with
+// SetQueries or else def.
+func (ghg *GitHubGateway) query(name, def string) (string, error) {
+	text, ok := ghg.queries[name]
+	if !ok {
+		text = def
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("error parsing %s query: %v", name, err)
+	}
+	var b strings.Builder
+	err = tmpl.Execute(&b, queryData{ghg})
+	if err != nil {
+		return "", fmt.Errorf("error building %s query: %v", name, err)
 	}
+	return b.String(), nil
+}
 
-	issues := []*github.Issue{}
-	for {
-		log.Printf("Getting issues page %d", opt.Page)
-		results, resp, err := ghg.c.Issues.List(ghg.ctx, true, opt)
-		issues = append(issues, results...)
-		if err != nil {
-			return nil, err
-		}
-		if resp.NextPage == 0 {
-			break
+// GetIssues downloads and returns the issues for the user authenticated
+// to c, transformed to GitHubItems. Issues in archived repos are left out,
+// as they can't be closed.
+func (ghg *GitHubGateway) GetIssues() ([]GitHubItem, error) {
+	issues, err := getPages("issues", func(page int) ([]*github.Issue, *github.Response, error) {
+		opt := &github.IssueListOptions{
+			Sort:        ghg.sort,
+			Direction:   ghg.order,
+			ListOptions: github.ListOptions{PerPage: paginationPerPage, Page: page},
 		}
-		opt.Page = resp.NextPage
+		return ghg.c.Issues.List(ghg.ctx, true, opt)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	items := []GitHubItem{}
 	for _, issue := range issues {
-		labels := []string{}
-		for _, label := range issue.Labels {
-			labels = append(labels, *label.Name)
-		}
-		item := GitHubItem{
-			Title:     strings.TrimSpace(issue.GetTitle()),
-			HTMLURL:   issue.GetHTMLURL(),
-			APIURL:    issue.GetURL(),
-			K:         fmt.Sprintf("%s#%d", issue.GetRepository().GetFullName(), issue.GetNumber()),
-			Labels:    labels,
-			Repo:      issue.GetRepository().GetFullName(),
-			Milestone: issue.GetMilestone().GetTitle(),
+		ghg.rememberRepo(issue.GetRepository())
+		if issue.GetRepository().GetArchived() {
+			continue
 		}
-		items = append(items, item)
+		items = append(items, issueItem(issue))
 	}
 
 	return items, nil
 }
 
-func (ghg *GitHubGateway) GetPRs() ([]GitHubItem, error) {
+// AddSearchedIssues returns items, from GetIssues, along with the open
+// issues assigned to the user that they're missing. GetIssues only lists
+// issues in repos the user is a member or collaborator of, so issues in
+// repos they can only read are searched for.
+func (ghg *GitHubGateway) AddSearchedIssues(items []GitHubItem) ([]GitHubItem, error) {
+	query, err := ghg.query("issues", "state:open archived:false assignee:@me")
+	if err != nil {
+		return nil, err
+	}
+	searched, err := ghg.searchIssues("assigned issues", query)
+	if err != nil {
+		return nil, err
+	}
+	listed := map[string]bool{}
+	for _, item := range items {
+		listed[item.Key()] = true
+	}
+	for _, issue := range searched {
+		item := issueItem(issue)
+		if !listed[item.Key()] {
+			listed[item.Key()] = true
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// issueItem transforms an assigned issue to a GitHubItem.
+func issueItem(issue *github.Issue) GitHubItem {
+	labels := []string{}
+	for _, label := range issue.Labels {
+		labels = append(labels, *label.Name)
+	}
+	repo := issueRepo(issue)
+	return GitHubItem{
+		Title:     strings.TrimSpace(issue.GetTitle()),
+		HTMLURL:   issue.GetHTMLURL(),
+		APIURL:    issue.GetURL(),
+		K:         fmt.Sprintf("%s#%d", repo, issue.GetNumber()),
+		Labels:    labels,
+		Repo:      repo,
+		Milestone: issue.GetMilestone().GetTitle(),
+		Number:    issue.GetNumber(),
+		Body:      issue.GetBody(),
+		Author:    issue.GetUser().GetLogin(),
+		Assignees: assignees(issue),
+	}
+}
+
+// issueRepo returns the full name of an issue's repo. Search results only
+// give the repo's API URL.
+func issueRepo(issue *github.Issue) string {
+	if name := issue.GetRepository().GetFullName(); name != "" {
+		return name
+	}
+	_, name, _ := strings.Cut(issue.GetRepositoryURL(), "/repos/")
+	return name
+}
+
+// searchIssues returns the issues and PRs matching query.
+func (ghg *GitHubGateway) searchIssues(what, query string) ([]*github.Issue, error) {
+	return getPages(what, func(page int) ([]*github.Issue, *github.Response, error) {
+		opt := &github.SearchOptions{
+			Sort:        ghg.sort,
+			Order:       ghg.order,
+			ListOptions: github.ListOptions{PerPage: paginationPerPage, Page: page},
+		}
+		results, resp, err := ghg.c.Search.Issues(ghg.ctx, query, opt)
+		if err != nil {
+			return nil, resp, err
+		}
+		return results.Issues, resp, nil
+	})
+}
+
+// Login returns the login of the token's user. It's only fetched the first
+// time it's needed in the run.
+func (ghg *GitHubGateway) Login() (string, error) {
+	if *ghg.login != "" {
+		return *ghg.login, nil
+	}
 	user, _, err := ghg.c.Users.Get(ghg.ctx, "")
+	if err != nil {
+		return "", err
+	}
+	*ghg.login = user.GetLogin()
+	return *ghg.login, nil
+}
+
+// GetPRs returns the open PRs whose review has been requested from the
+// user, directly or through one of their teams. With directOnly, PRs only
+// requested from their teams are left out, unless the query's been
+// replaced.
+func (ghg *GitHubGateway) GetPRs(directOnly bool) ([]GitHubItem, error) {
+	qualifier := "review-requested:"
+	if directOnly {
+		qualifier = "user-review-requested:"
+	}
+	query, err := ghg.query("reviews", "type:pr state:open "+qualifier+"{{.User}}")
 	if err != nil {
 		return nil, err
 	}
-	query := "type:pr state:open review-requested:" + user.GetLogin()
 
 	return ghg.getPRs(query)
 }
 
 func (ghg *GitHubGateway) GetOpenPRs() ([]GitHubItem, error) {
-	user, _, err := ghg.c.Users.Get(ghg.ctx, "")
+	query, err := ghg.query("authored", "type:pr state:open archived:false author:{{.User}}")
 	if err != nil {
 		return nil, err
 	}
-	query := "type:pr state:open archived:false author:" + user.GetLogin()
 
 	return ghg.getPRs(query)
 }
 
-func (ghg *GitHubGateway) getPRs(query string) ([]GitHubItem, error) {
-
-	issues := []*github.Issue{}
-	opt := &github.SearchOptions{
-		ListOptions: github.ListOptions{PerPage: paginationPerPage},
+// GetMentions returns the open issues and PRs that mention the user, but
+// that they didn't open and aren't assigned to or asked to review.
+func (ghg *GitHubGateway) GetMentions() ([]GitHubItem, error) {
+	query, err := ghg.query("mentions", "state:open archived:false mentions:{{.User}} -author:{{.User}} -assignee:{{.User}} -review-requested:{{.User}}")
+	if err != nil {
+		return nil, err
 	}
-	for {
-		log.Printf("Getting PRs page %d", opt.Page)
-		results, resp, err := ghg.c.Search.Issues(ghg.ctx, query, opt)
-		if err != nil {
-			return nil, err
-		}
-		issues = append(issues, results.Issues...)
-		if resp.NextPage == 0 {
-			break
-		}
-		opt.Page = resp.NextPage
+
+	return ghg.getPRs(query)
+}
+
+func (ghg *GitHubGateway) getPRs(query string) ([]GitHubItem, error) {
+	issues, err := ghg.searchIssues("PRs", query)
+	if err != nil {
+		return nil, err
 	}
 
 	items := []GitHubItem{}
@@ -165,18 +435,99 @@ func (ghg *GitHubGateway) getPRs(query string) ([]GitHubItem, error) {
 			labels = append(labels, *label.Name)
 		}
 		item := GitHubItem{
-			Title:   strings.TrimSpace(issue.GetTitle()),
-			HTMLURL: issue.GetHTMLURL(),
-			APIURL:  issue.GetURL(),
-			K:       fmt.Sprintf("%s#%d", issue.GetRepository().GetFullName(), issue.GetNumber()),
-			Labels:  labels,
-			Repo:    issue.GetRepository().GetFullName(),
+			Title:     strings.TrimSpace(issue.GetTitle()),
+			HTMLURL:   issue.GetHTMLURL(),
+			APIURL:    issue.GetURL(),
+			K:         fmt.Sprintf("%s#%d", issue.GetRepository().GetFullName(), issue.GetNumber()),
+			Labels:    labels,
+			Repo:      issue.GetRepository().GetFullName(),
+			Number:    issue.GetNumber(),
+			Author:    issue.GetUser().GetLogin(),
+			Assignees: assignees(issue),
+			UpdatedAt: issue.GetUpdatedAt(),
 		}
 		items = append(items, item)
 	}
 	return items, nil
 }
 
+// ParseIssueKey splits the key of an issue or PR, e.g. acme/tools#12, into
+// its repo and number.
+func ParseIssueKey(key string) (string, int, bool) {
+	repo, num, ok := strings.Cut(key, "#")
+	if !ok || strings.Count(repo, "/") != 1 {
+		return "", 0, false
+	}
+	number, err := strconv.Atoi(num)
+	if err != nil || number <= 0 {
+		return "", 0, false
+	}
+	return repo, number, true
+}
+
+// IssueState is the state of an issue or PR, as needed to explain why it's
+// no longer in the desired state.
+type IssueState struct {
+	State       string `json:"state"`
+	StateReason string `json:"state_reason"`
+	PullRequest *struct {
+		MergedAt *time.Time `json:"merged_at"`
+	} `json:"pull_request"`
+}
+
+// Merged is true for a merged PR.
+func (s IssueState) Merged() bool {
+	return s.PullRequest != nil && s.PullRequest.MergedAt != nil
+}
+
+// GetIssueState returns the state of the issue or PR number in repo.
+func (ghg *GitHubGateway) GetIssueState(repo string, number int) (IssueState, error) {
+	// The go-github version we use predates state_reason, so decode it
+	// ourselves.
+	req, err := ghg.c.NewRequest("GET", fmt.Sprintf("repos/%s/issues/%d", repo, number), nil)
+	if err != nil {
+		return IssueState{}, err
+	}
+	s := IssueState{}
+	_, err = ghg.c.Do(ghg.ctx, req, &s)
+	if err != nil {
+		return IssueState{}, fmt.Errorf("error getting %s#%d: %v", repo, number, err)
+	}
+	return s, nil
+}
+
+// ReopenIssue reopens the issue or PR number in repo.
+func (ghg *GitHubGateway) ReopenIssue(repo string, number int) error {
+	owner, name, _ := strings.Cut(repo, "/")
+	_, _, err := ghg.c.Issues.Edit(ghg.ctx, owner, name, number, &github.IssueRequest{
+		State: github.String("open"),
+	})
+	if err != nil {
+		return fmt.Errorf("error reopening %s#%d: %v", repo, number, err)
+	}
+	return nil
+}
+
+// CommentOnIssue adds a comment to the issue or PR number in repo.
+func (ghg *GitHubGateway) CommentOnIssue(repo string, number int, body string) error {
+	owner, name, _ := strings.Cut(repo, "/")
+	_, _, err := ghg.c.Issues.CreateComment(ghg.ctx, owner, name, number, &github.IssueComment{
+		Body: github.String(body),
+	})
+	if err != nil {
+		return fmt.Errorf("error commenting on %s#%d: %v", repo, number, err)
+	}
+	return nil
+}
+
+func assignees(issue *github.Issue) []string {
+	logins := []string{}
+	for _, u := range issue.Assignees {
+		logins = append(logins, u.GetLogin())
+	}
+	return logins
+}
+
 func (ghg *GitHubGateway) MarkNotificationAsRead(id string) error {
 	_, err := ghg.c.Activity.MarkThreadRead(ghg.ctx, id)
 	if err != nil {
@@ -186,36 +537,114 @@ func (ghg *GitHubGateway) MarkNotificationAsRead(id string) error {
 	return nil
 }
 
-func (ghg *GitHubGateway) GetNotifications() ([]GitHubItem, error) {
-	// Retrieve
-	opt := &github.NotificationListOptions{
-		ListOptions: github.ListOptions{PerPage: paginationPerPage},
+// NotificationFilter chooses which notifications are fetched. The zero
+// value fetches every unread notification.
+type NotificationFilter struct {
+	// Participating fetches only notifications about threads you're
+	// participating in or mentioned in.
+	Participating bool
+	// All fetches read notifications as well as unread ones.
+	All bool
+	// If not zero, read notifications updated since ReadSince are fetched
+	// as well as unread ones.
+	ReadSince time.Time
+}
+
+// chooses reports whether the filter chooses notification n, which has
+// already been fetched with Participating set as in the filter.
+func (f NotificationFilter) chooses(n *github.Notification) bool {
+	return n.GetUnread() || f.All || (!f.ReadSince.IsZero() && n.GetUpdatedAt().After(f.ReadSince))
+}
+
+func (ghg *GitHubGateway) GetNotifications(filter NotificationFilter) ([]GitHubItem, error) {
+	notifications, err := ghg.listNotifications(&github.NotificationListOptions{
+		All:           filter.All,
+		Participating: filter.Participating,
+	})
+	if err != nil {
+		return nil, err
 	}
-	notifications := []*github.Notification{}
-	for {
-		log.Printf("Getting Notifications page %d", opt.Page)
-		results, resp, err := ghg.c.Activity.ListNotifications(ghg.ctx, opt)
+	if !filter.All && !filter.ReadSince.IsZero() {
+		recent, err := ghg.listNotifications(&github.NotificationListOptions{
+			All:           true,
+			Participating: filter.Participating,
+			Since:         filter.ReadSince,
+		})
 		if err != nil {
 			return nil, err
 		}
-		notifications = append(notifications, results...)
-		if resp.NextPage == 0 {
-			break
+		for _, n := range recent {
+			if !n.GetUnread() {
+				notifications = append(notifications, n)
+			}
 		}
-		opt.Page = resp.NextPage
 	}
+	return ghg.notificationItems(notifications)
+}
 
-	// Transform
+// GetNotificationsSince returns the notifications filter chooses that were
+// updated after since and before before, along with the IDs of those that
+// were read and filter doesn't choose. Reading a notification doesn't
+// count as updating it, so notifications read before they were updated
+// again aren't noticed.
+func (ghg *GitHubGateway) GetNotificationsSince(filter NotificationFilter, since, before time.Time) (updated []GitHubItem, read []string, err error) {
+	notifications, err := ghg.listNotifications(&github.NotificationListOptions{
+		All:           true,
+		Participating: filter.Participating,
+		Since:         since,
+		Before:        before,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	kept := []*github.Notification{}
+	read = []string{}
+	for _, n := range notifications {
+		if filter.chooses(n) {
+			kept = append(kept, n)
+		} else {
+			read = append(read, n.GetID())
+		}
+	}
+	updated, err = ghg.notificationItems(kept)
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, read, nil
+}
+
+func (ghg *GitHubGateway) listNotifications(opt *github.NotificationListOptions) ([]*github.Notification, error) {
+	return getPages("notifications", func(page int) ([]*github.Notification, *github.Response, error) {
+		pageOpt := *opt
+		pageOpt.ListOptions = github.ListOptions{PerPage: paginationPerPage, Page: page}
+		return ghg.c.Activity.ListNotifications(ghg.ctx, &pageOpt)
+	})
+}
+
+// notificationItems transforms notifications into items.
+func (ghg *GitHubGateway) notificationItems(notifications []*github.Notification) ([]GitHubItem, error) {
 	items := []GitHubItem{}
 	for _, notification := range notifications {
+		ghg.rememberRepo(notification.GetRepository())
+		// Some subject types don't have a subject URL to work from, so they
+		// are keyed by their thread and link to a page of the repo.
+		if item, ok := subjectlessItem(notification); ok {
+			items = append(items, item)
+			continue
+		}
+
 		// notification.Subject.GetURL() is
 		// - ${baseUrl}/repos/cloudant/infra/issues/1500
 		// - ${baseUrl}/repos/cloudant/infra/commits/b63a54879672ba25e6fd9c7cf5547ba118b7f6ae
 		parts := strings.Split(notification.Subject.GetURL(), "/")
 
 		lp := len(parts)
+		if lp < 4 { //nolint:gomnd
+			log.Printf("unrecognised %s notification without subject URL: %s", notification.Subject.GetType(), notification.Subject.GetTitle())
+			continue
+		}
 		owner, repo, urlType, subjectID := parts[lp-4], parts[lp-3], parts[lp-2], parts[lp-1]
-		if !(urlType == "issues" || urlType == "commits" || urlType == "pulls") {
+		if !(urlType == "issues" || urlType == "commits" || urlType == "pulls" || urlType == "releases" || urlType == "discussions") {
 			wrappedErr := fmt.Errorf(
 				"unrecognised notification type, can't determine subjectID: %s",
 				notification.Subject.GetURL(),
@@ -247,8 +676,15 @@ func (ghg *GitHubGateway) GetNotifications() ([]GitHubItem, error) {
 		// As we could be receiving a comment or an issue, and we only care
 		// about the common-to-both html_url field, we just deserialise into a
 		// struct that contains only that field.
+		//
+		// Releases also give us their tag, which makes a friendlier key than
+		// the release's ID. Comments give us who wrote them.
 		type HTMLURLThing struct {
 			HTMLURL string `json:"html_url,omitempty"`
+			TagName string `json:"tag_name,omitempty"`
+			User    struct {
+				Login string `json:"login,omitempty"`
+			} `json:"user,omitempty"`
 		}
 		var req *http.Request
 		var err error
@@ -262,21 +698,95 @@ func (ghg *GitHubGateway) GetNotifications() ([]GitHubItem, error) {
 		}
 		var issueOrComment HTMLURLThing
 		_, err = ghg.c.Do(ghg.ctx, req, &issueOrComment)
-		if err != nil {
+		if err != nil && urlType == "commits" {
+			// Commits can be gone, say after a force push, but we know
+			// where they'd be.
+			log.Printf("Error retrieving commit notification's comment, linking to the commit: %v", err)
+		} else if err != nil {
 			return nil, fmt.Errorf("error retrieving notification's issue or comment: %v", err)
 		}
 		htmlURL := issueOrComment.HTMLURL
+		title := strings.TrimSpace(notification.Subject.GetTitle())
+
+		key := fmt.Sprintf("%s/%s#%s", owner, repo, subjectID)
+		switch urlType {
+		case "releases":
+			tag := issueOrComment.TagName
+			if tag == "" {
+				tag = subjectID
+			}
+			key = fmt.Sprintf("%s/%s@%s", owner, repo, tag)
+		case "commits":
+			// Full SHAs make for unwieldy task names.
+			short := subjectID[:min(len(subjectID), shortSHALength)]
+			key = fmt.Sprintf("%s/%s@%s", owner, repo, short)
+			title = "Comment on " + key
+			if htmlURL == "" {
+				htmlURL = notification.GetRepository().GetHTMLURL() + "/commit/" + subjectID
+			}
+		}
 
 		item := GitHubItem{
-			Title:   strings.TrimSpace(notification.Subject.GetTitle()),
-			HTMLURL: htmlURL,
-			APIURL:  notification.Subject.GetURL(),
-			K:       fmt.Sprintf("%s/%s#%s", owner, repo, subjectID),
-			Repo:    notification.GetRepository().GetFullName(),
-			ID:      *notification.ID,
+			Title:       title,
+			HTMLURL:     htmlURL,
+			APIURL:      notification.Subject.GetURL(),
+			K:           key,
+			Repo:        notification.GetRepository().GetFullName(),
+			ID:          *notification.ID,
+			SubjectType: notification.Subject.GetType(),
+			Read:        !notification.GetUnread(),
+			UpdatedAt:   notification.GetUpdatedAt(),
+		}
+		if latest := notification.Subject.GetLatestCommentURL(); latest != "" && latest != notification.Subject.GetURL() {
+			item.LatestCommenter = issueOrComment.User.Login
 		}
 		items = append(items, item)
 	}
 
 	return items, nil
 }
+
+func isSecurityAlertType(subjectType string) bool {
+	switch subjectType {
+	case "RepositoryVulnerabilityAlert", "RepositoryDependabotAlertsThread", "SecurityAdvisory":
+		return true
+	}
+	return false
+}
+
+// subjectlessItem returns an item for notifications whose subject has no
+// API URL we can use, which is the case for security alerts, check suites,
+// repository invitations and some discussions. ok is false for other
+// notifications.
+func subjectlessItem(notification *github.Notification) (item GitHubItem, ok bool) {
+	subjectType := notification.Subject.GetType()
+	var kind, page string
+	switch {
+	case isSecurityAlertType(subjectType):
+		kind, page = "alert", "/security"
+		if subjectType != "SecurityAdvisory" {
+			page += "/dependabot"
+		}
+	case subjectType == "CheckSuite":
+		kind, page = "checksuite", "/actions"
+	case subjectType == "RepositoryInvitation":
+		kind, page = "invitation", "/invitations"
+	case subjectType == "Discussion" && notification.Subject.GetURL() == "":
+		kind, page = "discussion", "/discussions"
+	default:
+		return GitHubItem{}, false
+	}
+
+	repo := notification.GetRepository()
+	return GitHubItem{
+		Title:       strings.TrimSpace(notification.Subject.GetTitle()),
+		HTMLURL:     repo.GetHTMLURL() + page,
+		APIURL:      notification.GetURL(),
+		K:           fmt.Sprintf("%s#%s-%s", repo.GetFullName(), kind, notification.GetID()),
+		Repo:        repo.GetFullName(),
+		ID:          notification.GetID(),
+		SubjectType: subjectType,
+		Read:        !notification.GetUnread(),
+		UpdatedAt:   notification.GetUpdatedAt(),
+	}, true
+}
@@ -0,0 +1,525 @@
+package gh
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+)
+
+func notification(id, subjectType, url string) *github.Notification {
+	return &github.Notification{
+		ID: github.String(id),
+		Subject: &github.NotificationSubject{
+			Title: github.String(" A title "),
+			Type:  github.String(subjectType),
+			URL:   github.String(url),
+		},
+		Repository: &github.Repository{
+			FullName: github.String("acme/tools"),
+			HTMLURL:  github.String("https://github.com/acme/tools"),
+		},
+	}
+}
+
+func TestSubjectlessItem(t *testing.T) {
+	cases := []struct {
+		subjectType string
+		key         string
+		htmlURL     string
+		security    bool
+	}{
+		{"RepositoryVulnerabilityAlert", "acme/tools#alert-42", "https://github.com/acme/tools/security/dependabot", true},
+		{"RepositoryDependabotAlertsThread", "acme/tools#alert-42", "https://github.com/acme/tools/security/dependabot", true},
+		{"SecurityAdvisory", "acme/tools#alert-42", "https://github.com/acme/tools/security", true},
+		{"CheckSuite", "acme/tools#checksuite-42", "https://github.com/acme/tools/actions", false},
+		{"RepositoryInvitation", "acme/tools#invitation-42", "https://github.com/acme/tools/invitations", false},
+		{"Discussion", "acme/tools#discussion-42", "https://github.com/acme/tools/discussions", false},
+	}
+	for _, c := range cases {
+		item, ok := subjectlessItem(notification("42", c.subjectType, ""))
+		if !ok {
+			t.Fatalf("%s: expected item", c.subjectType)
+		}
+		if item.Key() != c.key {
+			t.Fatalf("%s: didn't get expected key, got: %s", c.subjectType, item.Key())
+		}
+		if item.HTMLURL != c.htmlURL {
+			t.Fatalf("%s: didn't get expected URL, got: %s", c.subjectType, item.HTMLURL)
+		}
+		if item.Title != "A title" {
+			t.Fatalf("%s: expected trimmed title, got: %q", c.subjectType, item.Title)
+		}
+		// Security alerts are routed to the security project and flagged.
+		if item.IsSecurityAlert() != c.security {
+			t.Fatalf("%s: expected IsSecurityAlert to be %v", c.subjectType, c.security)
+		}
+	}
+}
+
+func TestSubjectlessItemWithURL(t *testing.T) {
+	for _, subjectType := range []string{"Issue", "PullRequest", "Discussion"} {
+		_, ok := subjectlessItem(notification("42", subjectType, "https://api.github.com/repos/acme/tools/issues/1"))
+		if ok {
+			t.Fatalf("%s: expected subject URL to be used", subjectType)
+		}
+	}
+}
+
+func TestParseIssueKey(t *testing.T) {
+	repo, number, ok := ParseIssueKey("acme/tools#12")
+	if !ok || repo != "acme/tools" || number != 12 {
+		t.Fatalf("Didn't get expected repo and number, got: %s %d %v", repo, number, ok)
+	}
+	for _, key := range []string{"acme/tools#alert-42", "acme/tools@v1.2.0", "acme/tools#12/ci/3", "tools#12"} {
+		if _, _, ok := ParseIssueKey(key); ok {
+			t.Fatalf("Expected %s not to parse", key)
+		}
+	}
+}
+
+func TestGetIssueState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/tools/issues/1"):
+			_, _ = io.WriteString(w, `{"state": "closed", "state_reason": "not_planned"}`)
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/tools/issues/2"):
+			_, _ = io.WriteString(w, `{"state": "closed", "pull_request": {"merged_at": "2024-05-01T09:00:00Z"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ghg.GetIssueState("acme/tools", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.StateReason != "not_planned" || s.Merged() {
+		t.Fatalf("Expected unmerged not_planned issue, got: %+v", s)
+	}
+	s, err = ghg.GetIssueState("acme/tools", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Merged() {
+		t.Fatalf("Expected merged PR, got: %+v", s)
+	}
+}
+
+func TestWithAuthorLabel(t *testing.T) {
+	labels := []string{"bug"}
+	item := GitHubItem{Labels: labels, Author: "alice"}.WithAuthorLabel()
+	if len(item.Labels) != 2 || item.Labels[1] != "author:alice" {
+		t.Fatalf("Didn't get expected labels, got: %v", item.Labels)
+	}
+	if len(labels) != 1 {
+		t.Fatal("Expected original labels not to change")
+	}
+	if len(GitHubItem{Labels: labels}.WithAuthorLabel().Labels) != 1 {
+		t.Fatal("Expected no label without an author")
+	}
+}
+
+func TestWithLabelEmoji(t *testing.T) {
+	emoji := map[string]string{"bug": "🐛", "sev1": "🔥", "defect": "🐛"}
+	item := GitHubItem{Title: "Crash on start", Labels: []string{"sev1", "bug", "defect", "ui"}}.WithLabelEmoji(emoji)
+	if item.Title != "🔥🐛 Crash on start" {
+		t.Fatalf("Unexpected title: %s", item.Title)
+	}
+	item = GitHubItem{Title: "Add dark mode", Labels: []string{"ui"}}.WithLabelEmoji(emoji)
+	if item.Title != "Add dark mode" {
+		t.Fatalf("Unexpected title: %s", item.Title)
+	}
+}
+
+func TestNotificationItemsLatestCommenter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/issues/comments/7"):
+			_, _ = io.WriteString(w, `{"html_url": "https://github.com/acme/tools/issues/1#issuecomment-7", "user": {"login": "me"}}`)
+		case strings.HasSuffix(r.URL.Path, "/issues/2"):
+			_, _ = io.WriteString(w, `{"html_url": "https://github.com/acme/tools/issues/2", "user": {"login": "me"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment := notification("1", "Issue", server.URL+"/repos/acme/tools/issues/1")
+	comment.Subject.LatestCommentURL = github.String(server.URL + "/repos/acme/tools/issues/comments/7")
+	// The issue's author isn't its latest commenter.
+	issue := notification("2", "Issue", server.URL+"/repos/acme/tools/issues/2")
+	issue.Subject.LatestCommentURL = issue.Subject.URL
+	items, err := ghg.notificationItems([]*github.Notification{comment, issue})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].LatestCommenter != "me" || items[1].LatestCommenter != "" {
+		t.Errorf("Unexpected items: %+v", items)
+	}
+}
+
+func TestGetNotificationsReadSince(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/notifications") && r.URL.Query().Get("all") == "true":
+			if r.URL.Query().Get("since") == "" {
+				t.Errorf("Expected since with all: %s", r.URL)
+			}
+			_, _ = io.WriteString(w, `[
+				{"id": "1", "unread": true, "subject": {"type": "CheckSuite"}, "repository": {"full_name": "acme/tools"}},
+				{"id": "2", "unread": false, "updated_at": "2024-05-01T09:00:00Z", "subject": {"type": "CheckSuite"}, "repository": {"full_name": "acme/tools"}}
+			]`)
+		case strings.HasSuffix(r.URL.Path, "/notifications"):
+			_, _ = io.WriteString(w, `[{"id": "1", "unread": true, "subject": {"type": "CheckSuite"}, "repository": {"full_name": "acme/tools"}}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ghg.GetNotifications(NotificationFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Expected only the unread notification, got: %+v", items)
+	}
+	items, err = ghg.GetNotifications(NotificationFilter{ReadSince: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Read || !items[1].Read || items[1].ID != "2" {
+		t.Fatalf("Expected the unread and recently read notifications, got: %+v", items)
+	}
+}
+
+func TestGetPRsDirectOnly(t *testing.T) {
+	queries := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/user"):
+			_, _ = io.WriteString(w, `{"login": "me"}`)
+		case strings.HasSuffix(r.URL.Path, "/search/issues"):
+			queries = append(queries, r.URL.Query().Get("q"))
+			_, _ = io.WriteString(w, `{"items": []}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, directOnly := range []bool{false, true} {
+		if _, err := ghg.GetPRs(directOnly); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"type:pr state:open review-requested:me", "type:pr state:open user-review-requested:me"}
+	if strings.Join(queries, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got queries %q, want %q", queries, want)
+	}
+}
+
+func TestLoginFetchedOnce(t *testing.T) {
+	users := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/user"):
+			users++
+			_, _ = io.WriteString(w, `{"login": "me"}`)
+		case strings.HasSuffix(r.URL.Path, "/search/issues"):
+			_, _ = io.WriteString(w, `{"items": []}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ghg.GetPRs(false); err != nil {
+		t.Fatal(err)
+	}
+	// Copies of the gateway share what's cached.
+	copied := ghg
+	if _, err := copied.GetOpenPRs(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ghg.GetMentions(); err != nil {
+		t.Fatal(err)
+	}
+	if users != 1 {
+		t.Fatalf("Expected the user to be fetched once, got %d requests", users)
+	}
+}
+
+func TestSetQueries(t *testing.T) {
+	queries := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/user"):
+			_, _ = io.WriteString(w, `{"login": "me"}`)
+		case strings.HasSuffix(r.URL.Path, "/search/issues"):
+			queries = append(queries, r.URL.Query().Get("q"))
+			_, _ = io.WriteString(w, `{"items": []}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ghg.SetQueries(map[string]string{"reviews": "type:pr state:open review-requested:{{.User}} -label:on-hold"})
+	if _, err := ghg.GetPRs(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ghg.GetOpenPRs(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"type:pr state:open review-requested:me -label:on-hold", "type:pr state:open archived:false author:me"}
+	if strings.Join(queries, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got queries %q, want %q", queries, want)
+	}
+
+	ghg.SetQueries(map[string]string{"mentions": "mentions:{{.Login}}"})
+	if _, err := ghg.GetMentions(); err == nil {
+		t.Fatal("Expected an error for a query using an unknown field")
+	}
+}
+
+func TestSetSort(t *testing.T) {
+	params := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/search/issues"):
+			params = append(params, q.Get("sort")+" "+q.Get("order"))
+			_, _ = io.WriteString(w, `{"items": []}`)
+		case strings.HasSuffix(r.URL.Path, "/issues"):
+			params = append(params, q.Get("sort")+" "+q.Get("direction"))
+			_, _ = io.WriteString(w, `[]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ghg.SetSort("updated", "desc")
+	issues, err := ghg.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ghg.AddSearchedIssues(issues); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"updated desc", "updated desc"}; strings.Join(params, ",") != strings.Join(want, ",") {
+		t.Errorf("got %q, want %q", params, want)
+	}
+}
+
+func TestGetMentions(t *testing.T) {
+	query := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/user"):
+			_, _ = io.WriteString(w, `{"login": "me"}`)
+		case strings.HasSuffix(r.URL.Path, "/search/issues"):
+			query = r.URL.Query().Get("q")
+			_, _ = io.WriteString(w, `{"items": [{"number": 7, "title": "Ask @me", "repository_url": "https://api.github.com/repos/acme/tools", "repository": {"full_name": "acme/tools"}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ghg.GetMentions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "state:open archived:false mentions:me -author:me -assignee:me -review-requested:me"; query != want {
+		t.Errorf("got query %q, want %q", query, want)
+	}
+	if len(items) != 1 || items[0].Key() != "acme/tools#7" {
+		t.Errorf("Expected the mention, got: %v", items)
+	}
+}
+
+func TestAddSearchedIssues(t *testing.T) {
+	query := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		query = r.URL.Query().Get("q")
+		// Search results only give the repo's URL.
+		_, _ = io.WriteString(w, `{"items": [
+			{"number": 1, "title": "Listed", "repository_url": "https://api.github.com/repos/acme/tools"},
+			{"number": 2, "title": "Read only", "body": "due: 2024-06-01", "repository_url": "https://api.github.com/repos/other/lib"}
+		]}`)
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ghg.AddSearchedIssues([]GitHubItem{{K: "acme/tools#1", Repo: "acme/tools", Number: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "state:open archived:false assignee:@me"; query != want {
+		t.Errorf("got query %q, want %q", query, want)
+	}
+	if len(items) != 2 || items[1].Key() != "other/lib#2" || items[1].Repo != "other/lib" || items[1].Body != "due: 2024-06-01" {
+		t.Errorf("Expected the listed issue and the one in other/lib, got: %+v", items)
+	}
+}
+
+func TestGetIssuesArchived(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/issues") {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, `[
+			{"number": 1, "title": "Live", "repository": {"full_name": "acme/tools"}},
+			{"number": 2, "title": "Stale", "repository": {"full_name": "acme/old", "archived": true}}
+		]`)
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ghg.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Key() != "acme/tools#1" {
+		t.Errorf("Expected only the issue in the live repo, got: %+v", items)
+	}
+}
+
+func TestNotificationItemsCommit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/repos/acme/tools/comments/9") {
+			_, _ = io.WriteString(w, `{"html_url": "https://github.com/acme/tools/commit/0123456789abcdef#commitcomment-9", "user": {"login": "octocat"}}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment := notification("1", "Commit", server.URL+"/repos/acme/tools/commits/0123456789abcdef")
+	comment.Subject.LatestCommentURL = github.String(server.URL + "/repos/acme/tools/comments/9")
+	gone := notification("2", "Commit", server.URL+"/repos/acme/tools/commits/fedcba9876543210")
+	items, err := ghg.notificationItems([]*github.Notification{comment, gone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected two items, got: %+v", items)
+	}
+	if items[0].Key() != "acme/tools@0123456" || items[0].Title != "Comment on acme/tools@0123456" || !strings.HasSuffix(items[0].HTMLURL, "#commitcomment-9") {
+		t.Errorf("Unexpected item: %+v", items[0])
+	}
+	if items[1].Key() != "acme/tools@fedcba9" || items[1].HTMLURL != "https://github.com/acme/tools/commit/fedcba9876543210" {
+		t.Errorf("Unexpected item for a missing commit: %+v", items[1])
+	}
+}
+
+func TestNotificationItemsRelease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/tools/releases/7"):
+			_, _ = io.WriteString(w, `{"html_url": "https://github.com/acme/tools/releases/tag/v1.2.0", "tag_name": "v1.2.0"}`)
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/tools/releases/8"):
+			_, _ = io.WriteString(w, `{"html_url": "https://github.com/acme/tools/releases/8"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		id      string
+		key     string
+		htmlURL string
+	}{
+		{"7", "acme/tools@v1.2.0", "https://github.com/acme/tools/releases/tag/v1.2.0"},
+		// Without a tag, the release's ID stands in for it.
+		{"8", "acme/tools@8", "https://github.com/acme/tools/releases/8"},
+	}
+	for _, c := range cases {
+		items, err := ghg.notificationItems([]*github.Notification{
+			notification(c.id, "Release", server.URL+"/repos/acme/tools/releases/"+c.id),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 {
+			t.Fatalf("%s: expected one item, got: %+v", c.id, items)
+		}
+		item := items[0]
+		if item.Key() != c.key || item.HTMLURL != c.htmlURL {
+			t.Errorf("%s: unexpected item: %+v", c.id, item)
+		}
+		// SubjectType is what routes the task to the releases project.
+		if item.SubjectType != "Release" || item.IsSecurityAlert() {
+			t.Errorf("%s: expected a release, got: %+v", c.id, item)
+		}
+	}
+}
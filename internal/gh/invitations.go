@@ -0,0 +1,51 @@
+package gh
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// ResolveInvitations turns repository invitation notifications in items
+// into tasks to accept the invitation, linking to it. Notifications for
+// invitations that are no longer pending, as they've been accepted or
+// declined, are left out so their tasks are completed.
+func (ghg *GitHubGateway) ResolveInvitations(items []GitHubItem) ([]GitHubItem, error) {
+	if !slices.ContainsFunc(items, isInvitation) {
+		return items, nil
+	}
+	invitations, err := getPages("invitations", func(page int) ([]*github.RepositoryInvitation, *github.Response, error) {
+		return ghg.c.Users.ListInvitations(ghg.ctx, &github.ListOptions{PerPage: paginationPerPage, Page: page})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing repository invitations: %v", err)
+	}
+	pending := map[string]*github.RepositoryInvitation{}
+	for _, inv := range invitations {
+		pending[strings.ToLower(inv.GetRepo().GetFullName())] = inv
+	}
+
+	resolved := []GitHubItem{}
+	for _, item := range items {
+		if !isInvitation(item) {
+			resolved = append(resolved, item)
+			continue
+		}
+		inv, ok := pending[strings.ToLower(item.Repo)]
+		if !ok {
+			continue
+		}
+		item.Title = "Accept invitation to " + item.Repo
+		if inv.GetHTMLURL() != "" {
+			item.HTMLURL = inv.GetHTMLURL()
+		}
+		resolved = append(resolved, item)
+	}
+	return resolved, nil
+}
+
+func isInvitation(item GitHubItem) bool {
+	return item.SubjectType == "RepositoryInvitation"
+}
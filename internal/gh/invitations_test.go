@@ -0,0 +1,48 @@
+package gh
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolveInvitations(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/user/repository_invitations") {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		_, _ = io.WriteString(w, `[{"id": 1, "html_url": "https://github.com/acme/tools/invitations", "repository": {"full_name": "Acme/Tools"}}]`)
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ghg.ResolveInvitations([]GitHubItem{
+		{K: "acme/tools#invitation-1", Repo: "acme/tools", SubjectType: "RepositoryInvitation", Title: "Invitation to join acme/tools"},
+		{K: "acme/lib#invitation-2", Repo: "acme/lib", SubjectType: "RepositoryInvitation"},
+		{K: "acme/tools#3", Repo: "acme/tools", SubjectType: "Issue"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Title != "Accept invitation to acme/tools" || items[0].HTMLURL != "https://github.com/acme/tools/invitations" {
+		t.Fatalf("Expected the pending invitation as a task to accept it, got: %+v", items)
+	}
+	if items[1].Key() != "acme/tools#3" {
+		t.Fatalf("Expected other notifications kept, got: %+v", items)
+	}
+
+	_, err = ghg.ResolveInvitations([]GitHubItem{{K: "acme/tools#3", SubjectType: "Issue"}})
+	if err != nil || requests != 1 {
+		t.Fatalf("Expected no request without invitations, got %d: %v", requests, err)
+	}
+}
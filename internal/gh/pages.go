@@ -0,0 +1,61 @@
+package gh
+
+import (
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// maxConcurrentPages is how many pages of a list are fetched at once.
+var maxConcurrentPages = 4
+
+// getPages returns the results from every page of a list, in page order.
+// get fetches a page, where page 0 is the first. Once the first page's
+// links say how many pages there are, the rest are fetched concurrently,
+// up to maxConcurrentPages at a time. Lists that don't say are paged
+// through one at a time.
+func getPages[T any](what string, get func(page int) ([]T, *github.Response, error)) ([]T, error) {
+	log.Printf("Getting %s page 0", what)
+	results, resp, err := get(0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.LastPage == 0 {
+		for resp.NextPage != 0 {
+			page := resp.NextPage
+			log.Printf("Getting %s page %d", what, page)
+			var more []T
+			more, resp, err = get(page)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, more...)
+		}
+		return results, nil
+	}
+
+	pages := make([][]T, resp.LastPage+1)
+	errs := make([]error, resp.LastPage+1)
+	sem := make(chan struct{}, maxConcurrentPages)
+	var wg sync.WaitGroup
+	for page := resp.NextPage; page <= resp.LastPage; page++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			log.Printf("Getting %s page %d", what, page)
+			pages[page], _, errs[page] = get(page)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	for _, p := range pages {
+		results = append(results, p...)
+	}
+	return results, nil
+}
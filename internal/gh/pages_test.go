@@ -0,0 +1,81 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetPagesConcurrently(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+			w.Header().Set("Link", fmt.Sprintf(`<http://%[1]s/api/v3/issues?page=2>; rel="next", <http://%[1]s/api/v3/issues?page=9>; rel="last"`, r.Host))
+		} else {
+			// Later pages finish out of order.
+			time.Sleep(time.Duration(10-page) * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `[{"number": %d, "repository": {"full_name": "acme/tools"}}]`, page)
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := ghg.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 9 {
+		t.Fatalf("Expected an issue from each of 9 pages, got: %v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Number != i+1 {
+			t.Fatalf("Expected issues in page order, got: %v", issues)
+		}
+	}
+	if maxInFlight.Load() > int32(maxConcurrentPages) {
+		t.Errorf("Expected at most %d pages at once, got %d", maxConcurrentPages, maxInFlight.Load())
+	}
+}
+
+func TestGetPagesError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<http://%[1]s/api/v3/notifications?page=2>; rel="next", <http://%[1]s/api/v3/notifications?page=3>; rel="last"`, r.Host))
+			w.Write([]byte(`[]`))
+		case "3":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ghg.GetNotifications(NotificationFilter{})
+	if err == nil {
+		t.Fatal("Expected an error when a page fails")
+	}
+}
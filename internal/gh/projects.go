@@ -0,0 +1,169 @@
+package gh
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// projectFieldsBatch is how many issues' and PRs' project fields are
+// fetched per request, well within GitHub's limit on the nodes a query may
+// return.
+const projectFieldsBatch = 25
+
+// projectFieldsFragments select the field values of the Projects (v2)
+// items for an issue or PR.
+const projectFieldsFragments = `
+fragment items on IssueOrPullRequest {
+  ... on Issue { projectItems(first: 20) { nodes { ...fields } } }
+  ... on PullRequest { projectItems(first: 20) { nodes { ...fields } } }
+}
+fragment fields on ProjectV2Item {
+  fieldValues(first: 50) {
+    nodes {
+      ... on ProjectV2ItemFieldSingleSelectValue {
+        name
+        field { ... on ProjectV2FieldCommon { name } }
+      }
+      ... on ProjectV2ItemFieldIterationValue {
+        title
+        startDate
+        duration
+        field { ... on ProjectV2FieldCommon { name } }
+      }
+    }
+  }
+}`
+
+// projectFieldsQuery returns a query for the project fields of n issues or
+// PRs, aliased i0, i1 and so on, given by the variables owner0, name0 and
+// number0, and so on.
+func projectFieldsQuery(n int) string {
+	params, fields := []string{}, []string{}
+	for i := range n {
+		params = append(params, fmt.Sprintf("$owner%d: String!, $name%d: String!, $number%d: Int!", i, i, i))
+		fields = append(fields, fmt.Sprintf("  i%d: repository(owner: $owner%d, name: $name%d) { issueOrPullRequest(number: $number%d) { ...items } }", i, i, i, i))
+	}
+	return fmt.Sprintf("query(%s) {\n%s\n}%s", strings.Join(params, ", "), strings.Join(fields, "\n"), projectFieldsFragments)
+}
+
+type projectFieldValue struct {
+	// Name is set for single select values, and Title, StartDate and
+	// Duration for iteration values.
+	Name      string `json:"name"`
+	Title     string `json:"title"`
+	StartDate string `json:"startDate"`
+	Duration  int    `json:"duration"`
+	Field     struct {
+		Name string `json:"name"`
+	} `json:"field"`
+}
+
+// Iteration is an iteration, such as a sprint, of a Projects (v2)
+// iteration field.
+type Iteration struct {
+	Title string
+	// Start is midnight local time on the iteration's first day.
+	Start time.Time
+	Days  int
+}
+
+// End returns midnight local time after the iteration's last day.
+func (it Iteration) End() time.Time {
+	return it.Start.AddDate(0, 0, it.Days)
+}
+
+// AddProjectFields sets ProjectFields on each of items, which must be
+// issues or PRs, to the values of the single select fields, such as
+// Priority, of the Projects (v2) they're in, and ProjectIterations to the
+// values of their iteration fields, such as Sprint. Where items are in
+// more than one project, the first project with a value for a field wins.
+// The fields of up to projectFieldsBatch items are fetched per request.
+func (ghg *GitHubGateway) AddProjectFields(items []GitHubItem) ([]GitHubItem, error) {
+	withFields := []GitHubItem{}
+	for batch := range slices.Chunk(items, projectFieldsBatch) {
+		log.Printf("Getting project fields for %d items from %s", len(batch), batch[0].Key())
+		variables := map[string]any{}
+		for i, item := range batch {
+			owner, repo := item.ownerRepo()
+			variables[fmt.Sprintf("owner%d", i)] = owner
+			variables[fmt.Sprintf("name%d", i)] = repo
+			variables[fmt.Sprintf("number%d", i)] = item.Number
+		}
+		var data map[string]*struct {
+			IssueOrPullRequest struct {
+				ProjectItems struct {
+					Nodes []struct {
+						FieldValues struct {
+							Nodes []projectFieldValue `json:"nodes"`
+						} `json:"fieldValues"`
+					} `json:"nodes"`
+				} `json:"projectItems"`
+			} `json:"issueOrPullRequest"`
+		}
+		err := ghg.graphQL(projectFieldsQuery(len(batch)), variables, &data)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving project fields for %s: %v", batch[0].Key(), err)
+		}
+		for i, item := range batch {
+			item.ProjectFields = map[string]string{}
+			item.ProjectIterations = map[string]Iteration{}
+			repository := data[fmt.Sprintf("i%d", i)]
+			if repository == nil {
+				return nil, fmt.Errorf("error retrieving project fields for %s: not found", item.Key())
+			}
+			for _, projectItem := range repository.IssueOrPullRequest.ProjectItems.Nodes {
+				for _, v := range projectItem.FieldValues.Nodes {
+					_, seenField := item.ProjectFields[v.Field.Name]
+					_, seenIteration := item.ProjectIterations[v.Field.Name]
+					switch {
+					case v.Field.Name == "" || seenField || seenIteration:
+					case v.StartDate != "":
+						start, err := time.ParseInLocation(time.DateOnly, v.StartDate, time.Local)
+						if err != nil {
+							return nil, fmt.Errorf("error parsing start of %s iteration %q for %s: %v", v.Field.Name, v.Title, item.Key(), err)
+						}
+						item.ProjectIterations[v.Field.Name] = Iteration{Title: v.Title, Start: start, Days: v.Duration}
+					default:
+						item.ProjectFields[v.Field.Name] = v.Name
+					}
+				}
+			}
+			withFields = append(withFields, item)
+		}
+	}
+	return withFields, nil
+}
+
+// graphQL runs a GraphQL query, decoding its data into out.
+func (ghg *GitHubGateway) graphQL(query string, variables map[string]any, out any) error {
+	// The GraphQL endpoint isn't under the REST API's path on GitHub
+	// Enterprise: it's /api/graphql rather than /api/v3/graphql.
+	u := *ghg.c.BaseURL
+	if strings.HasSuffix(u.Path, "/api/v3/") {
+		u.Path = strings.TrimSuffix(u.Path, "v3/") + "graphql"
+	} else {
+		u.Path += "graphql"
+	}
+	req, err := ghg.c.NewRequest("POST", u.String(), map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Data   any `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	resp.Data = out
+	_, err = ghg.c.Do(ghg.ctx, req, &resp)
+	if err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("%s", resp.Errors[0].Message)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddProjectFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if body.Variables["owner0"] != "acme" || body.Variables["name0"] != "tools" || body.Variables["number0"] != float64(1) {
+			t.Errorf("Unexpected variables: %v", body.Variables)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"data": {"i0": {"issueOrPullRequest": {"projectItems": {"nodes": [
+			{"fieldValues": {"nodes": [{}, {"name": "P1", "field": {"name": "Priority"}}]}},
+			{"fieldValues": {"nodes": [{"name": "P3", "field": {"name": "Priority"}}, {"name": "Doing", "field": {"name": "Status"}},
+				{"title": "Sprint 7", "startDate": "2024-05-06", "duration": 14, "field": {"name": "Sprint"}}]}}
+		]}}}}}`)
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ghg.AddProjectFields([]GitHubItem{{K: "acme/tools#1", Repo: "acme/tools", Number: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ProjectFields["Priority"] != "P1" || items[0].ProjectFields["Status"] != "Doing" {
+		t.Fatalf("Unexpected items: %+v", items)
+	}
+	sprint := items[0].ProjectIterations["Sprint"]
+	if sprint.Title != "Sprint 7" || !sprint.Start.Equal(time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local)) || !sprint.End().Equal(time.Date(2024, 5, 20, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Unexpected iteration: %+v", sprint)
+	}
+}
+
+func TestAddProjectFieldsBatches(t *testing.T) {
+	batches := []int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		n := len(body.Variables) / 3
+		batches = append(batches, n)
+		data := map[string]any{}
+		for i := range n {
+			if !strings.Contains(body.Query, fmt.Sprintf("i%d: repository(owner: $owner%d", i, i)) {
+				t.Errorf("Expected item %d in the query, got: %s", i, body.Query)
+			}
+			priority := fmt.Sprintf("P%v", body.Variables[fmt.Sprintf("number%d", i)])
+			data[fmt.Sprintf("i%d", i)] = map[string]any{"issueOrPullRequest": map[string]any{"projectItems": map[string]any{"nodes": []any{
+				map[string]any{"fieldValues": map[string]any{"nodes": []any{map[string]any{"name": priority, "field": map[string]any{"name": "Priority"}}}}},
+			}}}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []GitHubItem{}
+	for n := range projectFieldsBatch + 5 {
+		items = append(items, GitHubItem{K: fmt.Sprintf("acme/tools#%d", n), Repo: "acme/tools", Number: n})
+	}
+	items, err = ghg.AddProjectFields(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(batches, []int{projectFieldsBatch, 5}) {
+		t.Errorf("Expected the items fetched in two requests, got: %v", batches)
+	}
+	for n, item := range items {
+		if item.ProjectFields["Priority"] != fmt.Sprintf("P%d", n) {
+			t.Errorf("Expected %s's own fields, got: %v", item.Key(), item.ProjectFields)
+		}
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"errors": [{"message": "Your token has not been granted the required scopes"}]}`)
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ghg.AddProjectFields([]GitHubItem{{K: "acme/tools#1", Repo: "acme/tools", Number: 1}})
+	if err == nil {
+		t.Fatal("Expected error for GraphQL errors")
+	}
+}
@@ -0,0 +1,46 @@
+package gh
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+)
+
+// AddPRSizes sets Additions, Deletions and ChangedFiles on each of prs.
+func (ghg *GitHubGateway) AddPRSizes(prs []GitHubItem) ([]GitHubItem, error) {
+	items := []GitHubItem{}
+	for _, pr := range prs {
+		owner, repo := pr.ownerRepo()
+		log.Printf("Getting size of %s", pr.Key())
+		p, _, err := ghg.c.PullRequests.Get(ghg.ctx, owner, repo, pr.Number)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving PR %s: %v", pr.Key(), err)
+		}
+		pr.Additions = p.GetAdditions()
+		pr.Deletions = p.GetDeletions()
+		pr.ChangedFiles = p.GetChangedFiles()
+		items = append(items, pr)
+	}
+	return items, nil
+}
+
+// WithSizeLabel returns the PR with a size:<name> label, where name is the
+// key of thresholds with the smallest number of changed lines that the
+// PR's additions and deletions fit within. PRs bigger than every threshold
+// are size:XL.
+func (item GitHubItem) WithSizeLabel(thresholds map[string]int) GitHubItem {
+	lines := item.Additions + item.Deletions
+	names := slices.SortedFunc(maps.Keys(thresholds), func(a, b string) int {
+		return thresholds[a] - thresholds[b]
+	})
+	size := "XL"
+	for _, name := range names {
+		if lines <= thresholds[name] {
+			size = name
+			break
+		}
+	}
+	item.Labels = append(slices.Clone(item.Labels), "size:"+size)
+	return item
+}
@@ -0,0 +1,25 @@
+package gh
+
+import (
+	"testing"
+)
+
+func TestWithSizeLabel(t *testing.T) {
+	thresholds := map[string]int{"XS": 10, "S": 50, "M": 250, "L": 1000}
+	cases := []struct {
+		additions, deletions int
+		label                string
+	}{
+		{5, 5, "size:XS"},
+		{40, 5, "size:S"},
+		{200, 50, "size:M"},
+		{900, 0, "size:L"},
+		{1000, 1, "size:XL"},
+	}
+	for _, c := range cases {
+		item := GitHubItem{Additions: c.additions, Deletions: c.deletions}.WithSizeLabel(thresholds)
+		if len(item.Labels) != 1 || item.Labels[0] != c.label {
+			t.Fatalf("For +%d -%d expected %s, got: %v", c.additions, c.deletions, c.label, item.Labels)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package gh
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Rate is an account's GitHub rate limit, as of the last response.
+type Rate struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// rateTransport records the rate limit headers of each response.
+type rateTransport struct {
+	base http.RoundTripper
+	rate *atomic.Pointer[Rate]
+}
+
+func (t rateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		if r, ok := parseRate(resp.Header); ok {
+			t.rate.Store(&r)
+		}
+	}
+	return resp, err
+}
+
+// parseRate reads the rate limit from response headers. ok is false when
+// they're missing, as they are when GitHub Enterprise has no rate limit.
+func parseRate(h http.Header) (r Rate, ok bool) {
+	limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return Rate{}, false
+	}
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return Rate{}, false
+	}
+	reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return Rate{}, false
+	}
+	return Rate{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}, true
+}
+
+// Rate returns the rate limit as of the gateway's last response. ok is false
+// if no response has given it.
+func (ghg GitHubGateway) Rate() (r Rate, ok bool) {
+	if ghg.rate == nil {
+		return Rate{}, false
+	}
+	if p := ghg.rate.Load(); p != nil {
+		return *p, true
+	}
+	return Rate{}, false
+}
@@ -0,0 +1,42 @@
+package gh
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4990")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ghg.Rate(); ok {
+		t.Fatal("Expected no rate limit before any requests")
+	}
+	_, err = ghg.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := ghg.Rate()
+	want := Rate{Limit: 5000, Remaining: 4990, Reset: time.Unix(1700000000, 0)}
+	if !ok || r != want {
+		t.Fatalf("got %+v %v, want %+v", r, ok, want)
+	}
+}
+
+func TestParseRateMissing(t *testing.T) {
+	if _, ok := parseRate(http.Header{}); ok {
+		t.Fatal("Expected no rate limit without headers")
+	}
+}
@@ -0,0 +1,102 @@
+package gh
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// recordedResponse is a GitHub response saved to disk by recordingTransport.
+// Request headers, and so the access token, are never saved.
+type recordedResponse struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// recordingPath returns the file a response to req is saved in. Requests
+// are identified by method and URL only.
+func recordingPath(dir string, req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return path.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// recordingTransport saves every response it receives from base to dir, so
+// a sync can later be replayed with replayTransport.
+type recordingTransport struct {
+	base http.RoundTripper
+	dir  string
+}
+
+func (t recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	bytes, err := json.MarshalIndent(recordedResponse{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     header,
+		Body:       string(body),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(t.dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(recordingPath(t.dir, req), bytes, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error recording response: %v", err)
+	}
+	return resp, nil
+}
+
+// replayTransport serves responses saved by recordingTransport instead of
+// making requests, failing any request that wasn't recorded.
+type replayTransport struct {
+	dir string
+}
+
+func (t replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	bytes, err := os.ReadFile(recordingPath(t.dir, req))
+	if err != nil {
+		return nil, fmt.Errorf("no recorded response for %s %s: %v", req.Method, req.URL, err)
+	}
+	var r recordedResponse
+	err = json.Unmarshal(bytes, &r)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package gh
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(w, `{"login": "octocat"}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	recording := &http.Client{Transport: recordingTransport{base: http.DefaultTransport, dir: dir}}
+	resp, err := recording.Get(server.URL + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"login": "octocat"}` {
+		t.Fatalf("Recording changed the body, got: %s", body)
+	}
+
+	replaying := &http.Client{Transport: replayTransport{dir: dir}}
+	resp, err = replaying.Get(server.URL + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if requests != 1 {
+		t.Fatalf("Expected replay not to make a request, got %d requests", requests)
+	}
+	if resp.StatusCode != http.StatusAccepted || string(body) != `{"login": "octocat"}` {
+		t.Fatalf("Didn't get recorded response, got: %d %s", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Didn't get recorded headers, got: %v", resp.Header)
+	}
+
+	_, err = replaying.Get(server.URL + "/other")
+	if err == nil {
+		t.Fatal("Expected error for request that wasn't recorded")
+	}
+}
@@ -0,0 +1,41 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// GetLatestReleases returns an item for the latest release of each of
+// repos, keyed by its tag as release notifications are. Its UpdatedAt is
+// when the release was published. Repos without a release are skipped.
+func (ghg *GitHubGateway) GetLatestReleases(repos []string) ([]GitHubItem, error) {
+	items := []GitHubItem{}
+	for _, repo := range repos {
+		owner, name := GitHubItem{Repo: repo}.ownerRepo()
+		log.Printf("Getting latest release of %s", repo)
+		release, _, err := ghg.c.Repositories.GetLatestRelease(ghg.ctx, owner, name)
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+			log.Printf("%s has no releases", repo)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving latest release of %s: %v", repo, err)
+		}
+		items = append(items, GitHubItem{
+			Title:     fmt.Sprintf("Upgrade to %s %s", name, release.GetTagName()),
+			HTMLURL:   release.GetHTMLURL(),
+			APIURL:    release.GetURL(),
+			K:         fmt.Sprintf("%s@%s", repo, release.GetTagName()),
+			Repo:      repo,
+			ID:        strconv.FormatInt(release.GetID(), 10),
+			UpdatedAt: release.GetPublishedAt().Time,
+		})
+	}
+	return items, nil
+}
@@ -0,0 +1,39 @@
+package gh
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetLatestReleases(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/repos/acme/tools/releases/latest") {
+			_, _ = io.WriteString(w, `{"id": 7, "tag_name": "v2.3.0", "html_url": "https://github.com/acme/tools/releases/tag/v2.3.0", "published_at": "2024-05-01T09:00:00Z"}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ghg.GetLatestReleases([]string{"acme/tools", "acme/unreleased"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Expected a release for acme/tools only, got: %+v", items)
+	}
+	item := items[0]
+	if item.Key() != "acme/tools@v2.3.0" || item.Title != "Upgrade to tools v2.3.0" || !item.UpdatedAt.Equal(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected item: %+v", item)
+	}
+}
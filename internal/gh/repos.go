@@ -0,0 +1,108 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// RepoFilter chooses the repositories whose items are synced. The zero
+// value chooses every repository.
+type RepoFilter struct {
+	// PublicOnly and PrivateOnly choose only public or only private
+	// repositories. Internal repositories count as private.
+	PublicOnly  bool
+	PrivateOnly bool
+	// ExcludeForks leaves out forks.
+	ExcludeForks bool
+}
+
+// IsZero reports whether f chooses every repository.
+func (f RepoFilter) IsZero() bool {
+	return f == RepoFilter{}
+}
+
+func (f RepoFilter) chooses(repo *github.Repository) bool {
+	return !(f.PublicOnly && repo.GetPrivate()) && !(f.PrivateOnly && !repo.GetPrivate()) && !(f.ExcludeForks && repo.GetFork())
+}
+
+// FilterRepos returns the items whose repository f chooses. Items without
+// a repository are kept. Repositories are fetched once per run, unless
+// they came with the items. If a repository can't be fetched, the error
+// is returned along with the items kept so far and those in repositories
+// already known; the rest are dropped, as they may be in repositories f
+// leaves out.
+func (ghg *GitHubGateway) FilterRepos(items []GitHubItem, f RepoFilter) ([]GitHubItem, error) {
+	kept := []GitHubItem{}
+	var firstErr error
+	for _, item := range items {
+		if item.Repo == "" {
+			kept = append(kept, item)
+			continue
+		}
+		repo, known := ghg.repos[item.Repo]
+		if !known && firstErr == nil {
+			var err error
+			repo, err = ghg.repo(item.Repo)
+			if err != nil {
+				firstErr = err
+			}
+		}
+		if repo != nil && f.chooses(repo) {
+			kept = append(kept, item)
+		}
+	}
+	return kept, firstErr
+}
+
+func (ghg *GitHubGateway) repo(name string) (*github.Repository, error) {
+	if repo, ok := ghg.repos[name]; ok {
+		return repo, nil
+	}
+	owner, repoName := GitHubItem{Repo: name}.ownerRepo()
+	log.Printf("Getting repo %s", name)
+	repo, _, err := ghg.c.Repositories.Get(ghg.ctx, owner, repoName)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving repo %s: %v", name, err)
+	}
+	ghg.repos[name] = repo
+	return repo, nil
+}
+
+// RenamedRepos returns the current names of those of repos that have been
+// renamed or transferred, keyed by their old names. GitHub redirects
+// requests for the old name, so these are found by fetching each repo.
+// Repos that no longer exist are left out.
+func (ghg *GitHubGateway) RenamedRepos(repos []string) (map[string]string, error) {
+	renamed := map[string]string{}
+	for _, name := range repos {
+		owner, repoName := GitHubItem{Repo: name}.ownerRepo()
+		log.Printf("Checking whether repo %s was renamed", name)
+		repo, _, err := ghg.c.Repositories.Get(ghg.ctx, owner, repoName)
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving repo %s: %v", name, err)
+		}
+		ghg.rememberRepo(repo)
+		// Names are case-insensitive, so a change of case isn't a rename.
+		if repo.GetFullName() != "" && !strings.EqualFold(repo.GetFullName(), name) {
+			renamed[name] = repo.GetFullName()
+		}
+	}
+	return renamed, nil
+}
+
+// rememberRepo caches a repository that came with an issue or notification,
+// so filtering its items doesn't need to fetch it.
+func (ghg *GitHubGateway) rememberRepo(repo *github.Repository) {
+	if repo.GetFullName() != "" {
+		ghg.repos[repo.GetFullName()] = repo
+	}
+}
@@ -0,0 +1,107 @@
+package gh
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFilterRepos(t *testing.T) {
+	repoRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/issues"):
+			_, _ = io.WriteString(w, `[{"number": 1, "repository": {"full_name": "acme/secret", "private": true}}]`)
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/open"):
+			repoRequests++
+			_, _ = io.WriteString(w, `{"full_name": "acme/open", "private": false, "fork": true}`)
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/broken"):
+			repoRequests++
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := ghg.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := append(issues, GitHubItem{K: "acme/open#2", Repo: "acme/open"}, GitHubItem{K: "acme/open#3", Repo: "acme/open"}, GitHubItem{K: "x"})
+
+	kept, err := ghg.FilterRepos(items, RepoFilter{PrivateOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 || kept[0].Key() != "acme/secret#1" || kept[1].Key() != "x" {
+		t.Errorf("Expected the private issue and the item without a repo, got: %+v", kept)
+	}
+	kept, err = ghg.FilterRepos(items, RepoFilter{PublicOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 3 || kept[0].Key() != "acme/open#2" {
+		t.Errorf("Expected the public items and the item without a repo, got: %+v", kept)
+	}
+	kept, err = ghg.FilterRepos(items, RepoFilter{ExcludeForks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 || kept[0].Key() != "acme/secret#1" {
+		t.Errorf("Expected the items not in the fork, got: %+v", kept)
+	}
+	if repoRequests != 1 {
+		t.Errorf("Expected acme/open to be fetched once, got %d requests", repoRequests)
+	}
+
+	// Once a repo can't be fetched, items in unknown repos are dropped.
+	items = []GitHubItem{{K: "acme/broken#4", Repo: "acme/broken"}, {K: "acme/other#5", Repo: "acme/other"}, {K: "acme/open#6", Repo: "acme/open"}}
+	kept, err = ghg.FilterRepos(items, RepoFilter{PublicOnly: true})
+	if err == nil {
+		t.Fatal("Expected error fetching acme/broken")
+	}
+	if len(kept) != 1 || kept[0].Key() != "acme/open#6" {
+		t.Errorf("Expected only the item in a known public repo, got: %+v", kept)
+	}
+	if repoRequests != 2 {
+		t.Errorf("Expected no requests after the failure, got %d requests", repoRequests)
+	}
+}
+
+func TestRenamedRepos(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/old"):
+			http.Redirect(w, r, strings.TrimSuffix(r.URL.Path, "/repos/acme/old")+"/repositories/42", http.StatusMovedPermanently)
+		case strings.HasSuffix(r.URL.Path, "/repositories/42"):
+			_, _ = io.WriteString(w, `{"full_name": "acme/new"}`)
+		case strings.HasSuffix(r.URL.Path, "/repos/acme/same"):
+			_, _ = io.WriteString(w, `{"full_name": "Acme/Same"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed, err := ghg.RenamedRepos([]string{"acme/old", "acme/same", "acme/gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(renamed) != 1 || renamed["acme/old"] != "acme/new" {
+		t.Errorf("Expected only acme/old to be renamed, got: %v", renamed)
+	}
+}
@@ -0,0 +1,54 @@
+package gh
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// AddPendingReviewers sets PendingReviewers on each of prs to the users and
+// teams whose review has been requested but not yet given. GitHub removes
+// a reviewer from the requested list once they've reviewed.
+func (ghg *GitHubGateway) AddPendingReviewers(prs []GitHubItem) ([]GitHubItem, error) {
+	items := []GitHubItem{}
+	for _, pr := range prs {
+		owner, repo := pr.ownerRepo()
+		log.Printf("Getting requested reviewers for %s", pr.Key())
+		reviewers, _, err := ghg.c.PullRequests.ListReviewers(ghg.ctx, owner, repo, pr.Number, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving requested reviewers for %s: %v", pr.Key(), err)
+		}
+		pr.PendingReviewers = []string{}
+		for _, u := range reviewers.Users {
+			pr.PendingReviewers = append(pr.PendingReviewers, "@"+u.GetLogin())
+		}
+		for _, t := range reviewers.Teams {
+			pr.PendingReviewers = append(pr.PendingReviewers, fmt.Sprintf("@%s/%s", owner, t.GetSlug()))
+		}
+		items = append(items, pr)
+	}
+	return items, nil
+}
+
+// LastReviewRequest returns when review of pr was last requested from
+// login directly, or the zero time if it never was.
+func (ghg *GitHubGateway) LastReviewRequest(pr GitHubItem, login string) (time.Time, error) {
+	owner, repo := pr.ownerRepo()
+	log.Printf("Getting review requests for %s", pr.Key())
+	events, err := getPages("events", func(page int) ([]*github.IssueEvent, *github.Response, error) {
+		return ghg.c.Issues.ListIssueEvents(ghg.ctx, owner, repo, pr.Number, &github.ListOptions{PerPage: paginationPerPage, Page: page})
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error retrieving events for %s: %v", pr.Key(), err)
+	}
+	var last time.Time
+	for _, e := range events {
+		if e.GetEvent() == "review_requested" && strings.EqualFold(e.GetRequestedReviewer().GetLogin(), login) && e.GetCreatedAt().After(last) {
+			last = e.GetCreatedAt()
+		}
+	}
+	return last, nil
+}
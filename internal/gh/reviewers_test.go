@@ -0,0 +1,35 @@
+package gh
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAddPendingReviewers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/repos/acme/tools/pulls/3/requested_reviewers") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"users": [{"login": "octocat"}], "teams": [{"slug": "core"}]}`)
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prs, err := ghg.AddPendingReviewers([]GitHubItem{{K: "acme/tools#3", Repo: "acme/tools", Number: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(prs[0].PendingReviewers, []string{"@octocat", "@acme/core"}) {
+		t.Fatalf("Didn't get expected reviewers, got: %v", prs[0].PendingReviewers)
+	}
+}
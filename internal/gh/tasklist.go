@@ -0,0 +1,66 @@
+package gh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TaskListItem is a "- [ ] item" line in a GitHub Markdown task list.
+type TaskListItem struct {
+	Text    string
+	Checked bool
+}
+
+var taskListLine = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.+)$`)
+
+// ParseTaskList returns the task list items in body, ignoring any in fenced
+// code blocks.
+func ParseTaskList(body string) []TaskListItem {
+	items := []TaskListItem{}
+	inCode := false
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		m := taskListLine.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		items = append(items, TaskListItem{
+			Text:    strings.TrimSpace(m[2]),
+			Checked: m[1] != " ",
+		})
+	}
+	return items
+}
+
+// TrackedItems returns an item for each unchecked task list item in the
+// body of issue, for tracking issues. An item's key is derived from its
+// text, so it survives the list being reordered. Once checked, an item is
+// no longer returned.
+func TrackedItems(issue GitHubItem) []GitHubItem {
+	items := []GitHubItem{}
+	for _, t := range ParseTaskList(issue.Body) {
+		if t.Checked {
+			continue
+		}
+		sum := sha256.Sum256([]byte(t.Text))
+		items = append(items, GitHubItem{
+			Title:   t.Text,
+			HTMLURL: issue.HTMLURL,
+			APIURL:  issue.APIURL,
+			K:       fmt.Sprintf("%s/task-%s", issue.Key(), hex.EncodeToString(sum[:4])),
+			Repo:    issue.Repo,
+			Number:  issue.Number,
+			Parent:  issue.Key() + " " + issue.Title,
+		})
+	}
+	return items
+}
@@ -0,0 +1,45 @@
+package gh
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTaskList(t *testing.T) {
+	body := "Plan:\r\n- [ ] first\r\n  * [x] second\n+ [X] third\n- [] not an item\n```\n- [ ] in code\n```\n- [ ]   last  "
+	items := ParseTaskList(body)
+	expected := []TaskListItem{
+		{"first", false},
+		{"second", true},
+		{"third", true},
+		{"last", false},
+	}
+	if len(items) != len(expected) {
+		t.Fatalf("Expected %d items, got: %v", len(expected), items)
+	}
+	for i := range expected {
+		if items[i] != expected[i] {
+			t.Fatalf("Expected %v, got: %v", expected[i], items[i])
+		}
+	}
+}
+
+func TestTrackedItems(t *testing.T) {
+	issue := GitHubItem{K: "acme/tools#5", Title: "Epic", Repo: "acme/tools", Number: 5, Body: "- [x] done\n- [ ] to do\n- [ ] also to do"}
+	items := TrackedItems(issue)
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 unchecked items, got: %v", items)
+	}
+	if items[0].Title != "to do" || items[0].Parent != "acme/tools#5 Epic" {
+		t.Fatalf("Didn't get expected item, got: %v", items[0])
+	}
+	if !strings.HasPrefix(items[0].Key(), "acme/tools#5/task-") || strings.Contains(items[0].Key(), " ") {
+		t.Fatalf("Didn't get expected key, got: %s", items[0].Key())
+	}
+
+	// Reordering the list keeps the keys.
+	issue.Body = "- [ ] also to do\n- [ ] to do"
+	if TrackedItems(issue)[1].Key() != items[0].Key() {
+		t.Fatal("Expected key to be stable when the list is reordered")
+	}
+}
@@ -0,0 +1,95 @@
+package gh
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// tokenExpirationHeader is sent by GitHub with responses to requests made
+// with a token that expires, such as a fine-grained personal access token.
+const tokenExpirationHeader = "GitHub-Authentication-Token-Expiration"
+
+// scopesHeader lists the scopes of a classic personal access token or
+// OAuth token.
+const scopesHeader = "X-OAuth-Scopes"
+
+// tokenTransport records when the token expires and its scopes, from each
+// response.
+type tokenTransport struct {
+	base   http.RoundTripper
+	expiry *atomic.Pointer[time.Time]
+	scopes *atomic.Pointer[[]string]
+}
+
+func (t tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		if expiry, ok := parseTokenExpiry(resp.Header); ok {
+			t.expiry.Store(&expiry)
+		}
+		if scopes, ok := parseScopes(resp.Header); ok {
+			t.scopes.Store(&scopes)
+		}
+	}
+	return resp, err
+}
+
+// parseTokenExpiry reads when the token expires from response headers,
+// given as, say, "2024-06-01 12:00:00 UTC". ok is false for tokens that
+// don't expire.
+func parseTokenExpiry(h http.Header) (expiry time.Time, ok bool) {
+	v := h.Get(tokenExpirationHeader)
+	if v == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// TokenExpiry returns when the gateway's token expires, as of its last
+// response. ok is false if the token doesn't expire or no request has been
+// made.
+func (ghg GitHubGateway) TokenExpiry() (expiry time.Time, ok bool) {
+	if ghg.tokenExpiry == nil {
+		return time.Time{}, false
+	}
+	if p := ghg.tokenExpiry.Load(); p != nil {
+		return *p, true
+	}
+	return time.Time{}, false
+}
+
+// parseScopes reads the token's scopes from response headers, given as,
+// say, "repo, notifications". ok is false for tokens without scopes, such
+// as fine-grained personal access tokens and GitHub App tokens, which send
+// the header empty or not at all. A classic token granted no scopes sends
+// it empty too, but can't be told apart from those, so isn't checked.
+func parseScopes(h http.Header) (scopes []string, ok bool) {
+	for _, v := range h.Values(scopesHeader) {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	return scopes, len(scopes) > 0
+}
+
+// TokenScopes returns the scopes the gateway's token was granted, as of
+// its last response. ok is false if the token's permissions can't be
+// checked this way or no request has been made.
+func (ghg GitHubGateway) TokenScopes() (scopes []string, ok bool) {
+	if ghg.tokenScopes == nil {
+		return nil, false
+	}
+	if p := ghg.tokenScopes.Load(); p != nil {
+		return *p, true
+	}
+	return nil, false
+}
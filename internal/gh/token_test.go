@@ -0,0 +1,62 @@
+package gh
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenExpiry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("github-authentication-token-expiration", "2024-06-01 12:00:00 UTC")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ghg.TokenExpiry(); ok {
+		t.Fatal("Expected no expiry before any requests")
+	}
+	_, err = ghg.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiry, ok := ghg.TokenExpiry()
+	if want := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC); !ok || !expiry.Equal(want) {
+		t.Fatalf("got %s %v, want %s", expiry, ok, want)
+	}
+}
+
+func TestParseTokenExpiry(t *testing.T) {
+	h := http.Header{}
+	h.Set(tokenExpirationHeader, "2024-06-01 12:00:00 -0700")
+	expiry, ok := parseTokenExpiry(h)
+	if want := time.Date(2024, 6, 1, 19, 0, 0, 0, time.UTC); !ok || !expiry.Equal(want) {
+		t.Fatalf("got %s %v, want %s", expiry, ok, want)
+	}
+	if _, ok := parseTokenExpiry(http.Header{}); ok {
+		t.Fatal("Expected no expiry for a token that doesn't expire")
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	h := http.Header{}
+	h.Set(scopesHeader, "repo, notifications")
+	scopes, ok := parseScopes(h)
+	if !ok || !reflect.DeepEqual(scopes, []string{"repo", "notifications"}) {
+		t.Fatalf("got %v %v", scopes, ok)
+	}
+	h.Set(scopesHeader, "")
+	if scopes, ok := parseScopes(h); ok {
+		t.Fatalf("Expected scopes not to be known from an empty header, got: %v", scopes)
+	}
+	if _, ok := parseScopes(http.Header{}); ok {
+		t.Fatal("Expected scopes not to be known without the header")
+	}
+}
@@ -0,0 +1,41 @@
+package gh
+
+import (
+	"fmt"
+	"log"
+	"slices"
+)
+
+// AddRepoTopics appends the topics of each item's repository to its
+// Labels, so they become tags. Topics are fetched once per repository per
+// run.
+func (ghg *GitHubGateway) AddRepoTopics(items []GitHubItem) ([]GitHubItem, error) {
+	withTopics := []GitHubItem{}
+	for _, item := range items {
+		topics, err := ghg.repoTopics(item.Repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, topic := range topics {
+			if !slices.Contains(item.Labels, topic) {
+				item.Labels = append(slices.Clone(item.Labels), topic)
+			}
+		}
+		withTopics = append(withTopics, item)
+	}
+	return withTopics, nil
+}
+
+func (ghg *GitHubGateway) repoTopics(repo string) ([]string, error) {
+	if topics, ok := ghg.topics[repo]; ok {
+		return topics, nil
+	}
+	owner, name := GitHubItem{Repo: repo}.ownerRepo()
+	log.Printf("Getting topics for %s", repo)
+	topics, _, err := ghg.c.Repositories.ListAllTopics(ghg.ctx, owner, name)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving topics for %s: %v", repo, err)
+	}
+	ghg.topics[repo] = topics
+	return topics, nil
+}
@@ -0,0 +1,46 @@
+package gh
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAddRepoTopics(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/repos/acme/tools/topics") {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"names": ["infra", "bug"]}`)
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ghg.AddRepoTopics([]GitHubItem{
+		{K: "acme/tools#1", Repo: "acme/tools", Labels: []string{"bug"}},
+		{K: "acme/tools#2", Repo: "acme/tools"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(items[0].Labels, []string{"bug", "infra"}) {
+		t.Fatalf("Didn't get expected labels, got: %v", items[0].Labels)
+	}
+	if !slices.Equal(items[1].Labels, []string{"infra", "bug"}) {
+		t.Fatalf("Didn't get expected labels, got: %v", items[1].Labels)
+	}
+	if requests != 1 {
+		t.Fatalf("Expected topics to be fetched once, got %d requests", requests)
+	}
+}
@@ -0,0 +1,116 @@
+package gh
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// TransportOptions configures how the gateway connects to GitHub, for
+// servers that are only reachable through a proxy or use a private CA.
+type TransportOptions struct {
+	// ProxyURL is used for all requests when set. Otherwise the usual
+	// HTTPS_PROXY and friends environment variables are respected.
+	ProxyURL string
+	// CACertFile is a PEM file of CA certificates to trust in addition to
+	// the system pool.
+	CACertFile string
+	// InsecureSkipVerify disables TLS certificate verification.
+	InsecureSkipVerify bool
+	// RecordDir, when set, is where every response from GitHub is saved.
+	RecordDir string
+	// ReplayDir, when set, is where responses are served from instead of
+	// making requests to GitHub.
+	ReplayDir string
+	// MaxRequests, when non-zero, is how many requests the gateway can
+	// make before failing them with ErrBudgetExhausted.
+	MaxRequests int
+}
+
+// newRoundTripper builds the chain of transports that the oauth2 client
+// wraps: tracing, then recording or replaying if asked for.
+func newRoundTripper(opts TransportOptions) (http.RoundTripper, error) {
+	if opts.ReplayDir != "" {
+		return tracingTransport{base: replayTransport{dir: opts.ReplayDir}}, nil
+	}
+	var rt http.RoundTripper
+	rt, err := newTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.RecordDir != "" {
+		rt = recordingTransport{base: rt, dir: opts.RecordDir}
+	}
+	return tracingTransport{base: rt}, nil
+}
+
+// newTransport builds the base HTTP transport.
+func newTransport(opts TransportOptions) (*http.Transport, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.ProxyURL != "" {
+		u, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %v", opts.ProxyURL, err)
+		}
+		tr.Proxy = http.ProxyURL(u)
+	}
+
+	if opts.CACertFile != "" || opts.InsecureSkipVerify {
+		tr.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: opts.InsecureSkipVerify, //nolint:gosec
+		}
+	}
+	if opts.CACertFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA certificates: %v", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CACertFile)
+		}
+		tr.TLSClientConfig.RootCAs = pool
+	}
+
+	return tr, nil
+}
+
+var tracer = otel.Tracer("github.com/rhyshort/github-to-omnifocus/internal/gh")
+
+// tracingTransport records a span for each request made to GitHub, as a
+// child of any span in the request's context.
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracer.Start(req.Context(), "GitHub "+req.Method+" "+req.URL.Path)
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.full", req.URL.String()),
+	)
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,35 @@
+package gh
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestNewTransportProxy(t *testing.T) {
+	tr, err := newTransport(TransportOptions{ProxyURL: "http://proxy.example.com:8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "https://github.example.com/api/v3", nil)
+	u, err := tr.Proxy(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "proxy.example.com:8080" {
+		t.Fatalf("Didn't get expected proxy, got: %v", u)
+	}
+}
+
+func TestNewTransportBadCACertFile(t *testing.T) {
+	p := path.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(p, []byte("not a certificate"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = newTransport(TransportOptions{CACertFile: p})
+	if err == nil {
+		t.Fatal("Expected error for file without certificates")
+	}
+}
@@ -0,0 +1,83 @@
+package gh
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// Who an authored PR is waiting on, as set by AddWaitingOn.
+const (
+	WaitingOnAuthor    = "me"
+	WaitingOnReviewers = "reviewers"
+)
+
+// AddWaitingOn sets WaitingOn on each of prs: WaitingOnAuthor if changes
+// were requested, a check failed against its head commit or it has merge
+// conflicts, and otherwise WaitingOnReviewers.
+func (ghg *GitHubGateway) AddWaitingOn(prs []GitHubItem) ([]GitHubItem, error) {
+	items := []GitHubItem{}
+	for _, pr := range prs {
+		owner, repo := pr.ownerRepo()
+		log.Printf("Getting what %s is waiting on", pr.Key())
+		p, _, err := ghg.c.PullRequests.Get(ghg.ctx, owner, repo, pr.Number)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving PR %s: %v", pr.Key(), err)
+		}
+		reviews, err := getPages("reviews", func(page int) ([]*github.PullRequestReview, *github.Response, error) {
+			return ghg.c.PullRequests.ListReviews(ghg.ctx, owner, repo, pr.Number, &github.ListOptions{PerPage: paginationPerPage, Page: page})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving reviews for %s: %v", pr.Key(), err)
+		}
+		runs, err := getPages("check runs", func(page int) ([]*github.CheckRun, *github.Response, error) {
+			res, resp, err := ghg.c.Checks.ListCheckRunsForRef(ghg.ctx, owner, repo, p.GetHead().GetSHA(), &github.ListCheckRunsOptions{
+				ListOptions: github.ListOptions{PerPage: paginationPerPage, Page: page},
+			})
+			if err != nil {
+				return nil, resp, err
+			}
+			return res.CheckRuns, resp, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving check runs for %s: %v", pr.Key(), err)
+		}
+
+		pr.WaitingOn = WaitingOnReviewers
+		failed := slices.ContainsFunc(runs, func(run *github.CheckRun) bool { return failedConclusions[run.GetConclusion()] })
+		if changesRequested(reviews) || failed || p.GetMergeableState() == "dirty" {
+			pr.WaitingOn = WaitingOnAuthor
+		}
+		items = append(items, pr)
+	}
+	return items, nil
+}
+
+// changesRequested is true if a reviewer's latest approval or request for
+// changes in reviews, which are oldest first, requested changes.
+func changesRequested(reviews []*github.PullRequestReview) bool {
+	latest := map[string]string{}
+	for _, r := range reviews {
+		switch r.GetState() {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			latest[r.GetUser().GetLogin()] = r.GetState()
+		}
+	}
+	for _, state := range latest {
+		if state == "CHANGES_REQUESTED" {
+			return true
+		}
+	}
+	return false
+}
+
+// WithWaitingLabel returns the PR with a waiting:<who> label, such as
+// waiting:me, if AddWaitingOn has set who it's waiting on.
+func (item GitHubItem) WithWaitingLabel() GitHubItem {
+	if item.WaitingOn != "" {
+		item.Labels = append(slices.Clone(item.Labels), "waiting:"+item.WaitingOn)
+	}
+	return item
+}
@@ -0,0 +1,64 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAddWaitingOn(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		p := r.URL.Path
+		switch {
+		case strings.HasSuffix(p, "/pulls/4"):
+			_, _ = io.WriteString(w, `{"number": 4, "mergeable_state": "dirty", "head": {"sha": "sha4"}}`)
+		case strings.Contains(p, "/pulls/") && !strings.HasSuffix(p, "/reviews"):
+			n := p[len(p)-1:]
+			_, _ = io.WriteString(w, `{"number": `+n+`, "mergeable_state": "blocked", "head": {"sha": "sha`+n+`"}}`)
+		case strings.HasSuffix(p, "/pulls/1/reviews"):
+			// Changes requested, then approved.
+			_, _ = io.WriteString(w, `[{"state": "CHANGES_REQUESTED", "user": {"login": "alice"}}, {"state": "COMMENTED", "user": {"login": "alice"}}, {"state": "APPROVED", "user": {"login": "alice"}}]`)
+		case strings.HasSuffix(p, "/pulls/2/reviews"):
+			_, _ = io.WriteString(w, `[{"state": "APPROVED", "user": {"login": "alice"}}, {"state": "CHANGES_REQUESTED", "user": {"login": "bob"}}]`)
+		case strings.HasSuffix(p, "/reviews"):
+			_, _ = io.WriteString(w, `[]`)
+		case strings.HasSuffix(p, "/commits/sha3/check-runs"):
+			_, _ = io.WriteString(w, `{"total_count": 2, "check_runs": [{"conclusion": "success"}, {"conclusion": "failure"}]}`)
+		case strings.HasSuffix(p, "/check-runs"):
+			_, _ = io.WriteString(w, `{"total_count": 1, "check_runs": [{"conclusion": "success"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ghg, err := NewGitHubGateway(context.Background(), "", server.URL+"/", TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prs := []GitHubItem{}
+	for _, n := range []int{1, 2, 3, 4} {
+		prs = append(prs, GitHubItem{K: fmt.Sprintf("acme/tools#%d", n), Repo: "acme/tools", Number: n})
+	}
+	prs, err = ghg.AddWaitingOn(prs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitingOn := []string{}
+	for _, pr := range prs {
+		waitingOn = append(waitingOn, pr.WaitingOn)
+	}
+	// Approved; changes requested; a check failed; conflicts.
+	if want := []string{WaitingOnReviewers, WaitingOnAuthor, WaitingOnAuthor, WaitingOnAuthor}; !slices.Equal(waitingOn, want) {
+		t.Fatalf("Expected %v, got: %v", want, waitingOn)
+	}
+	if labels := prs[0].WithWaitingLabel().Labels; !slices.Equal(labels, []string{"waiting:reviewers"}) {
+		t.Fatalf("Expected a waiting:reviewers label, got: %v", labels)
+	}
+}
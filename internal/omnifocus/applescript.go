@@ -0,0 +1,118 @@
+package omnifocus
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"log"
+)
+
+var (
+	//go:embed applescript
+	applescript embed.FS
+)
+
+// AppleScriptBackend drives Omnifocus using AppleScript rather than JXA, for
+// versions of Omnifocus where the JXA scripts misbehave. All operations are
+// handled by one script, applescript/ofdriver.applescript.
+type AppleScriptBackend struct {
+	// Document is the name of the Omnifocus document to use, or "" for the
+	// default document.
+	Document string
+}
+
+// driverRequest is the input to the AppleScript driver.
+type driverRequest struct {
+	Op   string `json:"op"`
+	Args any    `json:"args"`
+}
+
+// run carries out op, passing it args, and unmarshals its output into out
+// unless out is nil.
+func (b AppleScriptBackend) run(op string, args any, out any) error {
+	code, _ := applescript.ReadFile("applescript/ofdriver.applescript")
+	input, _ := json.Marshal(driverRequest{Op: op, Args: args})
+
+	output, err := executeAppleScript(b.Document, code, input)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(output, out)
+}
+
+func (b AppleScriptBackend) Running(launch bool) (bool, error) {
+	status := AppStatus{}
+	err := b.run("running", AppStatus{Launch: launch}, &status)
+	return status.Running, err
+}
+
+func (b AppleScriptBackend) TasksForQuery(q TaskQuery) ([]Task, error) {
+	tasks := []Task{}
+	err := b.run("tasksForQuery", q, &tasks)
+	if err != nil {
+		return []Task{}, err
+	}
+	return tasks, nil
+}
+
+func (b AppleScriptBackend) AddTask(t NewOmnifocusTask) (Task, error) {
+	task := Task{}
+	err := b.run("addTask", t, &task)
+	return task, err
+}
+
+func (b AppleScriptBackend) CompleteTask(t Task) error {
+	return b.run("completeTask", t, nil)
+}
+
+func (b AppleScriptBackend) DropTask(t Task) error {
+	return b.run("dropTask", t, nil)
+}
+
+func (b AppleScriptBackend) AppendNote(t Task, line string) error {
+	return b.run("appendNote", TaskNote{ID: t.ID, Line: line}, nil)
+}
+
+func (b AppleScriptBackend) RenameTask(t Task, name string) error {
+	return b.run("renameTask", TaskName{ID: t.ID, Name: name}, nil)
+}
+
+func (b AppleScriptBackend) UpdateNotes(updates []NoteUpdate) error {
+	return b.run("updateNotes", updates, nil)
+}
+
+func (b AppleScriptBackend) UpdateChildren(updates []ChildUpdate) error {
+	return b.run("updateChildren", updates, nil)
+}
+
+func (b AppleScriptBackend) UpdateSchedules(updates []ScheduleUpdate) error {
+	return b.run("updateSchedules", updates, nil)
+}
+
+func (b AppleScriptBackend) EnsureTagExists(tag Tag) error {
+	return b.run("ensureTag", tag, nil)
+}
+
+func (b AppleScriptBackend) EnsureProjectExists(p Project) error {
+	return b.run("ensureProject", p, nil)
+}
+
+// ProbeBackend returns JXABackend if JXA scripts can be run against
+// Omnifocus, and otherwise AppleScriptBackend, either using document.
+// Failures that would stop either from working, such as not being allowed
+// to control Omnifocus, are returned rather than falling back.
+func ProbeBackend(document string) (Backend, error) {
+	_, err := JXABackend{Document: document}.Running(false)
+	if err == nil {
+		return JXABackend{Document: document}, nil
+	}
+	var se *ScriptError
+	if errors.As(err, &se) && se.Err == nil {
+		log.Printf("Using AppleScript for Omnifocus, as JXA failed: %v", err)
+		return AppleScriptBackend{Document: document}, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,19 @@
+package omnifocus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAppleScriptDriverOps(t *testing.T) {
+	code, err := applescript.ReadFile("applescript/ofdriver.applescript")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := []string{"running", "tasksForQuery", "addTask", "completeTask", "dropTask", "appendNote", "renameTask", "updateNotes", "updateChildren", "updateSchedules", "ensureTag", "ensureProject"}
+	for _, op := range ops {
+		if !strings.Contains(string(code), `op is "`+op+`"`) {
+			t.Fatalf("Expected the driver to handle %s", op)
+		}
+	}
+}
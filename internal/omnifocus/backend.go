@@ -0,0 +1,75 @@
+package omnifocus
+
+// Backend carries out the low-level operations the Gateway needs against
+// Omnifocus. JXABackend talks to the real application, as does
+// AppleScriptBackend for older versions; FakeBackend lets the sync be
+// exercised without it.
+type Backend interface {
+	Running(launch bool) (bool, error)
+	TasksForQuery(q TaskQuery) ([]Task, error)
+	AddTask(t NewOmnifocusTask) (Task, error)
+	CompleteTask(t Task) error
+	DropTask(t Task) error
+	AppendNote(t Task, line string) error
+	RenameTask(t Task, name string) error
+	UpdateNotes(updates []NoteUpdate) error
+	UpdateChildren(updates []ChildUpdate) error
+	UpdateSchedules(updates []ScheduleUpdate) error
+	EnsureTagExists(tag Tag) error
+	EnsureProjectExists(p Project) error
+}
+
+// JXABackend runs JXA scripts against the Omnifocus app using osascript.
+type JXABackend struct {
+	// Document is the name of the Omnifocus document to use, or "" for the
+	// default document.
+	Document string
+}
+
+func (b JXABackend) Running(launch bool) (bool, error) {
+	return OmnifocusRunning(b.Document, launch)
+}
+
+func (b JXABackend) TasksForQuery(q TaskQuery) ([]Task, error) {
+	return TasksForQuery(b.Document, q)
+}
+
+func (b JXABackend) AddTask(t NewOmnifocusTask) (Task, error) {
+	return AddNewOmnifocusTask(b.Document, t)
+}
+
+func (b JXABackend) CompleteTask(t Task) error {
+	return MarkOmnifocusTaskComplete(b.Document, t)
+}
+
+func (b JXABackend) DropTask(t Task) error {
+	return MarkOmnifocusTaskDropped(b.Document, t)
+}
+
+func (b JXABackend) AppendNote(t Task, line string) error {
+	return AppendOmnifocusTaskNote(b.Document, TaskNote{ID: t.ID, Line: line})
+}
+
+func (b JXABackend) RenameTask(t Task, name string) error {
+	return RenameOmnifocusTask(b.Document, TaskName{ID: t.ID, Name: name})
+}
+
+func (b JXABackend) UpdateNotes(updates []NoteUpdate) error {
+	return UpdateOmnifocusTaskNotes(b.Document, updates)
+}
+
+func (b JXABackend) UpdateChildren(updates []ChildUpdate) error {
+	return UpdateOmnifocusChildTasks(b.Document, updates)
+}
+
+func (b JXABackend) UpdateSchedules(updates []ScheduleUpdate) error {
+	return UpdateOmnifocusTaskSchedules(b.Document, updates)
+}
+
+func (b JXABackend) EnsureTagExists(tag Tag) error {
+	return EnsureTagExists(b.Document, tag)
+}
+
+func (b JXABackend) EnsureProjectExists(p Project) error {
+	return EnsureProjectExists(b.Document, p)
+}
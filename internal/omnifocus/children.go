@@ -0,0 +1,107 @@
+package omnifocus
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
+
+// waitingOnPrefix starts the names of the child tasks for pending
+// reviewers. Other child tasks, such as ones added by hand, are left alone.
+const waitingOnPrefix = "Waiting on "
+
+// ChildUpdate adds child tasks to the task with ID, and completes its
+// incomplete child tasks named in Complete.
+type ChildUpdate struct {
+	ID       string      `json:"id"`
+	Add      []ChildTask `json:"add"`
+	Complete []string    `json:"complete"`
+}
+
+// reviewerChildren returns a child task for each of t's pending reviewers.
+func reviewerChildren(t gh.GitHubItem) []ChildTask {
+	children := []ChildTask{}
+	for _, r := range t.PendingReviewers {
+		children = append(children, ChildTask{Name: waitingOnPrefix + r})
+	}
+	return children
+}
+
+// taskListChildren returns a child task for each unchecked task list item
+// in the body of issue t.
+func taskListChildren(t gh.GitHubItem) []ChildTask {
+	children := []ChildTask{}
+	for _, item := range gh.ParseTaskList(t.Body) {
+		if !item.Checked {
+			children = append(children, ChildTask{Name: item.Text})
+		}
+	}
+	return children
+}
+
+// childUpdate works out the update that brings the child tasks of t in
+// line with desired. Children that aren't desired are only completed if
+// managed, so that other children are left alone. A child that's been
+// completed, e.g. by hand, isn't added again.
+func childUpdate(t Task, desired []ChildTask, managed func(string) bool) (ChildUpdate, bool) {
+	u := ChildUpdate{ID: t.ID, Add: []ChildTask{}, Complete: []string{}}
+	for _, c := range desired {
+		if !slices.ContainsFunc(t.Children, func(existing ChildTask) bool { return existing.Name == c.Name }) {
+			u.Add = append(u.Add, c)
+		}
+	}
+	for _, c := range t.Children {
+		if c.Completed || !managed(c.Name) {
+			continue
+		}
+		if !slices.ContainsFunc(desired, func(d ChildTask) bool { return d.Name == c.Name }) {
+			u.Complete = append(u.Complete, c.Name)
+		}
+	}
+	return u, len(u.Add) > 0 || len(u.Complete) > 0
+}
+
+// updateChildren applies the child updates for synced, where children
+// returns the desired children of an item.
+func (og *Gateway) updateChildren(synced []SyncedTask, children func(gh.GitHubItem) []ChildTask, managed func(gh.GitHubItem, string) bool) error {
+	updates := []ChildUpdate{}
+	for _, s := range synced {
+		u, changed := childUpdate(s.Task, children(s.Item), func(name string) bool {
+			return managed(s.Item, name)
+		})
+		if changed {
+			updates = append(updates, u)
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	err := og.backend().UpdateChildren(updates)
+	if err != nil {
+		return fmt.Errorf("error updating child tasks: %w", err)
+	}
+	return nil
+}
+
+// UpdateReviewers brings the pending reviewer child tasks of each task in
+// line with its PR: completing those for reviewers who have reviewed, and
+// adding any newly requested.
+func (og *Gateway) UpdateReviewers(synced []SyncedTask) error {
+	return og.updateChildren(synced, reviewerChildren, func(_ gh.GitHubItem, name string) bool {
+		return strings.HasPrefix(name, waitingOnPrefix)
+	})
+}
+
+// UpdateTaskLists brings the child tasks of each issue task in line with
+// the task list in the issue's body: completing those for checked items and
+// adding new unchecked items. Children for items removed from the body are
+// left alone, as they can't be told apart from children added by hand.
+func (og *Gateway) UpdateTaskLists(synced []SyncedTask) error {
+	return og.updateChildren(synced, taskListChildren, func(item gh.GitHubItem, name string) bool {
+		return slices.ContainsFunc(gh.ParseTaskList(item.Body), func(t gh.TaskListItem) bool {
+			return t.Text == name
+		})
+	})
+}
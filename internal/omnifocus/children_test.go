@@ -0,0 +1,119 @@
+package omnifocus
+
+import (
+	"path"
+	"slices"
+	"testing"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
+
+func TestUpdateReviewers(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{
+		AppTag:                "github",
+		PendingChangesTag:     "pending",
+		PendingChangesProject: "GitHub Pending",
+		Backend:               fake,
+	}
+	pr := gh.GitHubItem{K: "acme/tools#1", Title: "My change", Repo: "acme/tools", PendingReviewers: []string{"@octocat", "@acme/core"}}
+	err = og.AddAuthoredPR(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := og.GetAuthoredPRs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(childNames(tasks[0]), []string{"Waiting on @octocat", "Waiting on @acme/core"}) {
+		t.Fatalf("Didn't get expected child tasks, got: %v", tasks[0].Children)
+	}
+
+	// Children not added by sync are left alone.
+	fake.Tasks[0].Children = append(fake.Tasks[0].Children, ChildTask{Name: "ping team"})
+	tasks, err = og.GetAuthoredPRs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr.PendingReviewers = []string{"@acme/core", "@hubot"}
+	err = og.UpdateReviewers([]SyncedTask{{Item: pr, Task: tasks[0]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err = og.GetAuthoredPRs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(childNames(tasks[0]), []string{"Waiting on @acme/core", "ping team", "Waiting on @hubot"}) {
+		t.Fatalf("Didn't get expected child tasks, got: %v", tasks[0].Children)
+	}
+
+	// Nothing to change makes no update.
+	ops := len(fake.Operations)
+	err = og.UpdateReviewers([]SyncedTask{{Item: pr, Task: tasks[0]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Operations) != ops {
+		t.Fatalf("Expected no update, got: %v", fake.Operations[ops:])
+	}
+}
+
+// childNames returns the names of t's incomplete children.
+func childNames(t Task) []string {
+	names := []string{}
+	for _, c := range t.Children {
+		if !c.Completed {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func TestUpdateTaskLists(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{
+		AppTag:          "github",
+		AssignedTag:     "assigned",
+		AssignedProject: "GitHub Assigned",
+		IssueTaskLists:  true,
+		Backend:         fake,
+	}
+	issue := gh.GitHubItem{K: "acme/tools#1", Title: "Fix me", Repo: "acme/tools", Body: "- [ ] one\n- [x] two\n- [ ] three"}
+	err = og.AddIssue(issue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(childNames(tasks[0]), []string{"one", "three"}) {
+		t.Fatalf("Didn't get expected child tasks, got: %v", tasks[0].Children)
+	}
+
+	// "three" is completed by hand, so mustn't come back.
+	fake.Tasks[0].Children[1].Completed = true
+	tasks, err = og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue.Body = "- [x] one\n- [x] two\n- [ ] three\n- [ ] four"
+	err = og.UpdateTaskLists([]SyncedTask{{Item: issue, Task: tasks[0]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err = og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(childNames(tasks[0]), []string{"four"}) {
+		t.Fatalf("Didn't get expected child tasks, got: %v", tasks[0].Children)
+	}
+}
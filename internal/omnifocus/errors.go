@@ -0,0 +1,80 @@
+package omnifocus
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Reasons a JXA script can fail that the user can do something about. A
+// ScriptError unwraps to one of these when osascript reports it.
+var (
+	ErrNotAuthorized    = errors.New("not allowed to control Omnifocus")
+	ErrNotRunning       = errors.New("Omnifocus isn't running")
+	ErrProjectNotFound  = errors.New("Omnifocus project not found")
+	ErrDocumentNotFound = errors.New("Omnifocus document not found")
+)
+
+// scriptErrors maps text osascript writes to stderr, mostly Apple event
+// error numbers, to the reason for the failure.
+var scriptErrors = []struct {
+	match []string
+	err   error
+}{
+	{[]string{"(-1743)", "Not authorized to send Apple events"}, ErrNotAuthorized},
+	{[]string{"(-600)", "isn't running"}, ErrNotRunning},
+	// Looking up a project that doesn't exist by name gives an invalid
+	// index.
+	{[]string{"(-1719)", "Invalid index"}, ErrProjectNotFound},
+	// Thrown by the scripts when OSA_DOCUMENT names a document that isn't
+	// open.
+	{[]string{"Omnifocus document not found"}, ErrDocumentNotFound},
+}
+
+// ScriptError is a JXA script failing. Err is set when the reason for the
+// failure is known.
+type ScriptError struct {
+	Err    error
+	Stderr string
+}
+
+func (e *ScriptError) Error() string {
+	if e.Err == nil {
+		return "osascript failed: " + e.Stderr
+	}
+	return fmt.Sprintf("%v: %s", e.Err, e.Stderr)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// parseScriptError works out why a script failed from osascript's stderr.
+func parseScriptError(stderr string) *ScriptError {
+	e := &ScriptError{Stderr: strings.TrimSpace(stderr)}
+	for _, se := range scriptErrors {
+		for _, m := range se.match {
+			if strings.Contains(stderr, m) {
+				e.Err = se.err
+				return e
+			}
+		}
+	}
+	return e
+}
+
+// Remediation returns what the user can do to fix err, or "" if there's
+// nothing specific.
+func Remediation(err error) string {
+	switch {
+	case errors.Is(err, ErrNotAuthorized):
+		return "Allow the app running github2omnifocus, such as Terminal, to control Omnifocus in System Settings > Privacy & Security > Automation, then run it again. If it isn't listed there, run `tccutil reset AppleEvents` so macOS asks again."
+	case errors.Is(err, ErrNotRunning):
+		return "Open Omnifocus and run again, or set OmnifocusNotRunning to \"launch\" or \"skip\" in the config."
+	case errors.Is(err, ErrProjectNotFound):
+		return "Create the projects named in the config in Omnifocus, or correct their names in the config, then run again."
+	case errors.Is(err, ErrDocumentNotFound):
+		return "Open the Omnifocus document named by OmnifocusDocument in the config, or correct its name, then run again."
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package omnifocus
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseScriptError(t *testing.T) {
+	cases := []struct {
+		stderr string
+		err    error
+	}{
+		{"execution error: Error: Error: Not authorized to send Apple events to OmniFocus. (-1743)", ErrNotAuthorized},
+		{"execution error: Error: Error: Application isn't running. (-600)", ErrNotRunning},
+		{"execution error: Error: Error: Invalid index. (-1719)", ErrProjectNotFound},
+		{"execution error: Error: Error: Omnifocus document not found: Testing (-2700)", ErrDocumentNotFound},
+		{"execution error: Error: SyntaxError: Unexpected token (-2700)", nil},
+	}
+	for _, c := range cases {
+		// Errors are wrapped on their way up.
+		err := fmt.Errorf("error adding task: %w", parseScriptError(c.stderr))
+		if c.err == nil {
+			if Remediation(err) != "" {
+				t.Fatalf("Expected no remediation for %q", c.stderr)
+			}
+			continue
+		}
+		if !errors.Is(err, c.err) {
+			t.Fatalf("Expected %v for %q, got: %v", c.err, c.stderr, err)
+		}
+		if Remediation(err) == "" {
+			t.Fatalf("Expected remediation for %q", c.stderr)
+		}
+	}
+}
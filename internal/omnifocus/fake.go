@@ -0,0 +1,248 @@
+package omnifocus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// FakeBackend is an in-memory stand-in for Omnifocus, persisted to a JSON
+// file after every change. It records each change, such as an add or
+// complete, so that the full sync can be run and checked in CI or by
+// contributors without a Mac.
+type FakeBackend struct {
+	path string
+
+	Tasks    []FakeTask `json:"tasks"`
+	Tags     []string   `json:"tags"`
+	Projects []string   `json:"projects,omitempty"`
+	// Folders maps each project created within a folder to the folder.
+	Folders    map[string]string `json:"folders,omitempty"`
+	Operations []FakeOperation   `json:"operations"`
+	// Stopped pretends Omnifocus isn't running, until it's launched.
+	Stopped bool `json:"stopped,omitempty"`
+}
+
+// FakeTask is a task held by FakeBackend.
+type FakeTask struct {
+	NewOmnifocusTask
+	ID        string `json:"id"`
+	Completed bool   `json:"completed"`
+	Dropped   bool   `json:"dropped,omitempty"`
+}
+
+// FakeOperation records a change made to a FakeBackend.
+type FakeOperation struct {
+	Op   string    `json:"op"`
+	At   time.Time `json:"at"`
+	Task FakeTask  `json:"task"`
+}
+
+// NewFakeBackend loads the fake's state from p, starting empty if the file
+// doesn't exist.
+func NewFakeBackend(p string) (*FakeBackend, error) {
+	f := &FakeBackend{path: p}
+	bytes, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(bytes, f)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling fake Omnifocus from %s: %v", p, err)
+	}
+	return f, nil
+}
+
+func (f *FakeBackend) save() error {
+	bytes, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, bytes, 0o600)
+}
+
+func (f *FakeBackend) Running(launch bool) (bool, error) {
+	if f.Stopped && launch {
+		f.Stopped = false
+		return true, f.save()
+	}
+	return !f.Stopped, nil
+}
+
+// TasksForQuery returns incomplete tasks in the project, the folder's
+// projects or any project, having all the query's tags, like
+// oftasksforprojectwithtag.js.
+func (f *FakeBackend) TasksForQuery(q TaskQuery) ([]Task, error) {
+	tasks := []Task{}
+	for _, t := range f.Tasks {
+		if t.Completed || t.Dropped || (q.ProjectName != "" && t.ProjectName != q.ProjectName) {
+			continue
+		}
+		if q.ProjectName == "" && q.FolderName != "" && f.Folders[t.ProjectName] != q.FolderName {
+			continue
+		}
+		hasAll := true
+		for _, tag := range q.Tags {
+			if !slices.Contains(t.NewOmnifocusTask.Tags, tag) {
+				hasAll = false
+				break
+			}
+		}
+		if hasAll {
+			tasks = append(tasks, t.task())
+		}
+	}
+	return tasks, nil
+}
+
+func (f *FakeBackend) AddTask(nt NewOmnifocusTask) (Task, error) {
+	t := FakeTask{
+		NewOmnifocusTask: nt,
+		ID:               fmt.Sprintf("fake-%d", len(f.Tasks)+1),
+	}
+	// New tasks go to the top of the project, as with the real script.
+	f.Tasks = append([]FakeTask{t}, f.Tasks...)
+	f.record("add", t)
+	return t.task(), f.save()
+}
+
+func (f *FakeBackend) CompleteTask(t Task) error {
+	for i := range f.Tasks {
+		if f.Tasks[i].ID == t.ID {
+			f.Tasks[i].Completed = true
+			f.record("complete", f.Tasks[i])
+			return f.save()
+		}
+	}
+	return fmt.Errorf("no task with id %s", t.ID)
+}
+
+func (f *FakeBackend) DropTask(t Task) error {
+	for i := range f.Tasks {
+		if f.Tasks[i].ID == t.ID {
+			f.Tasks[i].Dropped = true
+			f.record("drop", f.Tasks[i])
+			return f.save()
+		}
+	}
+	return fmt.Errorf("no task with id %s", t.ID)
+}
+
+func (f *FakeBackend) AppendNote(t Task, line string) error {
+	for i := range f.Tasks {
+		if f.Tasks[i].ID == t.ID {
+			if f.Tasks[i].Note != "" {
+				line = f.Tasks[i].Note + "\n" + line
+			}
+			f.Tasks[i].Note = line
+			f.record("note", f.Tasks[i])
+			return f.save()
+		}
+	}
+	return fmt.Errorf("no task with id %s", t.ID)
+}
+
+func (f *FakeBackend) RenameTask(t Task, name string) error {
+	for i := range f.Tasks {
+		if f.Tasks[i].ID == t.ID {
+			f.Tasks[i].Name = name
+			f.record("rename", f.Tasks[i])
+			return f.save()
+		}
+	}
+	return fmt.Errorf("no task with id %s", t.ID)
+}
+
+func (f *FakeBackend) UpdateNotes(updates []NoteUpdate) error {
+	for _, u := range updates {
+		i := slices.IndexFunc(f.Tasks, func(t FakeTask) bool { return t.ID == u.ID })
+		if i == -1 {
+			return fmt.Errorf("no task with id %s", u.ID)
+		}
+		f.Tasks[i].Note = u.Note
+		f.record("note", f.Tasks[i])
+	}
+	return f.save()
+}
+
+func (f *FakeBackend) UpdateSchedules(updates []ScheduleUpdate) error {
+	for _, u := range updates {
+		i := slices.IndexFunc(f.Tasks, func(t FakeTask) bool { return t.ID == u.ID })
+		if i == -1 {
+			return fmt.Errorf("no task with id %s", u.ID)
+		}
+		f.Tasks[i].Flagged = u.Flagged
+		if u.DueDateMS != 0 {
+			f.Tasks[i].DueDateMS = u.DueDateMS
+		}
+		if u.DeferDateMS != 0 {
+			f.Tasks[i].DeferDateMS = u.DeferDateMS
+		}
+		f.record("schedule", f.Tasks[i])
+	}
+	return f.save()
+}
+
+func (f *FakeBackend) UpdateChildren(updates []ChildUpdate) error {
+	for _, u := range updates {
+		i := slices.IndexFunc(f.Tasks, func(t FakeTask) bool { return t.ID == u.ID })
+		if i == -1 {
+			return fmt.Errorf("no task with id %s", u.ID)
+		}
+		for j, c := range f.Tasks[i].Children {
+			if !c.Completed && slices.Contains(u.Complete, c.Name) {
+				f.Tasks[i].Children[j].Completed = true
+			}
+		}
+		f.Tasks[i].Children = append(f.Tasks[i].Children, u.Add...)
+		f.record("children", f.Tasks[i])
+	}
+	return f.save()
+}
+
+func (f *FakeBackend) EnsureTagExists(tag Tag) error {
+	if slices.Contains(f.Tags, tag.Name) {
+		return nil
+	}
+	f.Tags = append(f.Tags, tag.Name)
+	return f.save()
+}
+
+func (f *FakeBackend) EnsureProjectExists(p Project) error {
+	if slices.Contains(f.Projects, p.Name) {
+		return nil
+	}
+	f.Projects = append(f.Projects, p.Name)
+	if p.Folder != "" {
+		if f.Folders == nil {
+			f.Folders = map[string]string{}
+		}
+		f.Folders[p.Name] = p.Folder
+	}
+	return f.save()
+}
+
+func (f *FakeBackend) record(op string, t FakeTask) {
+	f.Operations = append(f.Operations, FakeOperation{Op: op, At: time.Now(), Task: t})
+}
+
+func (t FakeTask) task() Task {
+	task := Task{
+		ID:        t.ID,
+		Name:      t.Name,
+		Completed: t.Completed,
+		Tags:      t.NewOmnifocusTask.Tags,
+		Note:      t.Note,
+		Children:  slices.Clone(t.NewOmnifocusTask.Children),
+	}
+	if task.Children == nil {
+		task.Children = []ChildTask{}
+	}
+	return task
+}
@@ -0,0 +1,571 @@
+package omnifocus
+
+import (
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
+)
+
+func TestFakeBackendGateway(t *testing.T) {
+	p := path.Join(t.TempDir(), "fake.json")
+	fake, err := NewFakeBackend(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{
+		AppTag:          "github",
+		ReviewTag:       "review",
+		ReviewProject:   "GitHub Reviews",
+		AssignedTag:     "assigned",
+		AssignedProject: "GitHub Assigned",
+		Backend:         fake,
+	}
+
+	err = og.AddPR(gh.GitHubItem{K: "acme/tools#1", Title: "Review me", Repo: "acme/tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = og.AddIssue(gh.GitHubItem{K: "acme/tools#2", Title: "Fix me", Repo: "acme/tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prs, err := og.GetPRs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 1 || prs[0].Key() != "acme/tools#1" {
+		t.Fatalf("Expected one PR task, got: %v", prs)
+	}
+
+	err = og.CompletePR(prs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reload from disk to check changes were saved.
+	reloaded, err := NewFakeBackend(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	og.Backend = reloaded
+	prs, err = og.GetPRs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 0 {
+		t.Fatalf("Expected completed PR task to be excluded, got: %v", prs)
+	}
+	issues, err := og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("Expected one issue task, got: %v", issues)
+	}
+	if len(reloaded.Operations) != 3 {
+		t.Fatalf("Expected 3 recorded operations, got: %v", reloaded.Operations)
+	}
+}
+
+func TestFakeBackendDrop(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{
+		AppTag:          "github",
+		AssignedTag:     "assigned",
+		AssignedProject: "GitHub Assigned",
+		Backend:         fake,
+	}
+	err = og.AddIssue(gh.GitHubItem{K: "acme/tools#2", Title: "Won't fix", Repo: "acme/tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = og.DropIssue(issues[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err = og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("Expected dropped issue task to be excluded, got: %v", issues)
+	}
+	if fake.Tasks[0].Completed || !fake.Tasks[0].Dropped {
+		t.Fatalf("Expected task to be dropped, not completed, got: %+v", fake.Tasks[0])
+	}
+}
+
+func TestReviewChecklist(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{
+		AppTag:          "github",
+		ReviewTag:       "review",
+		ReviewProject:   "GitHub Reviews",
+		ReviewChecklist: []string{"read description", "leave review"},
+		Backend:         fake,
+	}
+	err = og.AddPR(gh.GitHubItem{K: "acme/tools#1", Title: "Review me", Repo: "acme/tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	children := fake.Tasks[0].Children
+	if len(children) != 2 || children[0].Name != "read description" || children[1].Name != "leave review" {
+		t.Fatalf("Didn't get expected child tasks, got: %v", children)
+	}
+}
+
+func TestTrackedItems(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{
+		AppTag:         "github",
+		TrackingTag:    "tracking",
+		TrackingFolder: "GitHub Tracking",
+		Backend:        fake,
+	}
+	issue := gh.GitHubItem{K: "acme/tools#5", Title: "Epic", Repo: "acme/tools", Number: 5, Body: "- [ ] one\n- [ ] two"}
+	for _, item := range gh.TrackedItems(issue) {
+		err = og.AddTrackedItem(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(fake.Projects) != 1 || fake.Projects[0] != "acme/tools#5 Epic" {
+		t.Fatalf("Expected a project for the issue, got: %v", fake.Projects)
+	}
+	tasks, err := og.GetTrackedItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("Expected 2 tracked item tasks, got: %v", tasks)
+	}
+}
+
+func TestFakeBackendRunning(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{Backend: fake}
+	fake.Stopped = true
+
+	running, err := og.Running(false)
+	if err != nil || running {
+		t.Fatalf("Expected Omnifocus not to be running, got: %v %v", running, err)
+	}
+	running, err = og.Running(true)
+	if err != nil || !running {
+		t.Fatalf("Expected Omnifocus to be launched, got: %v %v", running, err)
+	}
+}
+
+func TestPrioritise(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{
+		AppTag:          "github",
+		ReviewTag:       "review",
+		ReviewProject:   "GitHub Reviews",
+		PriorityField:   "Priority",
+		PriorityFlagged: []string{"P0"},
+		PriorityDueIn:   map[string]time.Duration{"P0": 4 * time.Hour, "P1": 24 * time.Hour},
+		ReviewDueIn:     func(string) time.Duration { return 8 * time.Hour },
+		Backend:         fake,
+	}
+	for _, priority := range []string{"P0", "P1"} {
+		err = og.AddPR(gh.GitHubItem{K: "acme/tools#" + priority, Repo: "acme/tools", ProjectFields: map[string]string{"Priority": priority}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	p1, p0 := fake.Tasks[0], fake.Tasks[1]
+	if !p0.Flagged || p1.Flagged {
+		t.Errorf("Expected only the P0 task to be flagged, got: %+v %+v", p0, p1)
+	}
+	// The P1 task keeps its sooner review due date.
+	if p1.DueDateMS-p0.DueDateMS < (4*time.Hour).Milliseconds() || p1.DueDateMS-p0.DueDateMS > (4*time.Hour+time.Minute).Milliseconds() {
+		t.Errorf("Expected the P0 task due 4 hours before the P1 task, got: %+v %+v", p0, p1)
+	}
+}
+
+func TestScheduleForIteration(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{
+		AppTag:          "github",
+		AssignedTag:     "assigned",
+		AssignedProject: "GitHub Assigned",
+		IterationField:  "Sprint",
+		Backend:         fake,
+	}
+	start := time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local)
+	sprint := gh.Iteration{Title: "Sprint 7", Start: start, Days: 14}
+	err = og.AddIssue(gh.GitHubItem{K: "acme/tools#1", Repo: "acme/tools", ProjectIterations: map[string]gh.Iteration{"Sprint": sprint}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := fake.Tasks[0]
+	if task.DeferDateMS != start.UnixMilli() || task.DueDateMS != time.Date(2024, 5, 19, 17, 0, 0, 0, time.Local).UnixMilli() {
+		t.Errorf("Expected task deferred to the sprint's start and due at its end, got: %+v", task)
+	}
+}
+
+func TestUpdateSchedules(t *testing.T) {
+	dir := t.TempDir()
+	fake, err := NewFakeBackend(path.Join(dir, "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := state.Load(path.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{
+		AppTag:          "github",
+		AssignedTag:     "assigned",
+		AssignedProject: "GitHub Assigned",
+		PriorityField:   "Priority",
+		PriorityFlagged: []string{"P0"},
+		IterationField:  "Sprint",
+		State:           st,
+		Backend:         fake,
+	}
+	item := gh.GitHubItem{K: "acme/tools#1", Repo: "acme/tools", ProjectFields: map[string]string{"Priority": "P2"}, ProjectIterations: map[string]gh.Iteration{}}
+	err = og.AddIssue(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	synced := func(item gh.GitHubItem) []SyncedTask {
+		return []SyncedTask{{Item: item, Task: fake.Tasks[0].task()}}
+	}
+	err = og.UpdateSchedules(synced(item))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.Tasks[0].Flagged || fake.Tasks[0].DeferDateMS != 0 {
+		t.Fatalf("Expected the unchanged task to be left alone, got: %+v", fake.Tasks[0])
+	}
+
+	// The issue moves up the board and into a sprint.
+	start := time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local)
+	item.ProjectFields = map[string]string{"Priority": "P0"}
+	item.ProjectIterations = map[string]gh.Iteration{"Sprint": {Title: "Sprint 7", Start: start, Days: 14}}
+	err = og.UpdateSchedules(synced(item))
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := fake.Tasks[0]
+	if !task.Flagged || task.DeferDateMS != start.UnixMilli() || task.DueDateMS != time.Date(2024, 5, 19, 17, 0, 0, 0, time.Local).UnixMilli() {
+		t.Errorf("Expected the task flagged and scheduled for the sprint, got: %+v", task)
+	}
+
+	// Items whose fields weren't fetched are left alone.
+	err = og.UpdateSchedules(synced(gh.GitHubItem{K: "acme/tools#1", Repo: "acme/tools"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fake.Tasks[0].Flagged {
+		t.Errorf("Expected the task to stay flagged, got: %+v", fake.Tasks[0])
+	}
+}
+
+func TestDeferWaitingOnReviewers(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{
+		AppTag:                "github",
+		PendingChangesTag:     "pending",
+		PendingChangesProject: "GitHub Pending",
+		WaitingDeferIn:        24 * time.Hour,
+		Backend:               fake,
+	}
+	for _, waitingOn := range []string{gh.WaitingOnAuthor, gh.WaitingOnReviewers} {
+		err = og.AddAuthoredPR(gh.GitHubItem{K: "acme/tools#" + waitingOn, Repo: "acme/tools", WaitingOn: waitingOn})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	reviewers, author := fake.Tasks[0], fake.Tasks[1]
+	if author.DeferDateMS != 0 {
+		t.Errorf("Expected the task waiting on me not to be deferred, got: %+v", author)
+	}
+	if deferIn := time.Until(time.UnixMilli(reviewers.DeferDateMS)); deferIn < 23*time.Hour || deferIn > 24*time.Hour {
+		t.Errorf("Expected the task waiting on reviewers deferred a day, got: %+v", reviewers)
+	}
+}
+
+// projectsBackend records the projects ensured, as FakeBackend only keeps
+// their names, and the queries made.
+type projectsBackend struct {
+	*FakeBackend
+	projects []Project
+	queries  []TaskQuery
+}
+
+func (b *projectsBackend) EnsureProjectExists(p Project) error {
+	b.projects = append(b.projects, p)
+	return b.FakeBackend.EnsureProjectExists(p)
+}
+
+func (b *projectsBackend) TasksForQuery(q TaskQuery) ([]Task, error) {
+	b.queries = append(b.queries, q)
+	return b.FakeBackend.TasksForQuery(q)
+}
+
+func TestProjectReview(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &projectsBackend{FakeBackend: fake}
+	og := Gateway{AppTag: "github", TrackingTag: "tracking", ProjectReviewIntervalDays: 7, Backend: b}
+	item := gh.GitHubItem{K: "acme/tools#5/1", Repo: "acme/tools", Parent: "acme/tools#5 Epic"}
+	err = og.AddTrackedItem(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	og.ProjectFirstReviewIn = 24 * time.Hour
+	err = og.AddTrackedItem(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	og.ProjectTypes = map[string]string{"GitHub Reviews": "sequential"}
+	err = og.EnsureProjectTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	og.NotificationsProject, og.NotificationTag, og.NotificationsByRepo = "GitHub Notifications", "notification", true
+	err = og.AddNotification(gh.GitHubItem{K: "acme/tools#6", Repo: "acme/tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b.projects) != 4 {
+		t.Fatalf("Expected 4 projects to be ensured, got: %+v", b.projects)
+	}
+	for i, firstReviewIn := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, 24 * time.Hour, 24 * time.Hour} {
+		p := b.projects[i]
+		reviewIn := time.Until(time.UnixMilli(p.NextReviewDateMS))
+		if p.ReviewIntervalDays != 7 || reviewIn > firstReviewIn || reviewIn < firstReviewIn-time.Minute {
+			t.Errorf("Expected a weekly review, first in %v, got: %+v", firstReviewIn, p)
+		}
+	}
+}
+
+func TestTrackedItemsProject(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &projectsBackend{FakeBackend: fake}
+	og := Gateway{AppTag: "github", TrackingTag: "tracking", TrackingFolder: "GitHub Tracking", Backend: b}
+	err = og.AddTrackedItem(gh.GitHubItem{K: "acme/tools#5/task-1", Repo: "acme/tools", Parent: "acme/tools#5 Epic:\n  the  big one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project := fake.Tasks[0].ProjectName; project != "acme/tools#5 Epic: the big one" {
+		t.Errorf("Expected the project to be named by the cleaned title, got: %q", project)
+	}
+	// A tracked task outside the folder isn't read.
+	_, err = fake.AddTask(NewOmnifocusTask{ProjectName: "Elsewhere", Name: "acme/tools#6/task-1", Tags: []string{"github", "tracking"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := og.GetTrackedItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Key() != "acme/tools#5/task-1" {
+		t.Errorf("Expected only the task in the tracking folder, got: %v", tasks)
+	}
+	if q := b.queries[len(b.queries)-1]; q.FolderName != "GitHub Tracking" {
+		t.Errorf("Expected the tracking folder to be queried, got: %+v", q)
+	}
+}
+
+func TestEnsureProjectTypes(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &projectsBackend{FakeBackend: fake}
+	og := Gateway{
+		AppTag:              "github",
+		TrackingTag:         "tracking",
+		TrackingProjectType: "sequential",
+		ProjectTypes:        map[string]string{"GitHub Notifications": "single actions", "GitHub Assigned": "parallel"},
+		Backend:             b,
+	}
+	err = og.EnsureProjectTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = og.AddTrackedItem(gh.GitHubItem{K: "acme/tools#5/1", Repo: "acme/tools", Parent: "acme/tools#5 Epic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Project{
+		{Name: "GitHub Assigned", Type: "parallel", Retype: true},
+		{Name: "GitHub Notifications", Type: "single actions", Retype: true},
+		{Name: "acme/tools#5 Epic", Type: "sequential"},
+	}
+	if !slices.Equal(b.projects, expected) {
+		t.Errorf("Expected projects %+v, got: %+v", expected, b.projects)
+	}
+}
+
+func TestNotificationsByRepo(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &projectsBackend{FakeBackend: fake}
+	og := Gateway{
+		AppTag:               "github",
+		NotificationTag:      "notification",
+		NotificationsProject: "GitHub Notifications",
+		MentionsTag:          "mention",
+		ReleasesProject:      "Releases",
+		SecurityProject:      "Security",
+		Backend:              b,
+	}
+	err = og.AddNotification(gh.GitHubItem{K: "acme/tools#1", Repo: "acme/tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	og.NotificationsByRepo = true
+	for _, item := range []gh.GitHubItem{
+		{K: "acme/tools#2", Repo: "acme/tools"},
+		{K: "acme/web#3", Repo: "acme/web"},
+		{K: "acme/web#4", Repo: "acme/web"},
+		{K: "acme/web#5", Repo: "acme/web", SubjectType: "RepositoryVulnerabilityAlert"},
+		{K: "acme/web@v1.0.0", Repo: "acme/web", SubjectType: "Release"},
+	} {
+		err = og.AddNotification(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = og.AddMention(gh.GitHubItem{K: "acme/api#6", Repo: "acme/api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Project{
+		{Name: "GitHub Notifications : acme/tools", Folder: "GitHub Notifications", Type: "single actions"},
+		{Name: "GitHub Notifications : acme/web", Folder: "GitHub Notifications", Type: "single actions"},
+		{Name: "GitHub Notifications : acme/web", Folder: "GitHub Notifications", Type: "single actions"},
+		{Name: "GitHub Notifications : acme/api", Folder: "GitHub Notifications", Type: "single actions"},
+	}
+	if !slices.Equal(b.projects, expected) {
+		t.Errorf("Expected projects %+v, got: %+v", expected, b.projects)
+	}
+	projects := map[string]string{}
+	for _, task := range fake.Tasks {
+		projects[task.task().Key()] = task.ProjectName
+	}
+	expectedProjects := map[string]string{
+		"acme/tools#1":    "GitHub Notifications",
+		"acme/tools#2":    "GitHub Notifications : acme/tools",
+		"acme/web#3":      "GitHub Notifications : acme/web",
+		"acme/web#4":      "GitHub Notifications : acme/web",
+		"acme/web#5":      "Security",
+		"acme/web@v1.0.0": "Releases",
+		"acme/api#6":      "GitHub Notifications : acme/api",
+	}
+	if !maps.Equal(projects, expectedProjects) {
+		t.Errorf("Expected tasks in %v, got: %v", expectedProjects, projects)
+	}
+	for _, task := range fake.Tasks {
+		if task.Flagged != (task.task().Key() == "acme/web#5") {
+			t.Errorf("Expected only the security alert to be flagged, got: %+v", task)
+		}
+	}
+
+	notifications, err := og.GetNotifications()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 6 {
+		t.Errorf("Expected the notifications in every project, got: %v", notifications)
+	}
+	// Only the notification projects are queried, not the whole database.
+	_, err = fake.AddTask(NewOmnifocusTask{ProjectName: "Elsewhere", Name: "acme/cli#7", Tags: []string{"github", "notification"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range b.queries {
+		if q.ProjectName == "" && q.FolderName == "" {
+			t.Errorf("Expected every query to name a project or folder, got: %+v", q)
+		}
+	}
+	if notifications, err := og.GetNotifications(); err != nil || len(notifications) != 6 {
+		t.Errorf("Expected the task elsewhere to be left out, got: %v %v", notifications, err)
+	}
+	mentions, err := og.GetMentions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mentions) != 1 {
+		t.Errorf("Expected the mention in acme/api, got: %v", mentions)
+	}
+}
+
+func TestRetitle(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{AppTag: "github", AssignedTag: "assigned", AssignedProject: "GitHub Assigned", MaxTitleLength: 10, Backend: fake}
+	item := gh.GitHubItem{K: "acme/tools#1", Repo: "acme/tools", Title: "Old"}
+	err = og.AddIssue(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = og.AppendNote(Task{ID: fake.Tasks[0].ID}, "My notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.Title = "A much longer title"
+	err = og.Retitle(tasks[0], item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.Tasks[0].Name != "acme/tools#1 "+CleanTitle(item.Title, 10) || !strings.Contains(fake.Tasks[0].Note, "My notes") {
+		t.Errorf("Expected the task to be renamed keeping its note, got: %+v", fake.Tasks[0])
+	}
+	if len(fake.Tasks) != 1 {
+		t.Errorf("Expected no task to be added, got: %+v", fake.Tasks)
+	}
+}
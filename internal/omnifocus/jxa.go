@@ -2,21 +2,26 @@ package omnifocus
 
 import (
 	"encoding/json"
-	"io"
-	"log"
-	"os"
-	"os/exec"
 )
 
-// This file holds the wrapper functions for our JXA scripts
+// This file holds the wrapper functions for our JXA scripts. Each takes the
+// name of the Omnifocus document to use, or "" for the default document.
+
+// jxaScript returns the named script from jxa, preceded by ofdocument.js,
+// which the scripts share.
+func jxaScript(name string) []byte {
+	prelude, _ := jxa.ReadFile("jxa/ofdocument.js")
+	code, _ := jxa.ReadFile("jxa/" + name)
+	return append(prelude, code...)
+}
 
 // TasksForQuery returns a list of tasks from Omnifocus that
 // match the passed query.
-func TasksForQuery(q TaskQuery) ([]Task, error) {
-	jsCode, _ := jxa.ReadFile("jxa/oftasksforprojectwithtag.js")
+func TasksForQuery(document string, q TaskQuery) ([]Task, error) {
+	jsCode := jxaScript("oftasksforprojectwithtag.js")
 	args, _ := json.Marshal(q)
 
-	out, err := executeScript(jsCode, args)
+	out, err := executeScript(document, jsCode, args)
 	if err != nil {
 		return []Task{}, err
 	}
@@ -30,83 +35,181 @@ func TasksForQuery(q TaskQuery) ([]Task, error) {
 	return tasks, nil
 }
 
+// AppStatus is whether Omnifocus is running. Launch asks for it to be
+// launched if it isn't.
+type AppStatus struct {
+	Launch  bool `json:"launch,omitempty"`
+	Running bool `json:"running"`
+}
+
+// OmnifocusRunning reports whether Omnifocus is running, launching it first
+// if launch is true.
+func OmnifocusRunning(document string, launch bool) (bool, error) {
+	jsCode := jxaScript("ofrunning.js")
+	args, _ := json.Marshal(AppStatus{Launch: launch})
+
+	out, err := executeScript(document, jsCode, args)
+	if err != nil {
+		return false, err
+	}
+
+	status := AppStatus{}
+	err = json.Unmarshal(out, &status)
+	if err != nil {
+		return false, err
+	}
+	return status.Running, nil
+}
+
 // MarkOmnifocusTaskComplete marks a task as complete. t only requires the
 // id field to be set.
-func MarkOmnifocusTaskComplete(t Task) error {
-	jsCode, _ := jxa.ReadFile("jxa/ofmarktaskcomplete.js")
+func MarkOmnifocusTaskComplete(document string, t Task) error {
+	jsCode := jxaScript("ofmarktaskcomplete.js")
 	args, _ := json.Marshal(t)
 
-	_, err := executeScript(jsCode, args)
+	_, err := executeScript(document, jsCode, args)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	return nil
 }
 
-// EnsureTagExists creates a tag in Omnifocus if it doesn't already exist.
-func EnsureTagExists(tag Tag) error {
-	jsCode, _ := jxa.ReadFile("jxa/ofensuretagexists.js")
-	args, _ := json.Marshal(tag)
+// MarkOmnifocusTaskDropped marks a task as dropped. t only requires the id
+// field to be set.
+func MarkOmnifocusTaskDropped(document string, t Task) error {
+	jsCode := jxaScript("ofmarktaskdropped.js")
+	args, _ := json.Marshal(t)
 
-	_, err := executeScript(jsCode, args)
+	_, err := executeScript(document, jsCode, args)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	return nil
 }
 
-// AddNewOmnifocusTask adds a new Omnifocus task
-func AddNewOmnifocusTask(t NewOmnifocusTask) (Task, error) {
-	jsCode, _ := jxa.ReadFile("jxa/ofaddnewtask.js")
-	args, _ := json.Marshal(t)
+// TaskNote is a line to append to the note of the task with ID.
+type TaskNote struct {
+	ID   string `json:"id"`
+	Line string `json:"line"`
+}
+
+// AppendOmnifocusTaskNote appends a line to a task's note.
+func AppendOmnifocusTaskNote(document string, n TaskNote) error {
+	jsCode := jxaScript("ofappendtasknote.js")
+	args, _ := json.Marshal(n)
 
-	out, err := executeScript(jsCode, args)
+	_, err := executeScript(document, jsCode, args)
 	if err != nil {
-		return Task{}, err
+		return err
 	}
 
-	task := Task{}
-	err = json.Unmarshal(out, &task)
+	return nil
+}
+
+// TaskName is the new name for the task with ID.
+type TaskName struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// RenameOmnifocusTask renames a task.
+func RenameOmnifocusTask(document string, n TaskName) error {
+	jsCode := jxaScript("ofrenametask.js")
+	args, _ := json.Marshal(n)
+
+	_, err := executeScript(document, jsCode, args)
 	if err != nil {
-		return Task{}, err
+		return err
 	}
 
-	return task, nil
+	return nil
+}
+
+// UpdateOmnifocusTaskNotes sets the notes of several tasks in one go.
+func UpdateOmnifocusTaskNotes(document string, updates []NoteUpdate) error {
+	jsCode := jxaScript("ofupdatetasknotes.js")
+	args, _ := json.Marshal(updates)
+
+	_, err := executeScript(document, jsCode, args)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
-// executeScript runs jsCode passing it args as input, and returns the
-// output of the command.
-func executeScript(jsCode []byte, args []byte) ([]byte, error) {
-	// All scripts expect a JSON object passed in via the
-	// OSA_ARGS environment variable. The script itself is
-	// passed into osascript via stdin. The script outputs
-	// a JSON document over stdout.
+// UpdateOmnifocusTaskSchedules sets the flag and dates of several tasks in
+// one go.
+func UpdateOmnifocusTaskSchedules(document string, updates []ScheduleUpdate) error {
+	jsCode := jxaScript("ofupdatetaskschedules.js")
+	args, _ := json.Marshal(updates)
 
-	cmd := exec.Command("/usr/bin/osascript", "-l", "JavaScript", "-s", "o")
+	_, err := executeScript(document, jsCode, args)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
 
-	cmd.Env = append(os.Environ(),
-		"OSA_ARGS="+string(args),
-	)
+// UpdateOmnifocusChildTasks adds and completes the child tasks of several
+// tasks in one go.
+func UpdateOmnifocusChildTasks(document string, updates []ChildUpdate) error {
+	jsCode := jxaScript("ofupdatechildtasks.js")
+	args, _ := json.Marshal(updates)
 
-	stdin, err := cmd.StdinPipe()
+	_, err := executeScript(document, jsCode, args)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	go func() {
-		defer stdin.Close()
-		_, err := io.WriteString(stdin, string(jsCode))
-		if err != nil {
-			// should never fail
-			log.Fatal(err)
-		}
-	}()
-
-	out, err := cmd.Output()
+
+	return nil
+}
+
+// EnsureTagExists creates a tag in Omnifocus if it doesn't already exist.
+func EnsureTagExists(document string, tag Tag) error {
+	jsCode := jxaScript("ofensuretagexists.js")
+	args, _ := json.Marshal(tag)
+
+	_, err := executeScript(document, jsCode, args)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return out, nil
+	return nil
+}
+
+// EnsureProjectExists creates a project in Omnifocus if it doesn't already
+// exist.
+func EnsureProjectExists(document string, p Project) error {
+	jsCode := jxaScript("ofensureproject.js")
+	args, _ := json.Marshal(p)
+
+	_, err := executeScript(document, jsCode, args)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AddNewOmnifocusTask adds a new Omnifocus task
+func AddNewOmnifocusTask(document string, t NewOmnifocusTask) (Task, error) {
+	jsCode := jxaScript("ofaddnewtask.js")
+	args, _ := json.Marshal(t)
+
+	out, err := executeScript(document, jsCode, args)
+	if err != nil {
+		return Task{}, err
+	}
+
+	task := Task{}
+	err = json.Unmarshal(out, &task)
+	if err != nil {
+		return Task{}, err
+	}
+
+	return task, nil
 }
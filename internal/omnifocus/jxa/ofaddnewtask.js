@@ -1,8 +1,8 @@
 // Add a new task to Omnifocus
 // Accepts a OmnifocusTask object as JSON in OSA_ARGS
 // Call it:
-//   set -gx OSA_ARGS '{"projectName": "GitHub Reviews", "name": "task title", "tags": ["github"], "note": "a note", "dateDueMS": 100}'
-//   osascript -l JavaScript ofaddnewtask.js | jq .
+//   set -gx OSA_ARGS '{"projectName": "GitHub Reviews", "name": "task title", "tags": ["github"], "note": "a note", "dateDueMS": 100, "deferDateMS": 50, "flagged": false, "children": [{"name": "a child"}]}'
+//   cat ofdocument.js ofaddnewtask.js | osascript -l JavaScript - | jq .
 // Returns JSON:
 // {
 //  "id": "k9TCngde98W",
@@ -16,6 +16,15 @@
  * @property {string[]} tags
  * @property {string} note
  * @property {integer} dueDateMS
+ * @property {integer} deferDateMS
+ * @property {boolean} flagged
+ * @property {ChildTask[]} children
+ */
+
+/**
+ * @typedef {Object} ChildTask
+ * @property {string} name
+ * @property {boolean} completed
  */
 
 
@@ -26,7 +35,7 @@ function addNewTask(
 
     // @ts-ignore
     const ofApp = Application("OmniFocus")
-    const ofDoc = ofApp.defaultDocument
+    const ofDoc = ofDocument(ofApp)
 
     // https://discourse.omnigroup.com/t/automatically-flag-tasks-in-specific-projects-contexts-according-to-due-defer-date/32093/28
     const tagFoundOrCreated = charTag => {
@@ -54,10 +63,17 @@ function addNewTask(
         dueDate = new Date(t.dueDateMS)
     }
 
+    var deferDate = null
+    if (t.deferDateMS) {
+        deferDate = new Date(t.deferDateMS)
+    }
+
     var task = ofApp.Task({
         "name": t.name,
         "note": t.note,
         "dueDate": dueDate,
+        "deferDate": deferDate,
+        "flagged": t.flagged === true,
     })
     // ofDoc.inboxTasks.push(task)
     project.tasks.unshift(task)
@@ -66,6 +82,20 @@ function addNewTask(
             to: task.tags
         })
     })
+    // Children are untagged, so they're never mistaken for tasks the app
+    // manages. Completing them mustn't complete the task, which would only
+    // be added again on the next sync.
+    if ((t.children || []).length > 0) {
+        task.completedByChildren = false
+    }
+    ;(t.children || []).forEach((c) => {
+        const child = ofApp.Task({ "name": c.name })
+        task.tasks.push(child)
+        if (c.completed === true) {
+            // @ts-ignore
+            ofApp.markComplete(child)
+        }
+    })
 
 
     return { "id": task.id(), "name": task.name() };
@@ -0,0 +1,31 @@
+// Append a line to a task's note in OmniFocus
+// Accepts a TaskNote as JSON in an OSA_ARGS env var
+// Call it:
+//   set -gx OSA_ARGS '{"id": "a2g4XFUiQKm", "line": "Completed by github2omnifocus"}'
+//   cat ofdocument.js ofappendtasknote.js | osascript -l JavaScript - | jq .
+
+/**
+ * @typedef {Object} TaskNote
+ * @property {string} id
+ * @property {string} line
+ */
+
+function appendTaskNote(
+    /** @type {TaskNote} */ n
+) {
+    // @ts-ignore
+    const ofApp = Application("OmniFocus")
+    const task = ofDocument(ofApp).flattenedTasks.whose({ id: n.id })[0]
+    if (task) {
+        const note = task.note()
+        task.note = note ? note + "\n" + n.line : n.line
+        return true
+    }
+    return false
+}
+
+
+ObjC.import('stdlib')
+var args = JSON.parse($.getenv('OSA_ARGS'))
+var out = appendTaskNote(args)
+JSON.stringify(out)
@@ -0,0 +1,21 @@
+// Shared by the other scripts, which it's put in front of when they're run.
+// Run one of them using:
+//   cat ofdocument.js ofmarktaskcomplete.js | osascript -l JavaScript -
+
+/**
+ * Returns the Omnifocus document named by the OSA_DOCUMENT env var, or the
+ * default document if it's empty or unset.
+ */
+function ofDocument(ofApp) {
+    ObjC.import('stdlib')
+    const name = $.getenv('OSA_DOCUMENT')
+    if (!name) {
+        return ofApp.defaultDocument
+    }
+    const doc = ofApp.documents.byName(name)
+    if (!doc.exists()) {
+        throw new Error("Omnifocus document not found: " + name)
+    }
+    return doc
+}
+
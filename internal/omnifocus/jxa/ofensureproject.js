@@ -0,0 +1,64 @@
+// Ensure a project exists within Omnifocus
+// Accepts a Project as JSON in an OSA_ARGS env var.
+// Call it:
+//   set -gx OSA_ARGS '{"name":"acme/tools#5 Epic", "folder": "GitHub Tracking", "reviewIntervalDays": 7, "type": "sequential"}'
+//   cat ofdocument.js ofensureproject.js | osascript -l JavaScript - | jq .
+// Returns nothing.
+
+/**
+ * @typedef {Object} Project
+ * @property {string} name
+ * @property {string} folder
+ * @property {number} reviewIntervalDays
+ * @property {number} nextReviewDateMS
+ * @property {string} type
+ * @property {boolean} retype
+ */
+
+// typeProps returns the properties that give a project the type t:
+// "parallel", "sequential" or "single actions".
+function typeProps(t) {
+    return { sequential: t === "sequential", singletonActionHolder: t === "single actions" }
+}
+
+function ensureProject(/** @type {Project} */ p) {
+    const ofApp = Application("OmniFocus")
+    const ofDoc = ofDocument(ofApp)
+    const existing = ofDoc.flattenedProjects.whose({ name: p.name })
+    if (existing.length > 0) {
+        if (p.type && p.retype) {
+            const props = typeProps(p.type)
+            existing[0].sequential = props.sequential
+            existing[0].singletonActionHolder = props.singletonActionHolder
+        }
+        return
+    }
+
+    let container = ofDoc
+    if (p.folder) {
+        const folders = ofDoc.flattenedFolders.whose({ name: p.folder })
+        if (folders.length === 0) {
+            const folder = ofApp.Folder({ name: p.folder })
+            ofDoc.folders.push(folder)
+            container = folder
+        } else {
+            container = folders()[0]
+        }
+    }
+    let props = { name: p.name }
+    if (p.type) {
+        props = Object.assign(props, typeProps(p.type))
+    }
+    if (p.reviewIntervalDays) {
+        props.reviewInterval = { unit: "day", steps: p.reviewIntervalDays, fixed: false }
+    }
+    if (p.nextReviewDateMS) {
+        props.nextReviewDate = new Date(p.nextReviewDateMS)
+    }
+    container.projects.push(ofApp.Project(props))
+}
+
+ObjC.import('stdlib')
+var args = JSON.parse($.getenv('OSA_ARGS'))
+var out = ensureProject(args)
+JSON.stringify(out)
@@ -2,7 +2,7 @@
 // Accepts a Tag as JSON in an OSA_ARGS env var.
 // Call it:
 //   set -gx OSA_ARGS '{"name":"github"}'
-//   osascript -l JavaScript ofensuretagexists.js | jq .
+//   cat ofdocument.js ofensuretagexists.js | osascript -l JavaScript - | jq .
 // Returns nothing.
 
 /**
@@ -12,7 +12,7 @@
 
 function ensureTagExists(/** @type {Tag} */ tag) {
     const ofApp = Application("OmniFocus")
-    const ofDoc = ofApp.defaultDocument
+    const ofDoc = ofDocument(ofApp)
     const tagFoundOrCreated = charTag => {
         const
             tags = ofDoc.flattenedTags.whose({
@@ -2,7 +2,7 @@
 // Accepts a Task as JSON in an OSA_ARGS env var
 // Call it:
 //   set -gx OSA_ARGS '{"id": "a2g4XFUiQKm"}'
-//   osascript -l JavaScript ofmarktaskcomplete.js | jq .
+//   cat ofdocument.js ofmarktaskcomplete.js | osascript -l JavaScript - | jq .
 
 /**
  * @typedef {Object} OmnifocusTask
@@ -15,7 +15,7 @@ function markTaskComplete(
 ) {
     // @ts-ignore
     const ofApp = Application("OmniFocus")
-    const task = ofApp.defaultDocument.flattenedTasks.whose({ id: t.id })[0]
+    const task = ofDocument(ofApp).flattenedTasks.whose({ id: t.id })[0]
     if (task) {
         // @ts-ignore
         ofApp.markComplete(task)
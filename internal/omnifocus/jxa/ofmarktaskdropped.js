@@ -0,0 +1,31 @@
+// Mark a task dropped in OmniFocus
+// Accepts a Task as JSON in an OSA_ARGS env var
+// Call it:
+//   set -gx OSA_ARGS '{"id": "a2g4XFUiQKm"}'
+//   cat ofdocument.js ofmarktaskdropped.js | osascript -l JavaScript - | jq .
+
+/**
+ * @typedef {Object} OmnifocusTask
+ * @property {string} id
+ * @property {string} name // not used, but here to mirror type on Go side.
+ */
+
+function markTaskDropped(
+    /** @type {OmnifocusTask} */ t
+) {
+    // @ts-ignore
+    const ofApp = Application("OmniFocus")
+    const task = ofDocument(ofApp).flattenedTasks.whose({ id: t.id })[0]
+    if (task) {
+        // @ts-ignore
+        ofApp.markDropped(task)
+        return true
+    }
+    return false
+}
+
+
+ObjC.import('stdlib')
+var args = JSON.parse($.getenv('OSA_ARGS'))
+var out = markTaskDropped(args)
+JSON.stringify(out)
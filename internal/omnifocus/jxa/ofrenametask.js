@@ -0,0 +1,30 @@
+// Rename a task in OmniFocus
+// Accepts a TaskName as JSON in an OSA_ARGS env var
+// Call it:
+//   set -gx OSA_ARGS '{"id": "a2g4XFUiQKm", "name": "acme/tools#12 Fix the build"}'
+//   cat ofdocument.js ofrenametask.js | osascript -l JavaScript - | jq .
+
+/**
+ * @typedef {Object} TaskName
+ * @property {string} id
+ * @property {string} name
+ */
+
+function renameTask(
+    /** @type {TaskName} */ n
+) {
+    // @ts-ignore
+    const ofApp = Application("OmniFocus")
+    const task = ofDocument(ofApp).flattenedTasks.whose({ id: n.id })[0]
+    if (task) {
+        task.name = n.name
+        return true
+    }
+    return false
+}
+
+
+ObjC.import('stdlib')
+var args = JSON.parse($.getenv('OSA_ARGS'))
+var out = renameTask(args)
+JSON.stringify(out)
@@ -0,0 +1,36 @@
+// Report whether Omnifocus is running, optionally launching it first
+// Accepts an AppStatus as JSON in an OSA_ARGS env var.
+// Call it:
+//   set -gx OSA_ARGS '{"launch": true}'
+//   cat ofdocument.js ofrunning.js | osascript -l JavaScript - | jq .
+// Returns JSON:
+// {
+//     "running": true
+// }
+
+/**
+ * @typedef {Object} AppStatus
+ * @property {boolean} launch
+ */
+
+function running(/** @type {AppStatus} */ status) {
+    // Referring to the app doesn't launch it, calling most methods does.
+    // @ts-ignore
+    const ofApp = Application("OmniFocus")
+    if (!ofApp.running() && status.launch) {
+        ofApp.launch()
+        // Give it a while to open its database.
+        for (var i = 0; i < 30 && !ofApp.running(); i++) {
+            delay(1)
+        }
+        if (ofApp.running()) {
+            ofDocument(ofApp).name()
+        }
+    }
+    return { "running": ofApp.running() }
+}
+
+ObjC.import('stdlib')
+var args = JSON.parse($.getenv('OSA_ARGS'))
+var out = running(args)
+JSON.stringify(out)
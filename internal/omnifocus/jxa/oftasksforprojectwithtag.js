@@ -1,8 +1,11 @@
 // Return the tasks for a project having a given tag
-// Accepts a TaskQuery as JSON in an OSA_ARGS env var.
+// Accepts a TaskQuery as JSON in an OSA_ARGS env var. With a folderName,
+// tasks in any project within that folder are returned, and with neither,
+// tasks in any project. A project or folder that doesn't exist has no
+// tasks.
 // Call it:
 //   set -gx OSA_ARGS '{"projectName": "GitHub Notifications", "tags": ["github"]}'
-//   osascript -l JavaScript oftasksforprojectwithtag.js | jq .
+//   cat ofdocument.js oftasksforprojectwithtag.js | osascript -l JavaScript - | jq .
 // Returns JSON array:
 // [
 //     {
@@ -14,6 +17,7 @@
 /**
  * @typedef {Object} TaskQuery
  * @property {string} projectName
+ * @property {string} folderName
  * @property {string[]} tags
  */
 
@@ -22,9 +26,7 @@ function tasksForProjectWithTag(
 ) {
     // @ts-ignore
     const ofApp = Application("OmniFocus")
-    const ofDoc = ofApp.defaultDocument
-    const project = ofDoc.flattenedProjects
-        .whose({ name: query.projectName })[0];
+    const ofDoc = ofDocument(ofApp)
 
     const tagFoundOrCreated = charTag => {
         const
@@ -51,14 +53,29 @@ function tasksForProjectWithTag(
 
     // tasks is an array each task then has child tasks
 
-    let tasks = project.tasks()
-    project.tasks().forEach(task =>{
-        tasks = tasks.concat(getChildTasks(task))
-    })
+    let tasks = []
+    if (query.projectName) {
+        const projects = ofDoc.flattenedProjects
+            .whose({ name: query.projectName });
+        if (projects.length > 0) {
+            tasks = getProjectTasks(projects[0])
+        }
+    } else if (query.folderName) {
+        const folders = ofDoc.flattenedFolders
+            .whose({ name: query.folderName });
+        if (folders.length > 0) {
+            folders[0].flattenedProjects().forEach(project => {
+                tasks = tasks.concat(getProjectTasks(project))
+            })
+        }
+    } else {
+        tasks = ofDoc.flattenedTasks()
+    }
 
     return tasks
         // removing this filter will give us the completed tasks, so we can then mark them as done in GH(E)
         .filter((task) => task.completed() === false)
+        .filter((task) => task.dropped() === false)
         .filter((task) => {
             // Task must have all tags
             const tags = task.tags()
@@ -70,10 +87,18 @@ function tasksForProjectWithTag(
             return true
         })
         .map((task) => {
-            return { "id": task.id(), "name": task.name(), "completed": task.completed(), "tags": task.tags().map(tag => tag.name()) };
+            return { "id": task.id(), "name": task.name(), "completed": task.completed(), "tags": task.tags().map(tag => tag.name()), "note": task.note(), "children": task.tasks().map(c => ({ "name": c.name(), "completed": c.completed() })) };
         });
 }
 
+function getProjectTasks(project) {
+    let tasks = project.tasks()
+    project.tasks().forEach(task => {
+        tasks = tasks.concat(getChildTasks(task))
+    })
+    return tasks
+}
+
 function getChildTasks(parent) {
     let all_tasks = parent.tasks()
     parent.tasks().forEach(t => {
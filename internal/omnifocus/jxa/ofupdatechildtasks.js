@@ -0,0 +1,58 @@
+// Add and complete child tasks of several tasks in OmniFocus
+// Accepts an array of ChildUpdates as JSON in an OSA_ARGS env var
+// Call it:
+//   set -gx OSA_ARGS '[{"id": "a2g4XFUiQKm", "add": [{"name": "Waiting on @octocat"}], "complete": ["Waiting on @hubot"]}]'
+//   cat ofdocument.js ofupdatechildtasks.js | osascript -l JavaScript - | jq .
+// Returns the number of tasks updated.
+
+/**
+ * @typedef {Object} ChildTask
+ * @property {string} name
+ * @property {boolean} completed
+ */
+
+/**
+ * @typedef {Object} ChildUpdate
+ * @property {string} id
+ * @property {ChildTask[]} add
+ * @property {string[]} complete
+ */
+
+function updateChildTasks(
+    /** @type {ChildUpdate[]} */ updates
+) {
+    // @ts-ignore
+    const ofApp = Application("OmniFocus")
+    const ofDoc = ofDocument(ofApp)
+    let updated = 0
+    updates.forEach(u => {
+        const task = ofDoc.flattenedTasks.whose({ id: u.id })[0]
+        if (!task) {
+            return
+        }
+        // Completing the last child mustn't complete the task.
+        task.completedByChildren = false
+        task.tasks().forEach(child => {
+            if (child.completed() === false && u.complete.includes(child.name())) {
+                // @ts-ignore
+                ofApp.markComplete(child)
+            }
+        })
+        u.add.forEach(c => {
+            const child = ofApp.Task({ "name": c.name })
+            task.tasks.push(child)
+            if (c.completed === true) {
+                // @ts-ignore
+                ofApp.markComplete(child)
+            }
+        })
+        updated++
+    })
+    return updated
+}
+
+
+ObjC.import('stdlib')
+var args = JSON.parse($.getenv('OSA_ARGS'))
+var out = updateChildTasks(args)
+JSON.stringify(out)
@@ -0,0 +1,35 @@
+// Set the notes of several tasks in OmniFocus
+// Accepts an array of NoteUpdates as JSON in an OSA_ARGS env var
+// Call it:
+//   set -gx OSA_ARGS '[{"id": "a2g4XFUiQKm", "note": "https://github.com/..."}]'
+//   cat ofdocument.js ofupdatetasknotes.js | osascript -l JavaScript - | jq .
+// Returns the number of tasks updated.
+
+/**
+ * @typedef {Object} NoteUpdate
+ * @property {string} id
+ * @property {string} note
+ */
+
+function updateTaskNotes(
+    /** @type {NoteUpdate[]} */ updates
+) {
+    // @ts-ignore
+    const ofApp = Application("OmniFocus")
+    const ofDoc = ofDocument(ofApp)
+    let updated = 0
+    updates.forEach(u => {
+        const task = ofDoc.flattenedTasks.whose({ id: u.id })[0]
+        if (task) {
+            task.note = u.note
+            updated++
+        }
+    })
+    return updated
+}
+
+
+ObjC.import('stdlib')
+var args = JSON.parse($.getenv('OSA_ARGS'))
+var out = updateTaskNotes(args)
+JSON.stringify(out)
@@ -0,0 +1,44 @@
+// Set the flag and dates of several tasks in OmniFocus
+// Accepts an array of ScheduleUpdates as JSON in an OSA_ARGS env var. A
+// date of 0 is left as it is.
+// Call it:
+//   set -gx OSA_ARGS '[{"id": "a2g4XFUiQKm", "flagged": true, "dueDateMS": 1717261200000, "deferDateMS": 0}]'
+//   cat ofdocument.js ofupdatetaskschedules.js | osascript -l JavaScript - | jq .
+// Returns the number of tasks updated.
+
+/**
+ * @typedef {Object} ScheduleUpdate
+ * @property {string} id
+ * @property {boolean} flagged
+ * @property {number} dueDateMS
+ * @property {number} deferDateMS
+ */
+
+function updateTaskSchedules(
+    /** @type {ScheduleUpdate[]} */ updates
+) {
+    // @ts-ignore
+    const ofApp = Application("OmniFocus")
+    const ofDoc = ofDocument(ofApp)
+    let updated = 0
+    updates.forEach(u => {
+        const task = ofDoc.flattenedTasks.whose({ id: u.id })[0]
+        if (task) {
+            task.flagged = u.flagged === true
+            if (u.dueDateMS) {
+                task.dueDate = new Date(u.dueDateMS)
+            }
+            if (u.deferDateMS) {
+                task.deferDate = new Date(u.deferDateMS)
+            }
+            updated++
+        }
+    })
+    return updated
+}
+
+
+ObjC.import('stdlib')
+var args = JSON.parse($.getenv('OSA_ARGS'))
+var out = updateTaskSchedules(args)
+JSON.stringify(out)
@@ -0,0 +1,28 @@
+package omnifocus
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestJXAScriptsUseDocument(t *testing.T) {
+	entries, err := jxa.ReadDir("jxa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prelude, _ := jxa.ReadFile("jxa/ofdocument.js")
+	for _, e := range entries {
+		// ofinbox.js is only run by hand.
+		if e.Name() == "ofdocument.js" || e.Name() == "ofinbox.js" {
+			continue
+		}
+		code := jxaScript(e.Name())
+		if !bytes.HasPrefix(code, prelude) {
+			t.Fatalf("Expected %s to be run after ofdocument.js", e.Name())
+		}
+		if strings.Contains(string(code[len(prelude):]), "defaultDocument") {
+			t.Fatalf("Expected %s to use ofDocument rather than the default document", e.Name())
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package omnifocus
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
+
+// Lines that delimit the generated section of a task's note. Anything
+// outside the section is the user's, and kept when the section is
+// regenerated.
+const (
+	generatedStart = "--- github2omnifocus ---"
+	generatedEnd   = "--- end github2omnifocus ---"
+)
+
+// NoteUpdate sets the note of the task with ID.
+type NoteUpdate struct {
+	ID   string `json:"id"`
+	Note string `json:"note"`
+}
+
+// SyncedTask is a task along with the GitHub item it's synced from.
+type SyncedTask struct {
+	Item gh.GitHubItem
+	Task Task
+}
+
+// generated returns the generated section of the note for t's task: the
+// item's URL, who's involved and, for PRs, its size. Without NoteMetadata,
+// it isn't delimited as it's never regenerated.
+func (og *Gateway) generated(t gh.GitHubItem, synced time.Time) string {
+	lines := []string{t.HTMLURL}
+	if t.Author != "" {
+		lines = append(lines, "author: @"+t.Author)
+	}
+	if len(t.Assignees) > 0 {
+		lines = append(lines, "assignees: @"+strings.Join(t.Assignees, ", @"))
+	}
+	if t.ChangedFiles > 0 {
+		lines = append(lines, fmt.Sprintf("size: +%d -%d in %d files", t.Additions, t.Deletions, t.ChangedFiles))
+	}
+	if !og.NoteMetadata {
+		return strings.Join(lines, "\n")
+	}
+	lines = append([]string{generatedStart}, lines...)
+	lines = append(lines,
+		"account: "+og.Account,
+		"api: "+og.APIURL,
+		"synced: "+synced.UTC().Format(time.RFC3339),
+		generatedEnd,
+	)
+	return strings.Join(lines, "\n")
+}
+
+// withGenerated replaces the generated section of note with section,
+// keeping the rest of the note. url is the item's URL, which was the whole
+// generated note before sections existed.
+func withGenerated(note, url, section string) string {
+	start := strings.Index(note, generatedStart)
+	if start == -1 {
+		if note == url || strings.HasPrefix(note, url+"\n") {
+			return section + note[len(url):]
+		}
+		if note == "" {
+			return section
+		}
+		return section + "\n\n" + note
+	}
+
+	before := note[:start]
+	if strings.TrimSpace(before) == url {
+		before = ""
+	}
+	after := ""
+	if end := strings.Index(note[start:], generatedEnd); end != -1 {
+		after = note[start+end+len(generatedEnd):]
+	}
+	// Otherwise the end line has been lost, so treat everything after the
+	// start as the old section.
+	return before + section + after
+}
+
+// syncedLine matches the line of the generated section saying when it was
+// last changed.
+var syncedLine = regexp.MustCompile(`(?m)^synced: .*$`)
+
+// note returns the note for a new task for t.
+func (og *Gateway) note(t gh.GitHubItem) string {
+	return og.generated(t, time.Now())
+}
+
+// UpdateNotes regenerates the generated section of the notes of tasks
+// which are up to date with GitHub, leaving the rest of each note alone.
+// A note is only written when more than its synced time would change, so
+// that each sync doesn't rewrite every note.
+func (og *Gateway) UpdateNotes(synced []SyncedTask) error {
+	now := time.Now()
+	updates := []NoteUpdate{}
+	for _, s := range synced {
+		note := withGenerated(s.Task.Note, s.Item.HTMLURL, og.generated(s.Item, now))
+		if syncedLine.ReplaceAllString(note, "") != syncedLine.ReplaceAllString(s.Task.Note, "") {
+			updates = append(updates, NoteUpdate{ID: s.Task.ID, Note: note})
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	err := og.backend().UpdateNotes(updates)
+	if err != nil {
+		return fmt.Errorf("error updating task notes: %w", err)
+	}
+	return nil
+}
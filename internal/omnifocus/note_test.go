@@ -0,0 +1,106 @@
+package omnifocus
+
+import (
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
+
+func TestWithGenerated(t *testing.T) {
+	url := "https://github.com/acme/tools/issues/1"
+	section := generatedStart + "\n" + url + "\nsynced: 2\n" + generatedEnd
+	old := generatedStart + "\n" + url + "\nsynced: 1\n" + generatedEnd
+	cases := []struct {
+		note     string
+		expected string
+	}{
+		{"", section},
+		{url, section},
+		{url + "\nmy notes", section + "\nmy notes"},
+		{"my notes", section + "\n\nmy notes"},
+		{old + "\nmy notes", section + "\nmy notes"},
+		{"my notes\n" + old, "my notes\n" + section},
+		// Notes from before the URL was in the section.
+		{url + "\n\n" + generatedStart + "\nsynced: 1\n" + generatedEnd, section},
+		{url + "\n\n" + generatedStart + "\nsynced: 1", section},
+	}
+	for _, c := range cases {
+		if n := withGenerated(c.note, url, section); n != c.expected {
+			t.Fatalf("For %q expected %q, got %q", c.note, c.expected, n)
+		}
+	}
+}
+
+func TestUpdateNotes(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{
+		AppTag:          "github",
+		AssignedTag:     "assigned",
+		AssignedProject: "GitHub Assigned",
+		Backend:         fake,
+		NoteMetadata:    true,
+		Account:         "work",
+		APIURL:          "https://github.example.com/api/v3",
+	}
+	item := gh.GitHubItem{K: "acme/tools#1", Title: "Fix me", Repo: "acme/tools", HTMLURL: "https://github.example.com/acme/tools/issues/1"}
+	err = og.AddIssue(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := fake.Tasks[0].Note
+	if !strings.HasPrefix(note, generatedStart+"\nhttps://github.example.com/acme/tools/issues/1\naccount: work\napi: https://github.example.com/api/v3\n") {
+		t.Fatalf("Didn't get expected note, got: %q", note)
+	}
+
+	// Notes added by hand are kept when the section is regenerated.
+	fake.Tasks[0].Note = "before\n" + note + "\nafter"
+	issues, err := og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = og.UpdateNotes([]SyncedTask{{Item: item, Task: issues[0]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := fake.Tasks[0].Note
+	if !strings.HasPrefix(updated, "before\n"+generatedStart) || !strings.HasSuffix(updated, generatedEnd+"\nafter") {
+		t.Fatalf("Expected hand written notes to be kept, got: %q", updated)
+	}
+
+	// Only the synced time would change, so the note isn't written.
+	issues, err = og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.Operations = nil
+	err = og.UpdateNotes([]SyncedTask{{Item: item, Task: issues[0]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Operations) != 0 {
+		t.Fatalf("Expected the unchanged note to be left alone, got: %v", fake.Operations)
+	}
+	item.Assignees = []string{"octocat"}
+	err = og.UpdateNotes([]SyncedTask{{Item: item, Task: issues[0]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(fake.Tasks[0].Note, "assignees: @octocat") {
+		t.Fatalf("Expected the note to be updated for the new assignee, got: %q", fake.Tasks[0].Note)
+	}
+}
+
+func TestGeneratedPeople(t *testing.T) {
+	og := Gateway{}
+	note := og.generated(gh.GitHubItem{HTMLURL: "https://github.com/acme/tools/pull/1", Author: "alice", Assignees: []string{"bob", "carol"}}, time.Now())
+	expected := "https://github.com/acme/tools/pull/1\nauthor: @alice\nassignees: @bob, @carol"
+	if note != expected {
+		t.Fatalf("Expected %q, got %q", expected, note)
+	}
+}
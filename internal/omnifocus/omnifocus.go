@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"iter"
 	"log"
+	"maps"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+	"github.com/rhyshort/github-to-omnifocus/internal/state"
 )
 
 var (
@@ -29,6 +32,9 @@ type Task struct {
 	Name      string   `json:"name"`
 	Completed bool     `json:"completed"`
 	Tags      []string `json:"tags"`
+	Note      string   `json:"note"`
+	// Children are the task's child tasks.
+	Children []ChildTask `json:"children"`
 }
 
 func (t Task) String() string {
@@ -46,23 +52,46 @@ func (t Task) Key() string {
 	return strings.SplitN(t.Name, " ", 2)[0] //nolint:gomnd
 }
 
+// GetTitle meets the delta.Titled interface, returning the task's name
+// without the key prefix.
+func (t Task) GetTitle() string {
+	parts := strings.SplitN(t.Name, " ", 2) //nolint:gomnd
+	if len(parts) < 2 {                     //nolint:gomnd
+		return ""
+	}
+	return parts[1]
+}
+
 func (t Task) GetTags() iter.Seq[string] {
 	return slices.Values(t.Tags)
 }
 
 // TaskQuery defines a query to find Omnifocus tasks
 type TaskQuery struct {
-	ProjectName string   `json:"projectName"`
-	Tags        []string `json:"tags"`
+	ProjectName string `json:"projectName"`
+	// FolderName, if set without ProjectName, limits the query to the
+	// projects within the folder of that name.
+	FolderName string   `json:"folderName,omitempty"`
+	Tags       []string `json:"tags"`
 }
 
 // NewOmnifocusTask defines a request to create a new task
 type NewOmnifocusTask struct {
-	ProjectName string   `json:"projectName"`
-	Name        string   `json:"name"`
-	Tags        []string `json:"tags"`
-	Note        string   `json:"note"`
-	DueDateMS   int64    `json:"dueDateMS"`
+	ProjectName string      `json:"projectName"`
+	Name        string      `json:"name"`
+	Tags        []string    `json:"tags"`
+	Note        string      `json:"note"`
+	DueDateMS   int64       `json:"dueDateMS"`
+	DeferDateMS int64       `json:"deferDateMS"`
+	Flagged     bool        `json:"flagged"`
+	Children    []ChildTask `json:"children,omitempty"`
+}
+
+// ChildTask is a sub-task of a Task, or one created along with a
+// NewOmnifocusTask.
+type ChildTask struct {
+	Name      string `json:"name"`
+	Completed bool   `json:"completed,omitempty"`
 }
 
 // Tag represents an Omnifocus tag
@@ -70,6 +99,21 @@ type Tag struct {
 	Name string `json:"name"`
 }
 
+// Project represents an Omnifocus project, optionally in a folder
+type Project struct {
+	Name   string `json:"name"`
+	Folder string `json:"folder,omitempty"`
+	// ReviewIntervalDays, if non-zero, is how often a new project comes up
+	// for review, and NextReviewDateMS, if non-zero, when it first does.
+	ReviewIntervalDays int   `json:"reviewIntervalDays,omitempty"`
+	NextReviewDateMS   int64 `json:"nextReviewDateMS,omitempty"`
+	// Type, if set, is "parallel", "sequential" or "single actions". It's
+	// given to a new project, and with Retype set, applied to the project
+	// even if it already exists.
+	Type   string `json:"type,omitempty"`
+	Retype bool   `json:"retype,omitempty"`
+}
+
 type Gateway struct {
 	AppTag                  string
 	AssignedTag             string
@@ -78,16 +122,135 @@ type Gateway struct {
 	ReviewProject           string
 	NotificationTag         string
 	NotificationsProject    string
+	ReleasesProject         string
+	SecurityProject         string
 	SetNotificationsDueDate bool
 	SetTaskmasterDueDate    bool
 	TaskMasterTaskTag       string
 	DueDate                 time.Time
 	PendingChangesProject   string
 	PendingChangesTag       string
+	FailingChecksProject    string
+	FailingChecksTag        string
+	// TaskMasterMonths, if set, names the months, January first, for
+	// tags setting task master deadlines, as well as the English
+	// abbreviations.
+	TaskMasterMonths []string
+	// WatchedReleasesProject, if set, holds tasks tagged with
+	// WatchedReleasesTag for upgrading to watched repos' latest releases.
+	WatchedReleasesProject string
+	WatchedReleasesTag     string
+	// MentionsTag, if set, tags tasks alongside notifications for issues
+	// and PRs the user is only mentioned in.
+	MentionsTag string
+	// ReviewDueIn, if set, and IssueDueIn, when non-zero, set the due date
+	// of new review and issue tasks relative to when the item was first
+	// seen. ReviewDueIn is given the PR's repo, as it can differ by repo.
+	ReviewDueIn func(repo string) time.Duration
+	IssueDueIn  time.Duration
+	// WaitingDeferIn, when non-zero, defers new tasks for authored PRs
+	// waiting on reviewers until this long after they're added.
+	WaitingDeferIn time.Duration
+	// PriorityField, if set, is the project field whose value in an issue
+	// or PR's ProjectFields sets how urgent its task is. Tasks are flagged
+	// for values in PriorityFlagged, and due PriorityDueIn after the item
+	// was first seen, if that's sooner than they'd otherwise be due.
+	PriorityField   string
+	PriorityFlagged []string
+	PriorityDueIn   map[string]time.Duration
+	// IterationField, if set, is the project iteration field whose value
+	// in an issue or PR's ProjectIterations defers its task until the
+	// iteration starts, and makes it due by the iteration's end if it
+	// wouldn't be due sooner.
+	IterationField string
+	// ReviewChecklist, if set, names child tasks created under each new
+	// review task.
+	ReviewChecklist []string
+	// IssueTaskLists adds a child task under each issue task for each
+	// unchecked item in the task list in the issue's body.
+	IssueTaskLists bool
+	// TrackingTag, if set, enables tasks for the items in tracking issues'
+	// task lists. These go in a project per issue, within TrackingFolder
+	// if it's set.
+	TrackingTag    string
+	TrackingFolder string
+	// ProjectReviewIntervalDays, if non-zero, is how often the projects
+	// the gateway creates come up for review. They're first reviewed
+	// ProjectFirstReviewIn after they're created, if it's non-zero, or
+	// else after the interval.
+	ProjectReviewIntervalDays int
+	ProjectFirstReviewIn      time.Duration
+	// TrackingProjectType, if set, is the type of the projects for tracking
+	// issues, and ProjectTypes the type of other projects by name, as for
+	// Project.Type.
+	TrackingProjectType string
+	ProjectTypes        map[string]string
+	// NotificationsByRepo, if set, puts notification and mention tasks in
+	// a single action list per repo, named after the repo, within a folder
+	// named NotificationsProject. Those for ReleasesProject and
+	// SecurityProject still go there.
+	NotificationsByRepo bool
+	// TagCase, if set, is the case new tasks' tags are put in, other than
+	// the gateway's own tags, as for CaseTag.
+	TagCase string
+	// MaxTitleLength, if non-zero, is the most runes of an item's title
+	// kept in its task's name.
+	MaxTitleLength int
+	// DueDatePattern, if set, is matched against an issue's body and
+	// labels. Its first group is the issue's due date, as YYYY-MM-DD.
+	DueDatePattern *regexp.Regexp
+	// State records when items were first seen, for relative due dates.
+	State *state.Store
+	// Backend carries out operations on Omnifocus, defaulting to
+	// JXABackend.
+	Backend Backend
+	// NoteMetadata adds a block to task notes recording the Account and
+	// APIURL the task was synced from, and when.
+	NoteMetadata bool
+	Account      string
+	APIURL       string
+}
+
+func (og *Gateway) backend() Backend {
+	var b Backend = JXABackend{}
+	if og.Backend != nil {
+		b = og.Backend
+	}
+	if og.TagCase != "" {
+		b = casedBackend{Backend: b, tagCase: og.TagCase, own: og.ownTags()}
+	}
+	return b
+}
+
+// EnsureTags creates the tags the gateway uses to find its tasks if they
+// don't already exist in Omnifocus.
+func (og *Gateway) EnsureTags() error {
+	for _, tag := range og.ownTags() {
+		if tag == "" {
+			continue
+		}
+		err := og.backend().EnsureTagExists(Tag{Name: tag})
+		if err != nil {
+			return fmt.Errorf("error ensuring tag %q exists: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// EnsureProjectTypes sets the type of each project in ProjectTypes,
+// creating it if it doesn't exist.
+func (og *Gateway) EnsureProjectTypes() error {
+	for _, name := range slices.Sorted(maps.Keys(og.ProjectTypes)) {
+		err := og.backend().EnsureProjectExists(og.newProject(Project{Name: name, Type: og.ProjectTypes[name], Retype: true}))
+		if err != nil {
+			return fmt.Errorf("error ensuring project %q exists: %w", name, err)
+		}
+	}
+	return nil
 }
 
 func (og *Gateway) GetIssues() ([]Task, error) {
-	tasks, err := TasksForQuery(TaskQuery{
+	tasks, err := og.backend().TasksForQuery(TaskQuery{
 		ProjectName: og.AssignedProject,
 		Tags:        []string{og.AppTag, og.AssignedTag},
 	})
@@ -98,7 +261,7 @@ func (og *Gateway) GetIssues() ([]Task, error) {
 }
 
 func (og *Gateway) GetPRs() ([]Task, error) {
-	tasks, err := TasksForQuery(TaskQuery{
+	tasks, err := og.backend().TasksForQuery(TaskQuery{
 		ProjectName: og.ReviewProject,
 		Tags:        []string{og.AppTag, og.ReviewTag},
 	})
@@ -109,7 +272,7 @@ func (og *Gateway) GetPRs() ([]Task, error) {
 }
 
 func (og *Gateway) GetAuthoredPRs() ([]Task, error) {
-	tasks, err := TasksForQuery(TaskQuery{
+	tasks, err := og.backend().TasksForQuery(TaskQuery{
 		ProjectName: og.PendingChangesProject,
 		Tags:        []string{og.AppTag, og.PendingChangesTag},
 	})
@@ -119,10 +282,58 @@ func (og *Gateway) GetAuthoredPRs() ([]Task, error) {
 	return tasks, nil
 }
 
-func (og *Gateway) GetNotifications() ([]Task, error) {
-	tasks, err := TasksForQuery(TaskQuery{
-		ProjectName: og.NotificationsProject,
-		Tags:        []string{og.AppTag, og.NotificationTag},
+// GetFailingChecks returns the failing workflow tasks, or none if the
+// category isn't configured.
+func (og *Gateway) GetFailingChecks() ([]Task, error) {
+	if og.FailingChecksProject == "" {
+		return []Task{}, nil
+	}
+	tasks, err := og.backend().TasksForQuery(TaskQuery{
+		ProjectName: og.FailingChecksProject,
+		Tags:        []string{og.AppTag, og.FailingChecksTag},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// GetWatchedReleases returns the tasks for watched repos' releases, or none
+// if the category isn't configured.
+func (og *Gateway) GetWatchedReleases() ([]Task, error) {
+	if og.WatchedReleasesProject == "" {
+		return []Task{}, nil
+	}
+	tasks, err := og.backend().TasksForQuery(TaskQuery{
+		ProjectName: og.WatchedReleasesProject,
+		Tags:        []string{og.AppTag, og.WatchedReleasesTag},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// GetMentions returns the tasks for issues and PRs the user is only
+// mentioned in, or none if the category isn't configured.
+func (og *Gateway) GetMentions() ([]Task, error) {
+	if og.MentionsTag == "" {
+		return []Task{}, nil
+	}
+	return og.notificationTasks(og.MentionsTag, []string{og.NotificationsProject})
+}
+
+// GetTrackedItems returns the tasks for tracking issue task list items,
+// which are spread across a project per issue. Only the projects in
+// TrackingFolder are read if it's set; otherwise the projects are top
+// level, so every project is.
+func (og *Gateway) GetTrackedItems() ([]Task, error) {
+	if og.TrackingTag == "" {
+		return []Task{}, nil
+	}
+	tasks, err := og.backend().TasksForQuery(TaskQuery{
+		FolderName: og.TrackingFolder,
+		Tags:       []string{og.AppTag, og.TrackingTag},
 	})
 	if err != nil {
 		return nil, err
@@ -130,6 +341,91 @@ func (og *Gateway) GetNotifications() ([]Task, error) {
 	return tasks, nil
 }
 
+func (og *Gateway) GetNotifications() ([]Task, error) {
+	return og.notificationTasks(og.NotificationTag, og.notificationProjects())
+}
+
+// notificationTasks returns the tasks tagged tag in projects and, with
+// NotificationsByRepo set, in the per-repo projects within the
+// NotificationsProject folder. Tasks from before NotificationsByRepo was
+// set stay in the NotificationsProject project, if it still exists.
+func (og *Gateway) notificationTasks(tag string, projects []string) ([]Task, error) {
+	queries := []TaskQuery{}
+	for _, p := range projects {
+		queries = append(queries, TaskQuery{ProjectName: p, Tags: []string{og.AppTag, tag}})
+	}
+	if og.NotificationsByRepo {
+		queries = append(queries, TaskQuery{FolderName: og.NotificationsProject, Tags: []string{og.AppTag, tag}})
+	}
+	tasks := []Task{}
+	for _, q := range queries {
+		t, err := og.backend().TasksForQuery(q)
+		if err != nil {
+			return nil, err
+		}
+		// A project may be within the folder too.
+		for _, task := range t {
+			if !slices.ContainsFunc(tasks, func(seen Task) bool { return seen.ID == task.ID }) {
+				tasks = append(tasks, task)
+			}
+		}
+	}
+	return tasks, nil
+}
+
+// notificationProjects returns the distinct projects that notification
+// tasks may be routed to.
+func (og *Gateway) notificationProjects() []string {
+	projects := []string{og.NotificationsProject}
+	for _, p := range []string{og.ReleasesProject, og.SecurityProject} {
+		if p != "" && !slices.Contains(projects, p) {
+			projects = append(projects, p)
+		}
+	}
+	return projects
+}
+
+// NotificationProject returns the project a notification task belongs in.
+func (og *Gateway) NotificationProject(t gh.GitHubItem) string {
+	if t.IsSecurityAlert() && og.SecurityProject != "" {
+		return og.SecurityProject
+	}
+	if t.SubjectType == "Release" && og.ReleasesProject != "" {
+		return og.ReleasesProject
+	}
+	return og.MentionProject(t)
+}
+
+// MentionProject returns the project a mention task belongs in, which is
+// where notifications go unless they're routed elsewhere.
+func (og *Gateway) MentionProject(t gh.GitHubItem) string {
+	if og.NotificationsByRepo && t.Repo != "" {
+		return og.repoProject(t)
+	}
+	return og.NotificationsProject
+}
+
+// repoProject returns the name of the project for t's repo with
+// NotificationsByRepo set, such as "GitHub Notifications : acme/tools",
+// so it doesn't clash with other projects named after the repo.
+func (og *Gateway) repoProject(t gh.GitHubItem) string {
+	return fmt.Sprintf("%s : %s", og.NotificationsProject, t.Repo)
+}
+
+// ensureRepoProject creates project, if it's the project for t's repo
+// with NotificationsByRepo set and doesn't exist yet. A project that does
+// is left as the user has it.
+func (og *Gateway) ensureRepoProject(project string, t gh.GitHubItem) error {
+	if !og.NotificationsByRepo || t.Repo == "" || project != og.repoProject(t) {
+		return nil
+	}
+	err := og.backend().EnsureProjectExists(og.newProject(Project{Name: project, Folder: og.NotificationsProject, Type: "single actions"}))
+	if err != nil {
+		return fmt.Errorf("error ensuring project %q exists: %w", project, err)
+	}
+	return nil
+}
+
 func (og *Gateway) AddIssue(t gh.GitHubItem) error {
 	log.Printf("AddIssue: %s", t)
 	tags := []string{og.AppTag, og.AssignedTag, t.Repo}
@@ -140,12 +436,17 @@ func (og *Gateway) AddIssue(t gh.GitHubItem) error {
 
 	task := NewOmnifocusTask{
 		ProjectName: og.AssignedProject,
-		Name:        t.Key() + " " + t.Title,
+		Name:        og.taskName(t),
 		Tags:        tags,
-		Note:        t.HTMLURL,
+		Note:        og.note(t),
+	}
+	if og.IssueTaskLists {
+		task.Children = taskListChildren(t)
 	}
 
-	if og.SetTaskmasterDueDate {
+	if due, ok := og.dueDateFromItem(t); ok {
+		task.DueDateMS = due.UnixMilli()
+	} else if og.SetTaskmasterDueDate {
 		// Milestones are two week sprints, some tasks are weekly, only tag with milestone due date, if present _and_
 		// doesn't have a specific week tag.
 		if t.Milestone != "" && !slices.ContainsFunc(tags, func(tag string) bool { return strings.HasSuffix(tag, "W") }) {
@@ -163,13 +464,53 @@ func (og *Gateway) AddIssue(t gh.GitHubItem) error {
 		}
 	}
 
-	_, err := AddNewOmnifocusTask(task)
+	if task.DueDateMS == 0 && og.IssueDueIn > 0 {
+		task.DueDateMS = og.State.FirstSeenAt(t.Key(), time.Now()).Add(og.IssueDueIn).UnixMilli()
+	}
+	og.prioritise(&task, t)
+	og.scheduleForIteration(&task, t)
+
+	_, err := og.backend().AddTask(task)
 	if err != nil {
-		return fmt.Errorf("error adding task: %v", err)
+		return fmt.Errorf("error adding task: %w", err)
 	}
+	og.recordSchedule(t)
 	return nil
 }
 
+// prioritise flags task and brings its due date forward according to the
+// value of the item's PriorityField.
+func (og *Gateway) prioritise(task *NewOmnifocusTask, t gh.GitHubItem) {
+	priority, ok := t.ProjectFields[og.PriorityField]
+	if og.PriorityField == "" || !ok {
+		return
+	}
+	if slices.Contains(og.PriorityFlagged, priority) {
+		task.Flagged = true
+	}
+	if dueIn, ok := og.PriorityDueIn[priority]; ok {
+		due := og.State.FirstSeenAt(t.Key(), time.Now()).Add(dueIn).UnixMilli()
+		if task.DueDateMS <= 0 || due < task.DueDateMS {
+			task.DueDateMS = due
+		}
+	}
+}
+
+// scheduleForIteration sets task's defer and due dates from the item's
+// IterationField. It's due at the usual 5pm on the iteration's last day.
+func (og *Gateway) scheduleForIteration(task *NewOmnifocusTask, t gh.GitHubItem) {
+	it, ok := t.ProjectIterations[og.IterationField]
+	if og.IterationField == "" || !ok {
+		return
+	}
+	task.DeferDateMS = it.Start.UnixMilli()
+	last := it.End().AddDate(0, 0, -1)
+	due := time.Date(last.Year(), last.Month(), last.Day(), 17, 0, 0, 0, last.Location()).UnixMilli() //nolint:gomnd
+	if task.DueDateMS <= 0 || due < task.DueDateMS {
+		task.DueDateMS = due
+	}
+}
+
 func (og *Gateway) isTaskMasterTask(task NewOmnifocusTask) bool {
 	for _, tag := range task.Tags {
 		if strings.EqualFold(tag, og.TaskMasterTaskTag) {
@@ -179,6 +520,27 @@ func (og *Gateway) isTaskMasterTask(task NewOmnifocusTask) bool {
 	return false
 }
 
+// dueDateFromItem returns the date matched by DueDatePattern in the item's
+// labels or body, at the usual 5pm local time on that day.
+func (og *Gateway) dueDateFromItem(t gh.GitHubItem) (time.Time, bool) {
+	if og.DueDatePattern == nil {
+		return time.Time{}, false
+	}
+	for _, s := range append(slices.Clone(t.Labels), t.Body) {
+		m := og.DueDatePattern.FindStringSubmatch(s)
+		if len(m) < 2 {
+			continue
+		}
+		due, err := time.ParseInLocation(time.DateOnly, m[1], time.Local)
+		if err != nil {
+			log.Printf("Ignoring due date %q for %s: %v", m[1], t.Key(), err)
+			continue
+		}
+		return time.Date(due.Year(), due.Month(), due.Day(), 17, 0, 0, 0, time.Local), true //nolint:gomnd
+	}
+	return time.Time{}, false
+}
+
 func (og *Gateway) deadlineFromMilestone(milestone string) (int64, error) {
 	if strings.Contains(milestone, "->") {
 		end := strings.Split(milestone, "->")[1]
@@ -218,9 +580,11 @@ func (og *Gateway) deadline(tags []string) (int64, error) {
 	}
 
 	isMonth := func(tag string) (bool, time.Month) {
-		for idx, month := range arrowMonthAbbrv {
-			if strings.EqualFold(month, tag) {
-				return true, time.Month(idx)
+		for _, months := range [][]string{arrowMonthAbbrv, og.TaskMasterMonths} {
+			for idx, month := range months {
+				if strings.EqualFold(month, tag) {
+					return true, time.Month(idx + 1)
+				}
 			}
 		}
 		return false, time.January
@@ -244,15 +608,27 @@ func (og *Gateway) AddPR(t gh.GitHubItem) error {
 	tags := []string{og.AppTag, og.ReviewTag}
 	tags = append(tags, t.Labels...)
 	tags = slices.AppendSeq(tags, t.GetTags())
-	_, err := AddNewOmnifocusTask(NewOmnifocusTask{
+	task := NewOmnifocusTask{
 		ProjectName: og.ReviewProject,
-		Name:        t.Key() + " " + t.Title,
+		Name:        og.taskName(t),
 		Tags:        tags,
-		Note:        t.HTMLURL,
-	})
+		Note:        og.note(t),
+	}
+	if og.ReviewDueIn != nil {
+		if dueIn := og.ReviewDueIn(t.Repo); dueIn > 0 {
+			task.DueDateMS = og.State.FirstSeenAt(t.Key(), time.Now()).Add(dueIn).UnixMilli()
+		}
+	}
+	og.prioritise(&task, t)
+	og.scheduleForIteration(&task, t)
+	for _, step := range og.ReviewChecklist {
+		task.Children = append(task.Children, ChildTask{Name: step})
+	}
+	_, err := og.backend().AddTask(task)
 	if err != nil {
-		return fmt.Errorf("error adding task: %v", err)
+		return fmt.Errorf("error adding task: %w", err)
 	}
+	og.recordSchedule(t)
 	return nil
 }
 
@@ -261,56 +637,274 @@ func (og *Gateway) AddAuthoredPR(t gh.GitHubItem) error {
 	tags := []string{og.AppTag, og.PendingChangesTag}
 	tags = append(tags, t.Labels...)
 	tags = slices.AppendSeq(tags, t.GetTags())
-	_, err := AddNewOmnifocusTask(NewOmnifocusTask{
+	task := NewOmnifocusTask{
 		ProjectName: og.PendingChangesProject,
 		Tags:        tags,
-		Name:        t.Key() + " " + t.Title,
-		Note:        t.HTMLURL,
-	})
+		Name:        og.taskName(t),
+		Note:        og.note(t),
+		Children:    reviewerChildren(t),
+	}
+	if og.WaitingDeferIn > 0 && t.WaitingOn == gh.WaitingOnReviewers {
+		task.DeferDateMS = time.Now().Add(og.WaitingDeferIn).UnixMilli()
+	}
+	_, err := og.backend().AddTask(task)
 	return err
 }
 
+func (og *Gateway) AddFailingCheck(t gh.GitHubItem) error {
+	log.Printf("AddFailingCheck: %s", t)
+	_, err := og.backend().AddTask(NewOmnifocusTask{
+		ProjectName: og.FailingChecksProject,
+		Name:        og.taskName(t),
+		Tags:        []string{og.AppTag, og.FailingChecksTag, t.Repo},
+		Note:        og.note(t),
+		DueDateMS:   og.DueDate.UnixMilli(),
+	})
+	if err != nil {
+		return fmt.Errorf("error adding task: %w", err)
+	}
+	return nil
+}
+
+func (og *Gateway) AddWatchedRelease(t gh.GitHubItem) error {
+	log.Printf("AddWatchedRelease: %s", t)
+	_, err := og.backend().AddTask(NewOmnifocusTask{
+		ProjectName: og.WatchedReleasesProject,
+		Name:        og.taskName(t),
+		Tags:        []string{og.AppTag, og.WatchedReleasesTag, t.Repo},
+		Note:        og.note(t),
+	})
+	if err != nil {
+		return fmt.Errorf("error adding task: %w", err)
+	}
+	return nil
+}
+
+func (og *Gateway) AddMention(t gh.GitHubItem) error {
+	log.Printf("AddMention: %s", t)
+	tags := []string{og.AppTag, og.MentionsTag, t.Repo}
+	project := og.MentionProject(t)
+	err := og.ensureRepoProject(project, t)
+	if err != nil {
+		return err
+	}
+	_, err = og.backend().AddTask(NewOmnifocusTask{
+		ProjectName: project,
+		Name:        og.taskName(t),
+		Tags:        slices.AppendSeq(tags, t.GetTags()),
+		Note:        og.note(t),
+	})
+	if err != nil {
+		return fmt.Errorf("error adding task: %w", err)
+	}
+	return nil
+}
+
 func (og *Gateway) AddNotification(t gh.GitHubItem) error {
 	log.Printf("AddNotification: %s", t)
+	project := og.NotificationProject(t)
+	err := og.ensureRepoProject(project, t)
+	if err != nil {
+		return err
+	}
 	newT := NewOmnifocusTask{
-		ProjectName: og.NotificationsProject,
-		Name:        t.Key() + " " + t.Title,
+		ProjectName: project,
+		Name:        og.taskName(t),
 		Tags:        []string{og.AppTag, og.NotificationTag, t.Repo},
-		Note:        t.HTMLURL,
+		Note:        og.note(t),
 	}
 	if og.SetNotificationsDueDate {
 		newT.DueDateMS = og.DueDate.UnixMilli()
 	}
-	_, err := AddNewOmnifocusTask(newT)
+	if t.IsSecurityAlert() {
+		// These are easily lost among other notifications, so always make
+		// them stand out.
+		newT.Flagged = true
+		newT.DueDateMS = og.DueDate.UnixMilli()
+	}
+	_, err = og.backend().AddTask(newT)
 	if err != nil {
-		return fmt.Errorf("error adding task: %v", err)
+		return fmt.Errorf("error adding task: %w", err)
 	}
 	return nil
 }
 
 func (og *Gateway) CompleteIssue(t Task) error {
 	log.Printf("CompleteIssue: %s", t)
-	err := MarkOmnifocusTaskComplete(t)
+	err := og.backend().CompleteTask(t)
+	if err != nil {
+		return fmt.Errorf("error completing task: %w", err)
+	}
+	return nil
+}
+
+// AppendNote adds line to the end of the task's note.
+func (og *Gateway) AppendNote(t Task, line string) error {
+	err := og.backend().AppendNote(t, line)
+	if err != nil {
+		return fmt.Errorf("error updating task note: %w", err)
+	}
+	return nil
+}
+
+// Rekey renames the task so its name starts with key rather than its
+// current key, as when the item's repo has been renamed, and returns the
+// renamed task.
+func (og *Gateway) Rekey(t Task, key string) (Task, error) {
+	log.Printf("Rekey: %s to %s", t, key)
+	name := key
+	if title := t.GetTitle(); title != "" {
+		name += " " + title
+	}
+	err := og.backend().RenameTask(t, name)
+	if err != nil {
+		return Task{}, fmt.Errorf("error renaming task: %w", err)
+	}
+	t.Name = name
+	return t, nil
+}
+
+// Retitle renames the task after item, as when the item's title changed
+// on GitHub, leaving the rest of the task, such as its note, as it is.
+func (og *Gateway) Retitle(t Task, item gh.GitHubItem) error {
+	name := og.taskName(item)
+	log.Printf("Retitle: %s to %s", t, name)
+	err := og.backend().RenameTask(t, name)
+	if err != nil {
+		return fmt.Errorf("error renaming task: %w", err)
+	}
+	return nil
+}
+
+// Running reports whether Omnifocus is running, launching it first if
+// launch is true.
+func (og *Gateway) Running(launch bool) (bool, error) {
+	running, err := og.backend().Running(launch)
 	if err != nil {
-		return fmt.Errorf("error completing task: %v", err)
+		return false, fmt.Errorf("error checking Omnifocus is running: %w", err)
+	}
+	return running, nil
+}
+
+// DropIssue drops, rather than completes, an issue or PR's task, e.g. when
+// the issue was closed as not planned.
+func (og *Gateway) DropIssue(t Task) error {
+	log.Printf("DropIssue: %s", t)
+	err := og.backend().DropTask(t)
+	if err != nil {
+		return fmt.Errorf("error dropping task: %w", err)
 	}
 	return nil
 }
 
 func (og *Gateway) CompletePR(t Task) error {
 	log.Printf("CompletePR: %s", t)
-	err := MarkOmnifocusTaskComplete(t)
+	err := og.backend().CompleteTask(t)
+	if err != nil {
+		return fmt.Errorf("error completing task: %w", err)
+	}
+	return nil
+}
+
+// trackingProject returns the project for the tracking issue named name,
+// as it's created.
+func (og *Gateway) trackingProject(name string) Project {
+	return og.newProject(Project{Name: name, Folder: og.TrackingFolder, Type: og.TrackingProjectType})
+}
+
+// newProject returns p with the review schedule of projects the gateway
+// creates.
+func (og *Gateway) newProject(p Project) Project {
+	p.ReviewIntervalDays = og.ProjectReviewIntervalDays
+	firstReviewIn := og.ProjectFirstReviewIn
+	if firstReviewIn == 0 {
+		firstReviewIn = time.Duration(og.ProjectReviewIntervalDays) * 24 * time.Hour
+	}
+	if firstReviewIn > 0 {
+		p.NextReviewDateMS = time.Now().Add(firstReviewIn).UnixMilli()
+	}
+	return p
+}
+
+// TrackingProject returns the name of the project for the tracking issue
+// t's task list item is from: the issue's key then its cleaned title, as
+// for task names.
+func (og *Gateway) TrackingProject(t gh.GitHubItem) string {
+	key, title, _ := strings.Cut(t.Parent, " ")
+	return key + " " + CleanTitle(title, og.MaxTitleLength)
+}
+
+// AddTrackedItem adds a task for a tracking issue's task list item to the
+// issue's project, creating the project if needed.
+func (og *Gateway) AddTrackedItem(t gh.GitHubItem) error {
+	log.Printf("AddTrackedItem: %s", t)
+	project := og.TrackingProject(t)
+	err := og.backend().EnsureProjectExists(og.trackingProject(project))
+	if err != nil {
+		return fmt.Errorf("error ensuring project %q exists: %w", project, err)
+	}
+	_, err = og.backend().AddTask(NewOmnifocusTask{
+		ProjectName: project,
+		Name:        og.taskName(t),
+		Tags:        []string{og.AppTag, og.TrackingTag, t.Repo},
+		Note:        og.note(t),
+	})
+	if err != nil {
+		return fmt.Errorf("error adding task: %w", err)
+	}
+	return nil
+}
+
+func (og *Gateway) CompleteTrackedItem(t Task) error {
+	log.Printf("CompleteTrackedItem: %s", t)
+	err := og.backend().CompleteTask(t)
+	if err != nil {
+		return fmt.Errorf("error completing task: %w", err)
+	}
+	return nil
+}
+
+func (og *Gateway) CompleteFailingCheck(t Task) error {
+	log.Printf("CompleteFailingCheck: %s", t)
+	err := og.backend().CompleteTask(t)
+	if err != nil {
+		return fmt.Errorf("error completing task: %w", err)
+	}
+	return nil
+}
+
+func (og *Gateway) CompleteWatchedRelease(t Task) error {
+	log.Printf("CompleteWatchedRelease: %s", t)
+	err := og.backend().CompleteTask(t)
+	if err != nil {
+		return fmt.Errorf("error completing task: %w", err)
+	}
+	return nil
+}
+
+func (og *Gateway) CompleteMention(t Task) error {
+	log.Printf("CompleteMention: %s", t)
+	err := og.backend().CompleteTask(t)
+	if err != nil {
+		return fmt.Errorf("error completing task: %w", err)
+	}
+	return nil
+}
+
+func (og *Gateway) CompleteDuplicate(t Task) error {
+	log.Printf("CompleteDuplicate: %s", t)
+	err := og.backend().CompleteTask(t)
 	if err != nil {
-		return fmt.Errorf("error completing task: %v", err)
+		return fmt.Errorf("error completing task: %w", err)
 	}
 	return nil
 }
 
 func (og *Gateway) CompleteNotification(t Task) error {
 	log.Printf("CompleteNotification: %s", t)
-	err := MarkOmnifocusTaskComplete(t)
+	err := og.backend().CompleteTask(t)
 	if err != nil {
-		return fmt.Errorf("error completing task: %v", err)
+		return fmt.Errorf("error completing task: %w", err)
 	}
 	return nil
 }
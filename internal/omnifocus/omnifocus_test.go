@@ -1,6 +1,12 @@
 package omnifocus
 
-import "testing"
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
 
 func TestTaskKey(t *testing.T) {
 	task := Task{
@@ -12,3 +18,71 @@ func TestTaskKey(t *testing.T) {
 		t.Fatalf("Didn't get expected key, got: %s", k)
 	}
 }
+
+func TestTaskTitle(t *testing.T) {
+	task := Task{
+		ID:   "foo",
+		Name: "rhyshort/github-to-omnifocus#3 foo bar foo",
+	}
+	if task.GetTitle() != "foo bar foo" {
+		t.Fatalf("Didn't get expected title, got: %s", task.GetTitle())
+	}
+	if (Task{Name: "nokey"}).GetTitle() != "" {
+		t.Fatal("Expected empty title for task without one")
+	}
+}
+
+func TestDueDateFromItem(t *testing.T) {
+	og := Gateway{DueDatePattern: regexp.MustCompile(`due[:/]\s*(\d{4}-\d{2}-\d{2})`)}
+	cases := []struct {
+		item gh.GitHubItem
+		due  string
+	}{
+		{gh.GitHubItem{Body: "Needed for launch.\n\ndue: 2024-06-01"}, "2024-06-01"},
+		{gh.GitHubItem{Labels: []string{"bug", "due/2024-07-15"}, Body: "due: 2024-06-01"}, "2024-07-15"},
+		{gh.GitHubItem{Body: "due: 2024-13-01"}, ""},
+		{gh.GitHubItem{Body: "No date"}, ""},
+	}
+	for _, c := range cases {
+		due, ok := og.dueDateFromItem(c.item)
+		if c.due == "" {
+			if ok {
+				t.Fatalf("Expected no due date for %v, got: %s", c.item, due)
+			}
+			continue
+		}
+		if !ok || due.Format(time.DateOnly) != c.due || due.Location() != time.Local || due.Hour() != 17 {
+			t.Fatalf("Expected due date %s at 5pm local time for %v, got: %s", c.due, c.item, due)
+		}
+	}
+}
+
+func TestDeadlineEnglishMonths(t *testing.T) {
+	for tag, month := range map[string]time.Month{"Jan": time.January, "dec": time.December} {
+		deadline, err := (&Gateway{}).deadline([]string{"ready", tag})
+		if err != nil {
+			t.Fatal(err)
+		}
+		due := time.UnixMilli(deadline)
+		if due.Year() != time.Now().Year() || due.Month() != month || due.Day() != 31 {
+			t.Errorf("Expected %s to be due at the end of %s this year, got: %s", tag, month, due)
+		}
+	}
+}
+
+func TestDeadlineMonths(t *testing.T) {
+	og := Gateway{TaskMasterMonths: []string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"}}
+	for _, tag := range []string{"mär", "Mar"} {
+		deadline, err := og.deadline([]string{"ready", tag})
+		if err != nil {
+			t.Fatal(err)
+		}
+		due := time.UnixMilli(deadline)
+		if due.Month() != time.March || due.Day() != 31 {
+			t.Errorf("Expected %s to be due at the end of March, got: %s", tag, due)
+		}
+	}
+	if _, err := (&Gateway{}).deadline([]string{"Mär"}); err == nil {
+		t.Error("Expected only English months without TaskMasterMonths")
+	}
+}
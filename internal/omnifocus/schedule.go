@@ -0,0 +1,74 @@
+package omnifocus
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
+
+// ScheduleUpdate sets the flag and dates of the task with ID. A date of 0
+// is left as it is.
+type ScheduleUpdate struct {
+	ID          string `json:"id"`
+	Flagged     bool   `json:"flagged"`
+	DueDateMS   int64  `json:"dueDateMS"`
+	DeferDateMS int64  `json:"deferDateMS"`
+}
+
+// scheduleOf describes the values of t's PriorityField and IterationField,
+// which its task's flag and dates are set from, so a change to them can be
+// noticed.
+func (og *Gateway) scheduleOf(t gh.GitHubItem) string {
+	it := t.ProjectIterations[og.IterationField]
+	return fmt.Sprintf("priority %q, iteration %q from %s for %d days", t.ProjectFields[og.PriorityField], it.Title, it.Start.Format(time.DateOnly), it.Days)
+}
+
+// recordSchedule remembers the project field values t's new task was
+// scheduled from.
+func (og *Gateway) recordSchedule(t gh.GitHubItem) {
+	if og.PriorityField == "" && og.IterationField == "" {
+		return
+	}
+	og.State.SetSchedule(t.Key(), og.scheduleOf(t))
+}
+
+// UpdateSchedules applies changes to the PriorityField and IterationField
+// values of issues and PRs to their tasks, flagging them and setting their
+// dates as for new tasks. A date with nothing to set it from is left as it
+// is. Tasks added before their values were recorded are only recorded.
+func (og *Gateway) UpdateSchedules(synced []SyncedTask) error {
+	if og.PriorityField == "" && og.IterationField == "" {
+		return nil
+	}
+	updates := []ScheduleUpdate{}
+	schedules := map[string]string{}
+	for _, s := range synced {
+		if s.Item.ProjectFields == nil && s.Item.ProjectIterations == nil {
+			// The item's project fields weren't fetched.
+			continue
+		}
+		k := s.Item.Key()
+		schedule := og.scheduleOf(s.Item)
+		schedules[k] = schedule
+		if was, ok := og.State.Schedule(k); !ok || was == schedule {
+			continue
+		}
+		log.Printf("Rescheduling %s for %s", k, schedule)
+		task := NewOmnifocusTask{}
+		og.prioritise(&task, s.Item)
+		og.scheduleForIteration(&task, s.Item)
+		updates = append(updates, ScheduleUpdate{ID: s.Task.ID, Flagged: task.Flagged, DueDateMS: task.DueDateMS, DeferDateMS: task.DeferDateMS})
+	}
+	if len(updates) > 0 {
+		err := og.backend().UpdateSchedules(updates)
+		if err != nil {
+			return fmt.Errorf("error updating task schedules: %w", err)
+		}
+	}
+	for k, schedule := range schedules {
+		og.State.SetSchedule(k, schedule)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package omnifocus
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+)
+
+// executeScript runs jsCode against document passing it args as input, and
+// returns the output of the command.
+func executeScript(document string, jsCode []byte, args []byte) ([]byte, error) {
+	return osascript(document, jsCode, args, "-l", "JavaScript")
+}
+
+// executeAppleScript runs AppleScript code against document passing it args
+// as input, and returns the output of the command.
+func executeAppleScript(document string, code []byte, args []byte) ([]byte, error) {
+	return osascript(document, code, args)
+}
+
+func osascript(document string, code []byte, args []byte, flags ...string) ([]byte, error) {
+	// All scripts expect a JSON object passed in via the
+	// OSA_ARGS environment variable, and the name of the
+	// document to use, if not the default, in OSA_DOCUMENT.
+	// The script itself is passed into osascript via stdin.
+	// The script outputs a JSON document over stdout.
+
+	cmd := exec.Command("/usr/bin/osascript", append(flags, "-s", "o")...)
+
+	cmd.Env = append(os.Environ(),
+		"OSA_ARGS="+string(args),
+		"OSA_DOCUMENT="+document,
+	)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer stdin.Close()
+		_, err := io.WriteString(stdin, string(code))
+		if err != nil {
+			// should never fail
+			log.Fatal(err)
+		}
+	}()
+
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// With -s o, script errors are written to stdout.
+		return nil, parseScriptError(string(exitErr.Stderr) + string(out))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
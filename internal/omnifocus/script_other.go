@@ -0,0 +1,22 @@
+//go:build !darwin
+
+package omnifocus
+
+import (
+	"errors"
+	"runtime"
+)
+
+// ErrUnsupportedPlatform is returned by all Omnifocus operations when not
+// running on macOS.
+var ErrUnsupportedPlatform = errors.New("omnifocus is only available on macOS, not " + runtime.GOOS)
+
+// executeScript can't run JXA scripts outside macOS.
+func executeScript(document string, jsCode []byte, args []byte) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// executeAppleScript can't run AppleScript outside macOS.
+func executeAppleScript(document string, code []byte, args []byte) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
@@ -0,0 +1,60 @@
+package omnifocus
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+)
+
+// CaseTag returns tag in tagCase: all lower case, title case, such as
+// "Good First Issue" or "Size:S", or as it is if tagCase is "". Tags that
+// differ only in case have the same lower or title case.
+func CaseTag(tag, tagCase string) string {
+	switch tagCase {
+	case internal.TagCaseLower:
+		return strings.ToLower(tag)
+	case internal.TagCaseTitle:
+		var b strings.Builder
+		start := true
+		for _, r := range strings.ToLower(tag) {
+			if start {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+			start = !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+		}
+		return b.String()
+	}
+	return tag
+}
+
+// ownTags returns the tags the gateway identifies its tasks by.
+func (og *Gateway) ownTags() []string {
+	return []string{og.AppTag, og.AssignedTag, og.ReviewTag, og.NotificationTag, og.PendingChangesTag, og.FailingChecksTag, og.WatchedReleasesTag, og.TrackingTag, og.MentionsTag}
+}
+
+// casedBackend puts the tags of the tasks it adds, other than own, in
+// tagCase. Tasks are compared with their items ignoring case, so tasks
+// with tags in another case, as added before tagCase was set, are left as
+// they are.
+type casedBackend struct {
+	Backend
+	tagCase string
+	own     []string
+}
+
+func (b casedBackend) AddTask(t NewOmnifocusTask) (Task, error) {
+	tags := []string{}
+	for _, tag := range t.Tags {
+		if !slices.Contains(b.own, tag) {
+			tag = CaseTag(tag, b.tagCase)
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	t.Tags = tags
+	return b.Backend.AddTask(t)
+}
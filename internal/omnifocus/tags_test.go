@@ -0,0 +1,54 @@
+package omnifocus
+
+import (
+	"path"
+	"slices"
+	"testing"
+
+	"github.com/rhyshort/github-to-omnifocus/internal"
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
+
+func TestCaseTag(t *testing.T) {
+	cases := []struct {
+		tag, tagCase, expected string
+	}{
+		{"Good First Issue", "", "Good First Issue"},
+		{"Good First Issue", internal.TagCaseLower, "good first issue"},
+		{"good first ISSUE", internal.TagCaseTitle, "Good First Issue"},
+		{"size:s", internal.TagCaseTitle, "Size:S"},
+		{"acme/tools", internal.TagCaseTitle, "Acme/Tools"},
+		{"milestone: v1.2", internal.TagCaseTitle, "Milestone: V1.2"},
+		{"won't fix", internal.TagCaseTitle, "Won't Fix"},
+	}
+	for _, c := range cases {
+		if cased := CaseTag(c.tag, c.tagCase); cased != c.expected {
+			t.Errorf("Expected %q for %q in %q case, got: %q", c.expected, c.tag, c.tagCase, cased)
+		}
+	}
+}
+
+func TestTagCase(t *testing.T) {
+	fake, err := NewFakeBackend(path.Join(t.TempDir(), "fake.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	og := Gateway{AppTag: "GitHub", AssignedTag: "assigned", AssignedProject: "GitHub Assigned", TagCase: internal.TagCaseLower, Backend: fake}
+	err = og.AddIssue(gh.GitHubItem{K: "Acme/Tools#1", Repo: "Acme/Tools", Labels: []string{"Bug", "bug", "P1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := fake.Tasks[0].Tags
+	expected := []string{"GitHub", "assigned", "acme/tools", "bug", "p1"}
+	if !slices.Equal(tags, expected) {
+		t.Errorf("Expected tags %v, got: %v", expected, tags)
+	}
+	tasks, err := og.GetIssues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 {
+		t.Errorf("Expected the task to be found by its own tags, got: %v", tasks)
+	}
+}
@@ -0,0 +1,30 @@
+package omnifocus
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
+
+// CleanTitle makes title fit for a task name: control characters, such as
+// newlines, become spaces, runs of spaces are collapsed and, if max is
+// non-zero, titles longer than max runes are cut short with an ellipsis.
+// Cleaning a clean title leaves it unchanged.
+func CleanTitle(title string, max int) string {
+	title = strings.Join(strings.FieldsFunc(title, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+	if max > 0 && utf8.RuneCountInString(title) > max {
+		runes := []rune(title)
+		title = strings.TrimRightFunc(string(runes[:max-1]), unicode.IsSpace) + "…"
+	}
+	return title
+}
+
+// taskName returns the name for t's task, being its key then its cleaned
+// title. The key is never truncated, as it's how the task is found again.
+func (og *Gateway) taskName(t gh.GitHubItem) string {
+	return t.Key() + " " + CleanTitle(t.Title, og.MaxTitleLength)
+}
@@ -0,0 +1,39 @@
+package omnifocus
+
+import (
+	"testing"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
+
+func TestCleanTitle(t *testing.T) {
+	cases := []struct {
+		title    string
+		max      int
+		expected string
+	}{
+		{"Fix the build", 0, "Fix the build"},
+		{"Fix\tthe\r\nbuild\x07  now", 0, "Fix the build now"},
+		{"Fix the build", 13, "Fix the build"},
+		{"Fix the build", 9, "Fix the…"},
+		{"Überprüfung der Größe", 6, "Überp…"},
+		{"🐛🔥 Crash", 3, "🐛🔥…"},
+	}
+	for _, c := range cases {
+		cleaned := CleanTitle(c.title, c.max)
+		if cleaned != c.expected {
+			t.Fatalf("Expected %q for %q, got: %q", c.expected, c.title, cleaned)
+		}
+		if again := CleanTitle(cleaned, c.max); again != cleaned {
+			t.Fatalf("Expected cleaning %q to leave it unchanged, got: %q", cleaned, again)
+		}
+	}
+}
+
+func TestTaskNameKeepsKey(t *testing.T) {
+	og := Gateway{MaxTitleLength: 5}
+	task := Task{Name: og.taskName(gh.GitHubItem{K: "acme/widgets#12", Title: "A very long title"})}
+	if task.Key() != "acme/widgets#12" || task.Name != "acme/widgets#12 A ve…" {
+		t.Fatalf("Unexpected task name: %s", task.Name)
+	}
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// remoteClient fetches remote config files.
+var remoteClient = &http.Client{Timeout: 30 * time.Second}
+
+// localFields are fields that must stay in local config files: those
+// holding credentials, choosing where the token or sync summaries are
+// sent, or how connections are secured, and those that act on GitHub on
+// the user's behalf.
+var localFields = []string{
+	"APIURL", "AccessToken", "AuthFromGhCli", "ProxyURL", "CACertFile", "InsecureSkipVerify",
+	"WebhookURL", "WebhookFormat", "WebhookOn",
+	"SMTPServer", "SMTPUsername", "SMTPPassword", "EmailFrom", "EmailTo", "EmailOn",
+	"UncompletedAction", "UncompletedComment", "MarkStaleNotificationsRead",
+}
+
+// isRemote is true for includes that are URLs rather than paths.
+func isRemote(include string) bool {
+	return strings.HasPrefix(include, "https://") || strings.HasPrefix(include, "http://")
+}
+
+// loadRemoteConfig fetches the config file at url, such as a raw gist,
+// keeping a copy in the cache dir to use when it can't be fetched. Remote
+// config files can't set localFields or include other files.
+func loadRemoteConfig(url string) (rawConfig, error) {
+	if !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("remote config %s must use https", url)
+	}
+	cached, err := remoteCachePath(url)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := fetchRemoteConfig(url)
+	if err != nil {
+		log.Printf("Using cached copy of %s: %v", url, err)
+		bytes, err = os.ReadFile(cached)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching remote config %s and no cached copy: %v", url, err)
+		}
+	}
+
+	rc, includes, err := decodeRawConfig(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling config JSON from %s: %v", url, err)
+	}
+	if len(includes) > 0 {
+		return nil, fmt.Errorf("remote config %s can't include other files", url)
+	}
+	for section, fields := range rc {
+		for name := range fields {
+			// Field names are matched ignoring case, as when decoding.
+			i := slices.IndexFunc(localFields, func(f string) bool { return strings.EqualFold(f, name) })
+			if i >= 0 {
+				return nil, fmt.Errorf("remote config %s can't set %s in %s, keep it in a local file", url, localFields[i], section)
+			}
+		}
+	}
+
+	err = os.MkdirAll(path.Dir(cached), 0o700)
+	if err == nil {
+		err = os.WriteFile(cached, bytes, 0o600)
+	}
+	if err != nil {
+		log.Printf("Couldn't cache remote config %s: %v", url, err)
+	}
+	return rc, nil
+}
+
+func fetchRemoteConfig(url string) ([]byte, error) {
+	log.Printf("Fetching config from %s", url)
+	resp, err := remoteClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// remoteCachePath returns where the last copy of the config at url is kept.
+func remoteCachePath(url string) (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(url))
+	return path.Join(dir, "remote", hex.EncodeToString(sum[:8])+".json"), nil
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+)
+
+func TestLoadRemoteConfig(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	body := `{"defaults": {"AppTag": "github", "ReviewProject": "Team Reviews"}}`
+	up := true
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	defer func(c *http.Client) { remoteClient = c }(remoteClient)
+	remoteClient = server.Client()
+
+	dir := t.TempDir()
+	writeFile(t, path.Join(dir, "config.json"), `{
+		"include": ["`+server.URL+`/team.json"],
+		"work": {"AccessToken": "secret"}
+	}`)
+	for _, up = range []bool{true, false} {
+		rc, err := loadRawConfig(path.Join(dir, "config.json"), map[string]bool{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := buildConfig(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c["work"].ReviewProject != "Team Reviews" || c["work"].AccessToken != "secret" {
+			t.Fatalf("Expected remote and local values, got: %v", c["work"])
+		}
+	}
+
+	_, err := loadRemoteConfig(server.URL + "/other.json")
+	if err == nil {
+		t.Fatal("Expected error when not fetched or cached")
+	}
+
+	up = true
+	for _, body = range []string{
+		`{"work": {"AccessToken": "leaked"}}`,
+		`{"defaults": {"apiurl": "https://evil.example.com/api/v3/"}}`,
+		`{"work": {"AuthFromGhCli": true}}`,
+		`{"work": {"WebhookURL": "https://evil.example.com/hook"}}`,
+		`{"work": {"SMTPPassword": "leaked"}}`,
+	} {
+		_, err = loadRemoteConfig(server.URL + "/secrets.json")
+		if err == nil {
+			t.Errorf("Expected error for local-only field in remote config %s", body)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// Entry records a task added or completed by a sync, in an account's
+// journal.
+type Entry struct {
+	At       time.Time `json:"at"`
+	Op       string    `json:"op"`
+	Category string    `json:"category"`
+	Key      string    `json:"key"`
+	Repo     string    `json:"repo,omitempty"`
+	Title    string    `json:"title,omitempty"`
+}
+
+// Values for Entry.Op.
+const (
+	JournalAdd      = "add"
+	JournalComplete = "complete"
+)
+
+// journalFor is how long journal entries are kept.
+const journalFor = 90 * 24 * time.Hour
+
+// JournalPath returns the location of the journal for account within the
+// app's cache dir.
+func JournalPath(cacheDir, account string) string {
+	return path.Join(cacheDir, "journal", account+".jsonl")
+}
+
+// ReadJournal returns the entries in the journal at p made at or after
+// since, oldest first. A missing journal has no entries.
+func ReadJournal(p string, since time.Time) ([]Entry, error) {
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading journal from %s: %v", p, err)
+	}
+	entries := []Entry{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling journal entry from %s: %v", p, err)
+		}
+		if !e.At.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// AppendJournal adds entries to the journal at p, dropping entries older
+// than journalFor so the journal doesn't grow forever.
+func AppendJournal(p string, entries []Entry, now time.Time) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	kept, err := ReadJournal(p, now.Add(-journalFor))
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range append(kept, entries...) {
+		err = enc.Encode(e)
+		if err != nil {
+			return err
+		}
+	}
+	err = os.MkdirAll(path.Dir(p), 0o700)
+	if err != nil {
+		return fmt.Errorf("error creating journal dir: %v", err)
+	}
+	// Write then rename so a crash mid-write can't leave a truncated file.
+	tmp := p + ".tmp"
+	err = os.WriteFile(tmp, buf.Bytes(), 0o600)
+	if err != nil {
+		return fmt.Errorf("error writing journal to %s: %v", tmp, err)
+	}
+	return os.Rename(tmp, p)
+}
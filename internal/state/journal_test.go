@@ -0,0 +1,46 @@
+package state
+
+import (
+	"path"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	p := path.Join(t.TempDir(), "journal", "work.jsonl")
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	old := Entry{At: now.Add(-journalFor - time.Hour), Op: JournalAdd, Category: "PRs", Key: "acme/tools#1"}
+	lastWeek := Entry{At: now.Add(-7 * 24 * time.Hour), Op: JournalAdd, Category: "PRs", Key: "acme/tools#2"}
+
+	err := AppendJournal(p, []Entry{old, lastWeek}, now.Add(-journalFor-2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := Entry{At: now, Op: JournalComplete, Category: "PRs", Key: "acme/tools#2"}
+	err = AppendJournal(p, []Entry{done}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadJournal(p, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Key != "acme/tools#2" || entries[1].Op != JournalComplete {
+		t.Fatalf("Expected old entry to be dropped, got: %+v", entries)
+	}
+	entries, err = ReadJournal(p, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected only the entry since, got: %+v", entries)
+	}
+}
+
+func TestReadMissingJournal(t *testing.T) {
+	entries, err := ReadJournal(path.Join(t.TempDir(), "missing.jsonl"), time.Time{})
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Expected no entries, got: %v %v", entries, err)
+	}
+}
@@ -0,0 +1,320 @@
+// Package state persists the small amount of information github2omnifocus
+// needs to remember between runs for an account, such as when an item was
+// first seen.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/rhyshort/github-to-omnifocus/internal/gh"
+)
+
+// Store is the state for a single account. It is loaded at the start of a
+// sync and saved at the end.
+type Store struct {
+	path string
+
+	// FirstSeen records when each item key was first retrieved from GitHub.
+	FirstSeen map[string]time.Time `json:"firstSeen"`
+	// Completed records when the app completed the task for each item key,
+	// so that a task marked incomplete again in Omnifocus can be noticed.
+	Completed map[string]time.Time `json:"completed"`
+	// Kept records item keys whose tasks were marked incomplete again in
+	// Omnifocus. These tasks are left alone until they're completed by hand.
+	Kept map[string]time.Time `json:"kept"`
+	// ReviewChecked records when each review PR had last been updated as
+	// of checking whether its review was requested again.
+	ReviewChecked map[string]time.Time `json:"reviewChecked,omitempty"`
+	// Stale records the notifications whose tasks were completed for being
+	// unread too long, with when each was last updated, so they're not
+	// added again until there's new activity.
+	Stale map[string]time.Time `json:"stale,omitempty"`
+	// Open records the watched releases and mentions that had an open task
+	// as of the last sync, so a task completed by hand since can be
+	// noticed.
+	Open map[string]time.Time `json:"open,omitempty"`
+	// Dismissed records the watched releases and mentions whose tasks were
+	// completed by hand, or that were seen to some other way, so they're
+	// not added again while they're still wanted.
+	Dismissed map[string]time.Time `json:"dismissed,omitempty"`
+	// Scheduled records the project field values each issue and PR task's
+	// flag and dates were last set from, so a change on the project board
+	// can be applied to the task.
+	Scheduled map[string]string `json:"scheduled,omitempty"`
+	// Notifications holds the unread notifications as of
+	// NotificationsSince, so that only notifications updated since then
+	// need fetching. NotificationsFull is when they were last all fetched.
+	Notifications      []gh.GitHubItem `json:"notifications,omitempty"`
+	NotificationsSince time.Time       `json:"notificationsSince"`
+	NotificationsFull  time.Time       `json:"notificationsFull"`
+}
+
+// completedFor is how long a completion is remembered. A task marked
+// incomplete after this is treated as any other task.
+const completedFor = 30 * 24 * time.Hour
+
+// Path returns the location of the state file for account within the
+// app's cache dir.
+func Path(cacheDir, account string) string {
+	return path.Join(cacheDir, "state", account+".json")
+}
+
+// Load reads the state stored at p. A missing file is not an error and
+// results in an empty Store.
+func Load(p string) (*Store, error) {
+	s := &Store{path: p}
+	bytes, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		s.init()
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state from %s: %v", p, err)
+	}
+	err = json.Unmarshal(bytes, s)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling state JSON from %s: %v", p, err)
+	}
+	s.init()
+	return s, nil
+}
+
+func (s *Store) init() {
+	if s.FirstSeen == nil {
+		s.FirstSeen = map[string]time.Time{}
+	}
+	if s.Completed == nil {
+		s.Completed = map[string]time.Time{}
+	}
+	if s.Kept == nil {
+		s.Kept = map[string]time.Time{}
+	}
+	if s.ReviewChecked == nil {
+		s.ReviewChecked = map[string]time.Time{}
+	}
+	if s.Stale == nil {
+		s.Stale = map[string]time.Time{}
+	}
+	if s.Open == nil {
+		s.Open = map[string]time.Time{}
+	}
+	if s.Dismissed == nil {
+		s.Dismissed = map[string]time.Time{}
+	}
+	if s.Scheduled == nil {
+		s.Scheduled = map[string]string{}
+	}
+}
+
+// Save writes the state back to the file it was loaded from.
+func (s *Store) Save() error {
+	bytes, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path.Dir(s.path), 0o700)
+	if err != nil {
+		return fmt.Errorf("error creating state dir: %v", err)
+	}
+	// Write then rename so a crash mid-write can't leave a truncated file.
+	tmp := s.path + ".tmp"
+	err = os.WriteFile(tmp, bytes, 0o600)
+	if err != nil {
+		return fmt.Errorf("error writing state to %s: %v", tmp, err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Observe records now as the first-seen time of any key not already known,
+// and forgets keys that are not in keys, so the store only holds items that
+// are currently open.
+func (s *Store) Observe(keys []string, now time.Time) {
+	current := map[string]bool{}
+	for _, k := range keys {
+		current[k] = true
+		if _, ok := s.FirstSeen[k]; !ok {
+			s.FirstSeen[k] = now
+		}
+	}
+	for k := range s.FirstSeen {
+		if !current[k] {
+			delete(s.FirstSeen, k)
+		}
+	}
+	for _, m := range []map[string]time.Time{s.ReviewChecked, s.Stale, s.Dismissed} {
+		for k := range m {
+			if !current[k] {
+				delete(m, k)
+			}
+		}
+	}
+	for k := range s.Scheduled {
+		if !current[k] {
+			delete(s.Scheduled, k)
+		}
+	}
+}
+
+// FirstSeenAt returns when key was first observed, or now if it never has
+// been.
+func (s *Store) FirstSeenAt(key string, now time.Time) time.Time {
+	if s == nil {
+		return now
+	}
+	if t, ok := s.FirstSeen[key]; ok {
+		return t
+	}
+	return now
+}
+
+// MarkCompleted records that the task for key was completed at now.
+func (s *Store) MarkCompleted(key string, now time.Time) {
+	s.Completed[key] = now
+}
+
+// WasCompleted reports whether the task for key was completed by the app.
+func (s *Store) WasCompleted(key string) bool {
+	_, ok := s.Completed[key]
+	return ok
+}
+
+// Keep records that the task for key was marked incomplete again, so should
+// be left alone.
+func (s *Store) Keep(key string, now time.Time) {
+	delete(s.Completed, key)
+	s.Kept[key] = now
+}
+
+// IsKept reports whether the task for key should be left alone.
+func (s *Store) IsKept(key string) bool {
+	_, ok := s.Kept[key]
+	return ok
+}
+
+// ReviewRequested records that review of the PR key was requested again
+// at requestedAt. The PR is treated as first seen then, and whether its
+// task was completed or kept is forgotten.
+func (s *Store) ReviewRequested(key string, requestedAt time.Time) {
+	s.FirstSeen[key] = requestedAt
+	delete(s.Completed, key)
+	delete(s.Kept, key)
+}
+
+// MarkStale records that the task for the notification key was completed
+// for being unread too long, as of the notification's updatedAt.
+func (s *Store) MarkStale(key string, updatedAt time.Time) {
+	s.Stale[key] = updatedAt
+}
+
+// Revive forgets that the notification key was stale if it has had
+// activity since, being last updated at updatedAt, and treats it as first
+// seen at now.
+func (s *Store) Revive(key string, updatedAt, now time.Time) {
+	staleAt, ok := s.Stale[key]
+	if ok && updatedAt.After(staleAt) {
+		delete(s.Stale, key)
+		s.FirstSeen[key] = now
+	}
+}
+
+// IsStale reports whether the task for the notification key was completed
+// for being unread too long. Revive should be called first, so new
+// activity is taken into account.
+func (s *Store) IsStale(key string) bool {
+	_, ok := s.Stale[key]
+	return ok
+}
+
+// NoteDismissed records the keys in desired that had an open task as of
+// the last call but don't in open, as their tasks were completed by hand.
+// open is remembered for the next call.
+func (s *Store) NoteDismissed(desired, open []string, now time.Time) {
+	isOpen := toSet(open)
+	for _, k := range desired {
+		if _, ok := s.Open[k]; ok && !isOpen[k] {
+			s.Dismiss(k, now)
+		}
+	}
+	clear(s.Open)
+	for k := range isOpen {
+		s.Open[k] = now
+	}
+}
+
+// Dismiss records that key was seen to some other way, so its task isn't
+// wanted.
+func (s *Store) Dismiss(key string, now time.Time) {
+	s.Dismissed[key] = now
+}
+
+// IsDismissed reports whether the task for key was completed by hand, so
+// shouldn't be added again.
+func (s *Store) IsDismissed(key string) bool {
+	_, ok := s.Dismissed[key]
+	return ok
+}
+
+// SetSchedule records the project field values the task for key was
+// scheduled from, as given by schedule.
+func (s *Store) SetSchedule(key, schedule string) {
+	if s == nil {
+		return
+	}
+	s.Scheduled[key] = schedule
+}
+
+// Schedule returns the project field values the task for key was last
+// scheduled from. ok is false if they weren't recorded.
+func (s *Store) Schedule(key string) (schedule string, ok bool) {
+	if s == nil {
+		return "", false
+	}
+	schedule, ok = s.Scheduled[key]
+	return schedule, ok
+}
+
+// Rename moves what's known about the item oldKey to newKey, as when the
+// item's repo has been renamed.
+func (s *Store) Rename(oldKey, newKey string) {
+	for _, m := range []map[string]time.Time{s.FirstSeen, s.Completed, s.Kept, s.ReviewChecked, s.Stale, s.Open, s.Dismissed} {
+		if t, ok := m[oldKey]; ok {
+			m[newKey] = t
+			delete(m, oldKey)
+		}
+	}
+	if schedule, ok := s.Scheduled[oldKey]; ok {
+		s.Scheduled[newKey] = schedule
+		delete(s.Scheduled, oldKey)
+	}
+}
+
+// ForgetCompletions forgets completions that are in desired, as the item
+// has been reopened on GitHub, or are older than completedFor. It forgets
+// kept keys that are in desired, or that no longer have an open task.
+func (s *Store) ForgetCompletions(desired, open []string, now time.Time) {
+	isDesired := toSet(desired)
+	isOpen := toSet(open)
+	for k, t := range s.Completed {
+		if isDesired[k] || now.Sub(t) > completedFor {
+			delete(s.Completed, k)
+		}
+	}
+	for k := range s.Kept {
+		if isDesired[k] || !isOpen[k] {
+			delete(s.Kept, k)
+		}
+	}
+}
+
+func toSet(keys []string) map[string]bool {
+	set := map[string]bool{}
+	for _, k := range keys {
+		set[k] = true
+	}
+	return set
+}
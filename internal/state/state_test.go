@@ -0,0 +1,182 @@
+package state
+
+import (
+	"path"
+	"testing"
+	"time"
+)
+
+func TestObserve(t *testing.T) {
+	s, err := Load(path.Join(t.TempDir(), "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	s.Observe([]string{"a#1", "a#2"}, first)
+	s.Observe([]string{"a#2", "a#3"}, second)
+
+	if _, ok := s.FirstSeen["a#1"]; ok {
+		t.Fatal("Expected a#1 to be forgotten")
+	}
+	if !s.FirstSeenAt("a#2", second).Equal(first) {
+		t.Fatalf("Expected a#2 to keep first time, got: %v", s.FirstSeen["a#2"])
+	}
+	if !s.FirstSeenAt("a#3", second).Equal(second) {
+		t.Fatalf("Expected a#3 to be recorded at second time, got: %v", s.FirstSeen["a#3"])
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	p := path.Join(t.TempDir(), "state", "account.json")
+	s, err := Load(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s.Observe([]string{"a#1"}, now)
+	err = s.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.FirstSeenAt("a#1", time.Time{}).Equal(now) {
+		t.Fatalf("Expected saved time, got: %v", loaded.FirstSeen)
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	s, err := Load(path.Join(t.TempDir(), "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	s.MarkCompleted("a#1", now)
+	s.MarkCompleted("a#2", now)
+	s.MarkCompleted("a#3", now.Add(-31*24*time.Hour))
+	s.ForgetCompletions([]string{"a#2"}, nil, now)
+	if !s.WasCompleted("a#1") {
+		t.Fatal("Expected a#1 to be remembered")
+	}
+	if s.WasCompleted("a#2") {
+		t.Fatal("Expected desired a#2 to be forgotten")
+	}
+	if s.WasCompleted("a#3") {
+		t.Fatal("Expected old a#3 to be forgotten")
+	}
+
+	s.Keep("a#1", now)
+	if s.WasCompleted("a#1") || !s.IsKept("a#1") {
+		t.Fatal("Expected a#1 to be kept")
+	}
+	s.ForgetCompletions(nil, []string{"a#1"}, now)
+	if !s.IsKept("a#1") {
+		t.Fatal("Expected a#1 to stay kept while its task is open")
+	}
+	s.ForgetCompletions(nil, nil, now)
+	if s.IsKept("a#1") {
+		t.Fatal("Expected a#1 to be forgotten once its task is closed")
+	}
+}
+
+func TestRename(t *testing.T) {
+	s, err := Load(path.Join(t.TempDir(), "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s.Observe([]string{"acme/old#1"}, seen)
+	s.Keep("acme/old#1", seen)
+	s.Rename("acme/old#1", "acme/new#1")
+	if !s.FirstSeenAt("acme/new#1", time.Now()).Equal(seen) || !s.IsKept("acme/new#1") {
+		t.Fatalf("Expected acme/new#1 to take over acme/old#1's state, got: %+v", s)
+	}
+	if _, ok := s.FirstSeen["acme/old#1"]; ok || s.IsKept("acme/old#1") {
+		t.Fatalf("Expected acme/old#1 to be forgotten, got: %+v", s)
+	}
+}
+
+func TestReviewRequested(t *testing.T) {
+	s, err := Load(path.Join(t.TempDir(), "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s.Observe([]string{"acme/tools#1"}, seen)
+	s.MarkCompleted("acme/tools#1", seen)
+	s.Keep("acme/tools#1", seen)
+	s.ReviewChecked["acme/tools#1"] = seen
+
+	requested := seen.Add(48 * time.Hour)
+	s.ReviewRequested("acme/tools#1", requested)
+	if !s.FirstSeenAt("acme/tools#1", time.Now()).Equal(requested) {
+		t.Fatalf("Expected acme/tools#1 to be seen afresh, got: %+v", s)
+	}
+	if s.WasCompleted("acme/tools#1") || s.IsKept("acme/tools#1") {
+		t.Fatalf("Expected acme/tools#1's completion to be forgotten, got: %+v", s)
+	}
+
+	s.Observe([]string{}, requested)
+	if _, ok := s.ReviewChecked["acme/tools#1"]; ok {
+		t.Fatalf("Expected review checks to be pruned, got: %+v", s)
+	}
+}
+
+func TestStale(t *testing.T) {
+	s, err := Load(path.Join(t.TempDir(), "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s.Observe([]string{"acme/tools#1", "acme/tools#2"}, seen)
+	s.MarkStale("acme/tools#1", seen)
+	s.MarkStale("acme/tools#2", seen)
+
+	now := seen.Add(24 * time.Hour)
+	s.Revive("acme/tools#1", seen, now)
+	if !s.IsStale("acme/tools#1") || !s.FirstSeenAt("acme/tools#1", now).Equal(seen) {
+		t.Fatalf("Expected acme/tools#1 to still be stale, got: %+v", s)
+	}
+	if !s.IsStale("acme/tools#2") || !s.FirstSeenAt("acme/tools#2", now).Equal(seen) {
+		t.Fatalf("Expected checking acme/tools#2 to leave it alone, got: %+v", s)
+	}
+	s.Revive("acme/tools#2", seen.Add(time.Hour), now)
+	if s.IsStale("acme/tools#2") {
+		t.Fatal("Expected acme/tools#2 to not be stale after new activity")
+	}
+	if !s.FirstSeenAt("acme/tools#2", seen).Equal(now) {
+		t.Fatalf("Expected acme/tools#2 to be seen afresh, got: %+v", s)
+	}
+
+	s.Observe([]string{"acme/tools#2"}, now)
+	if s.IsStale("acme/tools#1") {
+		t.Fatal("Expected acme/tools#1 to be forgotten once it's gone")
+	}
+}
+
+func TestDismissed(t *testing.T) {
+	s, err := Load(path.Join(t.TempDir(), "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	keys := []string{"acme/tools@v1.0.0", "acme/cli@v2.0.0"}
+	s.Observe(keys, now)
+	// acme/cli's task hasn't been added yet, so isn't dismissed.
+	s.NoteDismissed(keys, []string{"acme/tools@v1.0.0"}, now)
+	s.NoteDismissed(keys, []string{"acme/cli@v2.0.0"}, now)
+	if !s.IsDismissed("acme/tools@v1.0.0") || s.IsDismissed("acme/cli@v2.0.0") {
+		t.Fatalf("Expected only acme/tools@v1.0.0 to be dismissed, got: %+v", s)
+	}
+
+	s.Observe([]string{"acme/tools@v1.1.0"}, now)
+	if s.IsDismissed("acme/tools@v1.0.0") {
+		t.Fatal("Expected acme/tools@v1.0.0 to be forgotten once it's gone")
+	}
+}